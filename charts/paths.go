@@ -7,3 +7,11 @@ import (
 func ChartYamlPath(chartDir string) string {
 	return fmt.Sprintf("%s/Chart.yaml", chartDir)
 }
+
+func ChartLockPath(chartDir string) string {
+	return fmt.Sprintf("%s/Chart.lock", chartDir)
+}
+
+func ChartArchivePath(chartDir, depName, depVersion string) string {
+	return fmt.Sprintf("%s/charts/%s-%s.tgz", chartDir, depName, depVersion)
+}
@@ -19,3 +19,11 @@ func Test_ChartYamlPath_Returns_Path_Affixed_With_Yaml_Extension(t *testing.T) {
 func Test_ChartYamlPath_Returns_Path_To_Requirements_Yaml_File(t *testing.T) {
 	assert.Regexp(t, regexp.MustCompile("^.*/Chart.yaml$"), ChartYamlPath("/some/dir"))
 }
+
+func Test_ChartLockPath_Returns_Path_To_Chart_Lock_File(t *testing.T) {
+	assert.Equal(t, "/some/dir/Chart.lock", ChartLockPath("/some/dir"))
+}
+
+func Test_ChartArchivePath_Returns_Path_To_Vendored_Archive(t *testing.T) {
+	assert.Equal(t, "/some/dir/charts/microservice-0.3.45.tgz", ChartArchivePath("/some/dir", "microservice", "0.3.45"))
+}
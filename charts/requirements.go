@@ -1,21 +1,38 @@
 package charts
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
 	"github.com/Hutchison-Technologies/helm-deployer/filesystem"
 	"github.com/Hutchison-Technologies/helm-deployer/gosexy/yaml"
+	"github.com/Masterminds/semver/v3"
+	goYaml "github.com/ghodss/yaml"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/provenance"
 )
 
-func HasDependency(chartYamlPath, depName, depAlias string) bool {
+// HasDependency reports whether the Chart.yaml at chartYamlPath lists depName,
+// aliased to depAlias when that is given. It returns an error when the file
+// can't be read or has no list of dependencies.
+func HasDependency(chartYamlPath, depName, depAlias string) (bool, error) {
 	if !filesystem.IsFile(chartYamlPath) {
-		return false
+		return false, nil
 	}
 
 	chart, err := yaml.Open(chartYamlPath)
 	if err != nil {
-		return false
+		return false, fmt.Errorf("Could not read %s, %s", chartYamlPath, err.Error())
 	}
 
-	deps := chart.Get("dependencies").([]interface{})
+	deps, ok := chart.Get("dependencies").([]interface{})
+	if !ok {
+		return false, fmt.Errorf("%s has no list of dependencies", chartYamlPath)
+	}
 	for _, dep := range deps {
 		asDep, ok := dep.(map[interface{}]interface{})
 		if !ok {
@@ -25,13 +42,111 @@ func HasDependency(chartYamlPath, depName, depAlias string) bool {
 		if depAlias != "" {
 			alias, aliasOk := asDep["alias"]
 			if nameOk && aliasOk && name == depName && alias == depAlias {
-				return true
+				return true, nil
 			}
 		} else {
 			if nameOk && name == depName {
-				return true
+				return true, nil
 			}
 		}
 	}
-	return false
+	return false, nil
+}
+
+// LockedDependencyVersion returns the version of depName that the Chart.lock
+// of chartDir resolved, once the lock is found to be in sync with Chart.yaml
+// and the archive it resolved is vendored under charts/.
+func LockedDependencyVersion(chartDir, depName string) (string, error) {
+	lock, err := ReadLock(chartDir)
+	if err != nil {
+		return "", err
+	}
+
+	var locked *chart.Dependency
+	for _, dep := range lock.Dependencies {
+		if dep.Name == depName {
+			locked = dep
+		}
+	}
+	if locked == nil {
+		return "", fmt.Errorf("The lock at \033[31m%s\033[97m does not resolve %s", ChartLockPath(chartDir), depName)
+	}
+	if err := CheckVendoredDependency(chartDir, locked); err != nil {
+		return "", err
+	}
+	return locked.Version, nil
+}
+
+// ReadLock returns the Chart.lock of chartDir, failing when it is missing or
+// out of date with Chart.yaml.
+func ReadLock(chartDir string) (*chart.Lock, error) {
+	chartYamlPath, chartLockPath := ChartYamlPath(chartDir), ChartLockPath(chartDir)
+	if !filesystem.IsFile(chartLockPath) {
+		return nil, fmt.Errorf("Expected to find a lock at \033[31m%s\033[97m, but found nothing, run helm dependency update", chartLockPath)
+	}
+
+	metadata := &chart.Metadata{}
+	if err := readYaml(chartYamlPath, metadata); err != nil {
+		return nil, err
+	}
+	lock := &chart.Lock{}
+	if err := readYaml(chartLockPath, lock); err != nil {
+		return nil, err
+	}
+	digest, err := dependenciesDigest(metadata.Dependencies, lock.Dependencies)
+	if err != nil {
+		return nil, err
+	}
+	if digest != lock.Digest {
+		return nil, fmt.Errorf("The lock at \033[31m%s\033[97m is out of date with %s, run helm dependency update", chartLockPath, chartYamlPath)
+	}
+	return lock, nil
+}
+
+// CheckVendoredDependency checks that the archive of the locked dep is
+// vendored under the charts/ of chartDir.
+func CheckVendoredDependency(chartDir string, dep *chart.Dependency) error {
+	archivePath := ChartArchivePath(chartDir, dep.Name, dep.Version)
+	if !filesystem.IsFile(archivePath) {
+		return fmt.Errorf("Expected to find %s %s vendored at \033[31m%s\033[97m, but found nothing, run helm dependency build", dep.Name, dep.Version, archivePath)
+	}
+	archive, err := loader.LoadFile(archivePath)
+	if err != nil {
+		return fmt.Errorf("Could not load the vendored chart at \033[31m%s\033[97m, %s", archivePath, err.Error())
+	}
+	if archive.Name() != dep.Name || archive.Metadata.Version != dep.Version {
+		return fmt.Errorf("The vendored chart at \033[31m%s\033[97m is %s %s, but the lock resolved %s %s, run helm dependency build", archivePath, archive.Name(), archive.Metadata.Version, dep.Name, dep.Version)
+	}
+	return nil
+}
+
+// IsVersionAtLeast reports whether the semantic version is minimum or later.
+func IsVersionAtLeast(version, minimum string) bool {
+	parsedVersion, err := semver.NewVersion(version)
+	if err != nil {
+		return false
+	}
+	return !parsedVersion.LessThan(semver.MustParse(minimum))
+}
+
+// dependenciesDigest digests the dependencies of a Chart.yaml and its lock as
+// helm does when writing Chart.lock.
+func dependenciesDigest(requested, locked []*chart.Dependency) (string, error) {
+	data, err := json.Marshal([2][]*chart.Dependency{requested, locked})
+	if err != nil {
+		return "", err
+	}
+	digest, err := provenance.Digest(bytes.NewBuffer(data))
+	return "sha256:" + digest, err
+}
+
+func readYaml(path string, into interface{}) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Could not read \033[31m%s\033[97m, %s", path, err.Error())
+	}
+	if err := goYaml.Unmarshal(contents, into); err != nil {
+		return fmt.Errorf("Could not parse \033[31m%s\033[97m, %s", path, err.Error())
+	}
+	return nil
 }
@@ -1,33 +1,135 @@
 package charts
 
 import (
-	"github.com/stretchr/testify/assert"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chartutil"
 )
 
 const TEST_CHART_PATH = "../testdata/Chart.yaml"
 
 func Test_HasDependency_Returns_False_When_File_Does_Not_Exist(t *testing.T) {
-	result := HasDependency("/some/nonexistent/path/to/a/file.yaml", "some-name", "some-alias")
+	result, err := HasDependency("/some/nonexistent/path/to/a/file.yaml", "some-name", "some-alias")
+	assert.Nil(t, err)
 	assert.False(t, result)
 }
 
 func Test_HasDependency_Returns_False_When_Dependency_Not_Present(t *testing.T) {
-	result := HasDependency(TEST_CHART_PATH, "made-up", "not-good")
+	result, err := HasDependency(TEST_CHART_PATH, "made-up", "not-good")
+	assert.Nil(t, err)
 	assert.False(t, result)
 }
 
 func Test_HasDependency_Returns_False_When_Dependency_Is_Present_But_Alias_Is_Not_Present(t *testing.T) {
-	result := HasDependency(TEST_CHART_PATH, "unaliased-dep", "gone-fishing")
+	result, err := HasDependency(TEST_CHART_PATH, "microservice", "gone-fishing")
+	assert.Nil(t, err)
 	assert.False(t, result)
 }
 
 func Test_HasDependency_Returns_False_When_Dependency_Is_Present_And_Alias_Is_Present_But_Alias_Does_Not_Match(t *testing.T) {
-	result := HasDependency(TEST_CHART_PATH, "aliased-dep", "garbage")
+	result, err := HasDependency(TEST_CHART_PATH, "blue-green-microservice", "garbage")
+	assert.Nil(t, err)
 	assert.False(t, result)
 }
 
 func Test_HasDependency_Returns_True_When_Dependency_Is_Present_And_Alias_Is_Present_And_Alias_Matches(t *testing.T) {
-	result := HasDependency(TEST_CHART_PATH, "aliased-dep", "aliaseddep")
+	result, err := HasDependency(TEST_CHART_PATH, "blue-green-microservice", "bluegreen")
+	assert.Nil(t, err)
 	assert.True(t, result)
 }
+
+func Test_HasDependency_Returns_Error_When_There_Are_No_Dependencies(t *testing.T) {
+	result, err := HasDependency("../testdata/standard-chart/Chart.yaml", "microservice", "")
+	assert.False(t, result)
+	assert.NotNil(t, err)
+}
+
+const TEST_LOCKED_CHART_DIR = "../testdata/bluegreen-chart"
+
+// lockedChart writes a chart to a temporary dir depending on
+// blue-green-microservice, locked at lockedVersion and vendored at
+// archiveVersion, and returns the dir.
+func lockedChart(t *testing.T, lockedVersion, archiveVersion string) string {
+	chartDir, err := ioutil.TempDir("", "locked-chart")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { os.RemoveAll(chartDir) })
+
+	requested := []*chart.Dependency{&chart.Dependency{Name: "blue-green-microservice", Version: ">=0.1.0", Repository: "file://../blue-green-microservice", Alias: "bluegreen"}}
+	locked := []*chart.Dependency{&chart.Dependency{Name: "blue-green-microservice", Version: lockedVersion, Repository: "file://../blue-green-microservice"}}
+	digest, err := dependenciesDigest(requested, locked)
+	assert.Nil(t, err)
+
+	chartYaml := "apiVersion: v2\nname: some-api\nversion: 0.1.0\ndependencies:\n- name: blue-green-microservice\n  version: \">=0.1.0\"\n  repository: file://../blue-green-microservice\n  alias: bluegreen\n"
+	chartLock := fmt.Sprintf("dependencies:\n- name: blue-green-microservice\n  repository: file://../blue-green-microservice\n  version: %s\ndigest: %s\ngenerated: \"2021-07-19T15:11:07Z\"\n", lockedVersion, digest)
+	assert.Nil(t, ioutil.WriteFile(ChartYamlPath(chartDir), []byte(chartYaml), 0644))
+	assert.Nil(t, ioutil.WriteFile(ChartLockPath(chartDir), []byte(chartLock), 0644))
+
+	assert.Nil(t, os.MkdirAll(filepath.Join(chartDir, "charts"), 0755))
+	archive, err := chartutil.Save(&chart.Chart{Metadata: &chart.Metadata{APIVersion: chart.APIVersionV2, Name: "blue-green-microservice", Version: archiveVersion}}, filepath.Join(chartDir, "charts"))
+	assert.Nil(t, err)
+	assert.Nil(t, os.Rename(archive, ChartArchivePath(chartDir, "blue-green-microservice", lockedVersion)))
+	return chartDir
+}
+
+func Test_LockedDependencyVersion_Returns_Locked_Version(t *testing.T) {
+	version, err := LockedDependencyVersion(TEST_LOCKED_CHART_DIR, "blue-green-microservice")
+	assert.Nil(t, err)
+	assert.Equal(t, "0.11.35", version)
+
+	version, err = LockedDependencyVersion(lockedChart(t, "0.10.2", "0.10.2"), "blue-green-microservice")
+	assert.Nil(t, err)
+	assert.Equal(t, "0.10.2", version)
+}
+
+func Test_LockedDependencyVersion_Returns_Error_When_Lock_Missing(t *testing.T) {
+	_, err := LockedDependencyVersion("../testdata/standard-chart", "blue-green-microservice")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "helm dependency update")
+}
+
+func Test_LockedDependencyVersion_Returns_Error_When_Lock_Out_Of_Date(t *testing.T) {
+	chartDir := lockedChart(t, "0.11.35", "0.11.35")
+	chartYaml, _ := ioutil.ReadFile(ChartYamlPath(chartDir))
+	assert.Nil(t, ioutil.WriteFile(ChartYamlPath(chartDir), []byte(strings.Replace(string(chartYaml), ">=0.1.0", ">=0.11.0", 1)), 0644))
+
+	_, err := LockedDependencyVersion(chartDir, "blue-green-microservice")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "out of date")
+}
+
+func Test_LockedDependencyVersion_Returns_Error_When_Dependency_Not_Locked(t *testing.T) {
+	_, err := LockedDependencyVersion(TEST_LOCKED_CHART_DIR, "microservice")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "does not resolve microservice")
+}
+
+func Test_LockedDependencyVersion_Returns_Error_When_Archive_Not_Vendored(t *testing.T) {
+	_, err := LockedDependencyVersion("../testdata", "microservice")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "helm dependency build")
+}
+
+func Test_LockedDependencyVersion_Returns_Error_When_Archive_Does_Not_Match_Lock(t *testing.T) {
+	_, err := LockedDependencyVersion(lockedChart(t, "0.11.35", "0.10.2"), "blue-green-microservice")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "0.10.2")
+}
+
+func Test_IsVersionAtLeast(t *testing.T) {
+	assert.True(t, IsVersionAtLeast("0.11.35", "0.11.34"))
+	assert.True(t, IsVersionAtLeast("0.11.34", "0.11.34"))
+	assert.True(t, IsVersionAtLeast("1.0.0", "0.11.34"))
+	assert.False(t, IsVersionAtLeast("0.10.2", "0.11.34"))
+	assert.False(t, IsVersionAtLeast("0.11.34-rc.1", "0.11.34"))
+	assert.False(t, IsVersionAtLeast("latest", "0.11.34"))
+}
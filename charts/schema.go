@@ -0,0 +1,128 @@
+package charts
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	goYaml "github.com/ghodss/yaml"
+	"github.com/xeipuuv/gojsonschema"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+// ValuesViolations validates chartValues, the values a release is deployed
+// with, against the values.schema.json of the chart in chartDir and of each of
+// its subcharts, then checks that each of requiredKeys, a dotted path such as
+// bluegreen.deployment.image, is set. Every violation is returned as
+// "<yaml path>: <problem>".
+func ValuesViolations(chartDir string, chartValues []byte, requiredKeys []string) ([]string, error) {
+	chrt, err := loader.Load(chartDir)
+	if err != nil {
+		return nil, fmt.Errorf("Could not load chart at \033[31m%s\033[97m, %s", chartDir, err.Error())
+	}
+
+	values := map[string]interface{}{}
+	if err := goYaml.Unmarshal(chartValues, &values); err != nil {
+		return nil, err
+	}
+	if err := chartutil.ProcessDependencies(chrt, values); err != nil {
+		return nil, fmt.Errorf("Could not process the dependencies of \033[31m%s\033[97m, %s", chartDir, err.Error())
+	}
+	coalesced, err := chartutil.CoalesceValues(chrt, values)
+	if err != nil {
+		return nil, fmt.Errorf("Could not coalesce the values of \033[31m%s\033[97m, %s", chartDir, err.Error())
+	}
+
+	violations, err := schemaViolations(chrt, coalesced, "")
+	if err != nil {
+		return nil, err
+	}
+	return append(violations, MissingValues(coalesced, requiredKeys)...), nil
+}
+
+// MissingValues returns a violation for each of requiredKeys that is absent,
+// null or an empty string in values.
+func MissingValues(values map[string]interface{}, requiredKeys []string) []string {
+	violations := []string{}
+	for _, key := range requiredKeys {
+		var value interface{} = values
+		for _, part := range strings.Split(key, ".") {
+			asMap, ok := value.(map[string]interface{})
+			if !ok {
+				value = nil
+				break
+			}
+			value = asMap[part]
+		}
+		if value == nil || value == "" {
+			violations = append(violations, fmt.Sprintf("%s: is required but not set", key))
+		}
+	}
+	return violations
+}
+
+func schemaViolations(chrt *chart.Chart, values map[string]interface{}, path string) ([]string, error) {
+	violations := []string{}
+	if chrt.Schema != nil {
+		valuesJSON, err := goYaml.Marshal(values)
+		if err != nil {
+			return nil, err
+		}
+		valuesJSON, err = goYaml.YAMLToJSON(valuesJSON)
+		if err != nil {
+			return nil, err
+		}
+		result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(chrt.Schema), gojsonschema.NewBytesLoader(valuesJSON))
+		if err != nil {
+			return nil, fmt.Errorf("Could not validate against the values.schema.json of %s, %s", chrt.Name(), err.Error())
+		}
+		chartViolations := []string{}
+		for _, resultError := range result.Errors() {
+			field, description := resultError.Field(), resultError.Description()
+			if property, ok := resultError.Details()["property"].(string); ok && resultError.Type() == "required" {
+				field, description = valuesPath(field, property), "is required but not set"
+			}
+			chartViolations = append(chartViolations, fmt.Sprintf("%s: %s", valuesPath(path, field), description))
+		}
+		sort.Strings(chartViolations)
+		violations = append(violations, chartViolations...)
+	}
+
+	for _, subchart := range chrt.Dependencies() {
+		subchartValues, ok := values[subchart.Name()].(map[string]interface{})
+		if !ok {
+			subchartValues = map[string]interface{}{}
+		}
+		subchartViolations, err := schemaViolations(subchart, subchartValues, valuesPath(path, subchart.Name()))
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, subchartViolations...)
+	}
+	return violations, nil
+}
+
+// valuesPath joins field onto path, where either being empty or "(root)" is
+// the top of the values.
+func valuesPath(path, field string) string {
+	if path == gojsonschema.STRING_ROOT_SCHEMA_PROPERTY {
+		path = ""
+	}
+	if field == gojsonschema.STRING_ROOT_SCHEMA_PROPERTY {
+		field = ""
+	}
+	switch {
+	case path == "":
+		if field == "" {
+			return gojsonschema.STRING_ROOT_SCHEMA_PROPERTY
+		}
+		return field
+	case field == "":
+		return path
+	default:
+		return path + "." + field
+	}
+}
@@ -0,0 +1,82 @@
+package charts
+
+import (
+	"testing"
+
+	"github.com/Hutchison-Technologies/helm-deployer/gosexy/yaml"
+	goYaml "github.com/ghodss/yaml"
+	"github.com/stretchr/testify/assert"
+)
+
+const TEST_SCHEMA_CHART_DIR = "../testdata/schema-chart"
+
+func schemaChartValues(t *testing.T, names ...string) map[string]interface{} {
+	paths := []string{}
+	for _, name := range names {
+		paths = append(paths, TEST_SCHEMA_CHART_DIR+"/"+name)
+	}
+	valuesYaml, err := MergeValuesYaml(paths, nil, nil)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	values := map[string]interface{}{}
+	assert.Nil(t, goYaml.Unmarshal(schemaChartValuesYaml(t, valuesYaml), &values))
+	return values
+}
+
+func schemaChartValuesYaml(t *testing.T, valuesYaml *yaml.Yaml) []byte {
+	values, err := valuesYaml.Marshal()
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return values
+}
+
+func Test_ValuesViolations_Returns_Error_When_Chart_Does_Not_Exist(t *testing.T) {
+	valuesYaml, _ := LoadValuesYaml(TEST_VALUES_PATH)
+	_, err := ValuesViolations("/some/nonexistent/chart", schemaChartValuesYaml(t, valuesYaml), nil)
+	assert.NotNil(t, err)
+}
+
+func Test_ValuesViolations_Returns_Nothing_When_Values_Are_Valid(t *testing.T) {
+	valuesYaml, _ := MergeValuesYaml([]string{TEST_SCHEMA_CHART_DIR + "/values.yaml", TEST_SCHEMA_CHART_DIR + "/prod.yaml"}, nil, nil)
+	violations, err := ValuesViolations(TEST_SCHEMA_CHART_DIR, schemaChartValuesYaml(t, valuesYaml), []string{"image.repository", "jobs.schedule"})
+	assert.Nil(t, err)
+	assert.Empty(t, violations)
+}
+
+func Test_ValuesViolations_Returns_Every_Violation_With_Its_Path(t *testing.T) {
+	valuesYaml, _ := MergeValuesYaml([]string{TEST_SCHEMA_CHART_DIR + "/values.yaml", TEST_SCHEMA_CHART_DIR + "/invalid.yaml"}, nil, nil)
+	violations, err := ValuesViolations(TEST_SCHEMA_CHART_DIR, schemaChartValuesYaml(t, valuesYaml), []string{"image.repository", "ingress.host"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"image.repository: is required but not set",
+		"image.tag: Invalid type. Expected: string, given: integer",
+		"replicas: Invalid type. Expected: integer, given: string",
+		"jobs.schedule: Invalid type. Expected: string, given: integer",
+		"image.repository: is required but not set",
+		"ingress.host: is required but not set",
+	}, violations)
+}
+
+func Test_ValuesViolations_Reports_A_Missing_Top_Level_Key(t *testing.T) {
+	valuesYaml, _ := LoadValuesYaml(TEST_SCHEMA_CHART_DIR + "/values.yaml")
+	violations, err := ValuesViolations(TEST_SCHEMA_CHART_DIR, schemaChartValuesYaml(t, valuesYaml), nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"image: is required but not set"}, violations)
+}
+
+func Test_MissingValues_Returns_Nothing_When_Keys_Are_Set(t *testing.T) {
+	values := schemaChartValues(t, "values.yaml", "prod.yaml")
+	assert.Empty(t, MissingValues(values, []string{"replicas", "image.repository", "image.tag"}))
+}
+
+func Test_MissingValues_Returns_Each_Missing_Key(t *testing.T) {
+	values := schemaChartValues(t, "values.yaml", "prod.yaml")
+	values["empty"] = ""
+	assert.Equal(t, []string{
+		"image.digest: is required but not set",
+		"replicas.count: is required but not set",
+		"empty: is required but not set",
+	}, MissingValues(values, []string{"image.digest", "replicas.count", "empty", "image"}))
+}
@@ -6,6 +6,10 @@ import (
 
 	"github.com/Hutchison-Technologies/helm-deployer/filesystem"
 	"github.com/Hutchison-Technologies/helm-deployer/gosexy/yaml"
+
+	goYaml "github.com/ghodss/yaml"
+	"helm.sh/helm/v3/pkg/cli/values"
+	"helm.sh/helm/v3/pkg/getter"
 )
 
 func LoadValuesYaml(path string) (*yaml.Yaml, error) {
@@ -21,6 +25,29 @@ func LoadValuesYaml(path string) (*yaml.Yaml, error) {
 	return values, nil
 }
 
+// MergeValuesYaml deep merges the values files at paths, each overriding the
+// last, then applies set and setString overrides, as helm does with -f, --set
+// and --set-string.
+func MergeValuesYaml(paths, set, setString []string) (*yaml.Yaml, error) {
+	for _, path := range paths {
+		if !filesystem.IsFile(path) {
+			return nil, errors.New(fmt.Sprintf("Expected to find chart values yaml at: \033[31m%s\033[97m, but found nothing.", path))
+		}
+	}
+
+	options := values.Options{ValueFiles: paths, Values: set, StringValues: setString}
+	merged, err := options.MergeValues(getter.Providers{})
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Could not merge chart values, %s", err.Error()))
+	}
+
+	mergedYaml, err := goYaml.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Parse(mergedYaml)
+}
+
 func EditValuesYaml(valuesYaml *yaml.Yaml, settings [][]interface{}) ([]byte, error) {
 	for _, setting := range settings {
 		err := valuesYaml.Set(setting...)
@@ -28,11 +55,7 @@ func EditValuesYaml(valuesYaml *yaml.Yaml, settings [][]interface{}) ([]byte, er
 			return nil, err
 		}
 	}
-	err := valuesYaml.Save()
-	if err != nil {
-		return nil, err
-	}
-	values, err := valuesYaml.ToByteArray()
+	values, err := valuesYaml.Marshal()
 	if err != nil {
 		return nil, err
 	}
@@ -1,8 +1,10 @@
 package charts
 
 import (
-	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 const TEST_VALUES_PATH = "../testdata/prod.yaml"
@@ -21,3 +23,76 @@ func Test_LoadValuesYaml_Returns_FileContents(t *testing.T) {
 	fileContents, _ := LoadValuesYaml(TEST_VALUES_PATH)
 	assert.NotNil(t, fileContents)
 }
+
+func Test_EditValuesYaml_Returns_Edited_Values(t *testing.T) {
+	valuesYaml, _ := LoadValuesYaml(TEST_VALUES_PATH)
+	values, err := EditValuesYaml(valuesYaml, [][]interface{}{
+		[]interface{}{"bluegreen", "deployment", "colour", "green"},
+		[]interface{}{"bluegreen", "deployment", "version", "v9.9.9"},
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, string(values), "colour: green")
+	assert.Contains(t, string(values), "version: v9.9.9")
+}
+
+func Test_EditValuesYaml_Does_Not_Modify_Values_File(t *testing.T) {
+	original, err := ioutil.ReadFile(TEST_VALUES_PATH)
+	assert.Nil(t, err)
+
+	valuesYaml, _ := LoadValuesYaml(TEST_VALUES_PATH)
+	_, err = EditValuesYaml(valuesYaml, [][]interface{}{
+		[]interface{}{"bluegreen", "deployment", "colour", "green"},
+		[]interface{}{"bluegreen", "deployment", "version", "v9.9.9"},
+	})
+	assert.Nil(t, err)
+
+	afterEdit, err := ioutil.ReadFile(TEST_VALUES_PATH)
+	assert.Nil(t, err)
+	assert.Equal(t, original, afterEdit)
+}
+
+const TEST_LAYERED_VALUES_DIR = "../testdata/layered-values"
+
+func layeredValuesPaths(names ...string) []string {
+	paths := []string{}
+	for _, name := range names {
+		paths = append(paths, TEST_LAYERED_VALUES_DIR+"/"+name)
+	}
+	return paths
+}
+
+func Test_MergeValuesYaml_Returns_Error_When_File_Does_Not_Exist(t *testing.T) {
+	_, err := MergeValuesYaml(layeredValuesPaths("values.yaml", "nonexistent.yaml"), nil, nil)
+	assert.NotNil(t, err)
+}
+
+func Test_MergeValuesYaml_Deep_Merges_Files_In_Order(t *testing.T) {
+	merged, err := MergeValuesYaml(layeredValuesPaths("values.yaml", "common.yaml", "prod.yaml", "extra.yaml"), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "registry.example.com/some-api", merged.Get("image", "repository"))
+	assert.Equal(t, "stable", merged.Get("image", "tag"))
+	assert.Equal(t, 5, merged.Get("replicas"))
+	assert.Equal(t, "500m", merged.Get("resources", "limits", "cpu"))
+	assert.Equal(t, "512Mi", merged.Get("resources", "limits", "memory"))
+	assert.Equal(t, true, merged.Get("ingress", "enabled"))
+}
+
+func Test_MergeValuesYaml_Applies_Set_Over_Files(t *testing.T) {
+	merged, err := MergeValuesYaml(layeredValuesPaths("values.yaml", "prod.yaml"), []string{"replicas=7,image.tag=v1.2.3"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 7, merged.Get("replicas"))
+	assert.Equal(t, "v1.2.3", merged.Get("image", "tag"))
+	assert.Equal(t, "512Mi", merged.Get("resources", "limits", "memory"))
+}
+
+func Test_MergeValuesYaml_Applies_SetString_Last(t *testing.T) {
+	merged, err := MergeValuesYaml(layeredValuesPaths("values.yaml"), []string{"image.tag=v1"}, []string{"image.tag=0123", "replicas=2"})
+	assert.Nil(t, err)
+	assert.Equal(t, "0123", merged.Get("image", "tag"))
+	assert.Equal(t, "2", merged.Get("replicas"))
+}
+
+func Test_MergeValuesYaml_Returns_Error_When_Set_Invalid(t *testing.T) {
+	_, err := MergeValuesYaml(layeredValuesPaths("values.yaml"), []string{"replicas"}, nil)
+	assert.NotNil(t, err)
+}
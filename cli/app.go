@@ -6,34 +6,22 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 	"time"
-	"bytes"
-	"bufio"
-	goYaml "github.com/ghodss/yaml"
 
-	"github.com/Hutchison-Technologies/helm-deployer/charts"
+	"github.com/Hutchison-Technologies/helm-deployer/config"
+	"github.com/Hutchison-Technologies/helm-deployer/deployer"
 	"github.com/Hutchison-Technologies/helm-deployer/deployment"
-	"github.com/Hutchison-Technologies/helm-deployer/gosexy/yaml"
-	"github.com/Hutchison-Technologies/helm-deployer/h3lm"
 	"github.com/Hutchison-Technologies/helm-deployer/kubectl"
+	"github.com/Hutchison-Technologies/helm-deployer/notify"
 	"github.com/Hutchison-Technologies/helm-deployer/runtime"
 
-	"github.com/databus23/helm-diff/diff"
-	"github.com/databus23/helm-diff/manifest"
-
-	apiv1 "k8s.io/api/core/v1"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	appv1 "k8s.io/client-go/kubernetes/typed/apps/v1"
-	autoscalingv1 "k8s.io/client-go/kubernetes/typed/autoscaling/v1"
-	v1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
-	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/rest"
 
-	"helm.sh/helm/v3/pkg/chart/loader"
 	"helm.sh/helm/v3/pkg/action"
-	"helm.sh/helm/v3/pkg/cli"
-	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/kube"
 )
 
@@ -42,324 +30,295 @@ const (
 	APP_NAME                = "app-name"
 	APP_VERSION             = "app-version"
 	TARGET_ENV              = "target-env"
-	DEFAULT_COLOUR          = "blue"
-	RELEASE_UPGRADE_TIMEOUT = 900
-	ROLLBACK_VERSION_POOL   = 50
-	ROLLBACK_TIMEOUT        = 900
+	REVISION                = "revision"
+	COLOUR                  = "colour"
+	SMOKE_TEST              = "smoke-test"
+	MAX_RESTARTS            = "max-restarts"
+	KEEP_WARM               = "keep-warm"
+	CANARY_STEPS            = "steps"
+	STEP_PAUSE              = "step-pause"
+	FAIL_ON_NO_CHANGE       = "fail-on-no-change"
+	LOG_FORMAT              = "log-format"
+	RESULT_FILE             = "result-file"
+	WEBHOOK_URL             = "webhook-url"
+	ARTIFACTS_DIR           = "artifacts-dir"
+	FAILURE_LOG_LINES       = "failure-log-lines"
+	DEPLOY_TYPE             = "deploy-type"
+	NAMESPACE               = "namespace"
+	CREATE_NAMESPACE        = "create-namespace"
+	KUBECONFIG              = "kubeconfig"
+	KUBE_CONTEXT            = "kube-context"
+	INSTALL_TIMEOUT         = "install-timeout"
+	UPGRADE_TIMEOUT         = "upgrade-timeout"
+	ROLLBACK_TIMEOUT        = "rollback-timeout"
+	FORCE                   = "force"
+	RECREATE                = "recreate"
+	ATOMIC                  = "atomic"
+	WAIT_FOR_JOBS           = "wait-for-jobs"
+	MAX_HISTORY             = "max-history"
+	CLEANUP_ON_FAIL         = "cleanup-on-fail"
+	RECOVER_PENDING         = "recover-pending"
+	PENDING_TIMEOUT         = "pending-timeout"
+	VALUES                  = "values"
+	SET                     = "set"
+	SET_STRING              = "set-string"
+	SHOW_VALUES             = "show-values"
+	BUILD_DEPS              = "build-deps"
+	VERIFY_DEPS             = "verify-deps"
+	REPOSITORY_CONFIG       = "repository-config"
+	REPOSITORY_CACHE        = "repository-cache"
 )
 
+// invocation is what a single run of a command parses from its flags and sets
+// up from them. Run builds a new one for every run, so that nothing carries
+// over from one run to the next in the same process.
+type invocation struct {
+	environment  *config.Environment
+	restConfig   *rest.Config
+	result       *Result
+	notifier     *notify.Notifier
+	chartValues  *valuesOptions
+	diagnostics  *diagnosticsOptions
+	dependencies *dependencyOptions
+	phases       *phaseLogger
+}
+
+func newInvocation(command string) *invocation {
+	return &invocation{
+		environment: &config.Environment{
+			Namespace:       config.DEFAULT_NAMESPACE,
+			Timeout:         config.DEFAULT_TIMEOUT,
+			InstallTimeout:  config.DEFAULT_TIMEOUT,
+			UpgradeTimeout:  config.DEFAULT_TIMEOUT,
+			RollbackTimeout: config.DEFAULT_ROLLBACK_TIMEOUT,
+			PendingTimeout:  config.DEFAULT_PENDING_TIMEOUT,
+
+			MinBlueGreenMicroserviceVersion: config.DEFAULT_MIN_BLUE_GREEN_MICROSERVICE_VERSION,
+			MinMicroserviceVersion:          config.DEFAULT_MIN_MICROSERVICE_VERSION,
+		},
+		result:       NewResult(command),
+		chartValues:  &valuesOptions{},
+		diagnostics:  &diagnosticsOptions{logLines: deployer.DEFAULT_FAILURE_LOG_LINES},
+		dependencies: &dependencyOptions{},
+		phases:       &phaseLogger{out: os.Stderr, start: time.Now()},
+	}
+}
+
 func Run() error {
+	format := LOG_FORMAT_TEXT
+	if len(os.Args) > 1 && IsValidLogFormat(LogFormatFromArgs(os.Args[1:])) {
+		format = LogFormatFromArgs(os.Args[1:])
+	}
+	ConfigureLogging(format, IsTerminal(os.Stdout))
 	log.Println("Starting helm-deployer..")
 
+	if len(os.Args) < 2 {
+		return runtime.ValidationError(errors.New(fmt.Sprintf("Missing command, should be one of: %s", knownCommands())))
+	}
+
+	// ctx is cancelled when the process is asked to stop, so that the command
+	// in progress can settle its release before exiting.
+	ctx, stop := cancelOnSignal(context.Background())
+	defer stop()
+
+	invocation := newInvocation(os.Args[1])
+	err := runCommand(ctx, invocation)
+	result := invocation.result
+	result.finish(err)
+	invocation.notifyFinished()
+	if writeErr := result.Write(); writeErr != nil {
+		log.Println(writeErr.Error())
+		if err == nil {
+			err = writeErr
+		}
+	} else if result.Path != "" {
+		log.Printf("Wrote the result to %s", Green(result.Path))
+	}
+	return err
+}
+
+func runCommand(ctx context.Context, invocation *invocation) error {
 	switch DetermineCommand(os.Args[1]) {
 	case Command.BLUEGREEN:
 		log.Println("Running bluegreen deploy..")
-		return RunBlueGreenDeploy()
+		return RunBlueGreenDeploy(ctx, invocation)
 	case Command.STANDARD_CHART:
 		log.Println("Running standard-chart deploy..")
-		return RunStandardChartDeploy()
+		return RunStandardChartDeploy(ctx, invocation)
 	case Command.MICROSERVICE:
 		log.Println("Running microservice deploy..")
-		return RunMicroserviceDeploy()
+		return RunMicroserviceDeploy(ctx, invocation)
+	case Command.ROLLBACK:
+		log.Println("Running rollback..")
+		return RunRollback(ctx, invocation)
+	case Command.SWAP:
+		log.Println("Running bluegreen swap..")
+		return RunBlueGreenSwap(ctx, invocation)
+	case Command.PLAN:
+		log.Println("Running plan..")
+		return RunPlan(ctx, invocation)
+	case Command.CANARY:
+		log.Println("Running canary deploy..")
+		return RunCanaryDeploy(ctx, invocation)
 	default:
-		return errors.New(fmt.Sprintf("Unknown command: %s\nShould be one of: %s", Green(os.Args[1]), strings.Join([]string{Orange(Command.BLUEGREEN), Orange(Command.STANDARD_CHART)}, ", ")))
+		return runtime.ValidationError(errors.New(fmt.Sprintf("Unknown command: %s\nShould be one of: %s", Green(os.Args[1]), knownCommands())))
 	}
 }
 
-func parseCLIFlags(flagsToParse []*Flag) map[string]string {
-	potentialParsedFlags, potentialParseFlagsErr := ParseFlags(flagsToParse)
-	cliFlags, err := HandleParseFlags(potentialParsedFlags, potentialParseFlagsErr)
-	runtime.PanicIfError(err)
-	return cliFlags
-}
-
-func loadChartValues(chartDir, targetEnv string) *yaml.Yaml {
-	chartValuesPath := deployment.ChartValuesPath(chartDir, targetEnv)
-	values, err := charts.LoadValuesYaml(chartValuesPath)
-	runtime.PanicIfError(err)
-	return values
-}
-
-func editChartValues(valuesYaml *yaml.Yaml, settings [][]interface{}) []byte {
-	values, err := charts.EditValuesYaml(valuesYaml, settings)
-	runtime.PanicIfError(err)
-	return values
+func knownCommands() string {
+	return strings.Join([]string{Orange(Command.BLUEGREEN), Orange(Command.STANDARD_CHART), Orange(Command.MICROSERVICE), Orange(Command.ROLLBACK), Orange(Command.SWAP), Orange(Command.PLAN), Orange(Command.CANARY)}, ", ")
 }
 
-func kubeCtlClient() v1.CoreV1Interface {
-	client, err := kubectl.Client()
-	runtime.PanicIfError(err)
-	return client
-}
-
-func kubeCtlAppClient() appv1.AppsV1Interface {
-	client, err := kubectl.AppsClient()
-	runtime.PanicIfError(err)
-	return client
-}
-
-func kubeCtlHPAClient() autoscalingv1.AutoscalingV1Interface {
-	client, err := kubectl.HPAClient()
-	runtime.PanicIfError(err)
-	return client
+func parseCLIFlags(flagsToParse []*Flag) (map[string]string, error) {
+	potentialParsedFlags, potentialParseFlagsErr := ParseFlags(flagsToParse)
+	cliFlags, err := HandleParseFlags(potentialParsedFlags, potentialParseFlagsErr)
+	return cliFlags, runtime.ValidationError(err)
 }
 
-func buildHelmConfig() *action.Configuration {
-    log.Println("Building helm configuration..")
-
-	kube.ManagedFieldsManager = "helm"
-	helmConfig := new(action.Configuration) 
-	settings := cli.New()
-
-	if err := helmConfig.Init(settings.RESTClientGetter(), "default",
-		os.Getenv("HELM_DRIVER"), log.Printf); err != nil {
-		log.Printf("%+v", err)
-		os.Exit(1)
+// clusterConfig resolves the environment's cluster connection once, so that
+// helm and every kubectl client talk to the same cluster.
+func (invocation *invocation) clusterConfig() (*rest.Config, error) {
+	if invocation.restConfig != nil {
+		return invocation.restConfig, nil
 	}
-
-	log.Printf("Configured helm configuration.")
-    return helmConfig
+	environment := invocation.environment
+	if kubectl.UsesInClusterConfig(environment.Kubeconfig) {
+		log.Println("No kubeconfig found, using the in-cluster service account")
+	}
+	config, err := kubectl.RESTConfig(environment.Kubeconfig, environment.KubeContext)
+	if err != nil {
+		return nil, runtime.ClusterError(fmt.Errorf("Failed to load the cluster config: %s", err))
+	}
+	invocation.restConfig = config
+	return invocation.restConfig, nil
 }
 
-func releaseWithValues(releaseName string, chartValuesYaml *yaml.Yaml, chartValuesEdits [][]interface{}, helmConfig *action.Configuration, chartDir string) *release.Release {
-	log.Printf("Editing chart values to deploy %s..", Green(releaseName))
-	chartValues := editChartValues(chartValuesYaml, chartValuesEdits)
-	log.Printf("Successfully edited chart values:\n%s", Orange(string(chartValues)))
-
-	log.Printf("Deploying: %s..", Green(releaseName))
-	deployedRelease, err := deployRelease(helmConfig, releaseName, chartDir, chartValues)
+// kubeCtlClientset connects to the environment's cluster.
+func (invocation *invocation) kubeCtlClientset() (kubernetes.Interface, error) {
+	config, err := invocation.clusterConfig()
 	if err != nil {
-		log.Printf("Error deploying %s: %s", Green(releaseName), err.Error())
-		log.Println("Determining whether rollback is necessary..")
-		if shouldRollBack(helmConfig, releaseName) {
-			log.Println("Rollback is necessary")
-			rollbackErr := rollback(helmConfig, releaseName)
-			runtime.PanicIfError(rollbackErr)
-		} else {
-			log.Println("Current release is ok, nothing to do")
-		}
-		panic(fmt.Errorf("Original deploy error: %s", err))
-	} else {
-		return deployedRelease
+		return nil, err
 	}
+	client, err := kubectl.Clientset(config)
+	return client, runtime.ClusterError(err)
 }
 
+func (invocation *invocation) buildHelmConfig() (*action.Configuration, error) {
+    log.Println("Building helm configuration..")
 
-func deployRelease(helmConfig *action.Configuration, releaseName, chartDir string, chartValues []byte) (*release.Release, error) {
-	log.Printf("Checking for existing %s release..", Green(releaseName))
-
-	releaseNamespace := "default"
-
-	// Create new fetchManager to get information about existing releases
-	fetchManager := action.NewGet(helmConfig)
-	releaseContent, err := fetchManager.Run(releaseName)
-	existingReleaseCode := release.StatusUnknown
-
-	if releaseContent != nil {
-		log.Println("Found existing release:")
-		PrintRelease(releaseContent)
-		existingReleaseCode = releaseContent.Info.Status
+	environment := invocation.environment
+	config, err := invocation.clusterConfig()
+	if err != nil {
+		return nil, err
 	}
 
-	switch deployment.DetermineReleaseCourse(releaseName, existingReleaseCode, err) {
-	case deployment.ReleaseCourse.INSTALL:
-		log.Println("No existing release found, installing release..")
-		
-		chart, err := loader.Load(chartDir)
-		if err != nil {
-			panic(err)
-		}
-		log.Println("Chart: ", chart)
-
-		duration, err := time.ParseDuration("300s")
-		if err != nil {
-			panic(err)
-		}
-
-		installManager := action.NewInstall(helmConfig)
-		installManager.Namespace = releaseNamespace
-		installManager.ReleaseName = releaseName
-		installManager.Wait = true
-		installManager.WaitForJobs = true
-		installManager.Timeout = duration
-		installManager.Description = "Some chart"
-		// Disable when not testing...
-		installManager.DryRun = false
-
-
-		vals := make(map[string]interface{})
-		err = goYaml.Unmarshal(chartValues, &vals)
-		if err != nil {
-			panic(err)
-		}
-
-		// Push values to chart and install
-		installResponse, err := installManager.Run(chart, vals)
-		if err != nil {
-			return nil, err
-		}
-		log.Println("Installed release: ", installResponse)
-		return installResponse, nil
-	case deployment.ReleaseCourse.UPGRADE_WITH_DIFF_CHECK:
-		log.Println("Dry-running release to obtain full manifest..")
-
-		dryRunRelease, err := upgradeRelease(helmConfig, releaseName, chartDir, chartValues, true)
-		if err != nil {
-			return nil, err
-		}
-
-		currentManifestString := string(releaseContent.Manifest)
-		newManifestString := string(dryRunRelease.Manifest)
-
-		currentManifests := manifest.Parse(currentManifestString, "default")
-		newManifests := manifest.Parse(newManifestString, "default")
-
-		log.Println("Checking proposed release for changes against existing release..")
-		
-		var b bytes.Buffer
-		var manifestContext int = -1
-
-		hasChanges := diff.Manifests(currentManifests, newManifests, []string{}, false, manifestContext, bufio.NewWriter(&b))
-		if !hasChanges {
-			return nil, errors.New("No difference detected between this release and the existing release, no deploy.")
-		}
-		fallthrough
-	case deployment.ReleaseCourse.UPGRADE:
-		log.Printf("Upgrading release, will timeout after %d seconds..", RELEASE_UPGRADE_TIMEOUT)
-		upgradeRelease, err := upgradeRelease(helmConfig, releaseName, chartDir, chartValues, false)
-		if err != nil {
-			return nil, err
-		}
-		return upgradeRelease, nil
+	kube.ManagedFieldsManager = "helm"
+	helmConfig := new(action.Configuration) 
+	restClientGetter := kubectl.RESTClientGetter(config, environment.Kubeconfig, environment.KubeContext, environment.Namespace)
+	if err := helmConfig.Init(restClientGetter, environment.Namespace,
+		os.Getenv("HELM_DRIVER"), log.Printf); err != nil {
+		return nil, runtime.ClusterError(fmt.Errorf("Failed to configure helm: %s", err))
 	}
 
-	return nil, errors.New("Unknown release course")
+	log.Printf("Configured helm configuration.")
+    return helmConfig, nil
 }
 
-
-func upgradeRelease(helmConfig *action.Configuration, releaseName, chartDir string, chartValues []byte, dryRun bool) (*release.Release, error) {
-	chart, err := loader.Load(chartDir)
-    if err != nil {
-        panic(err)
-    }
-	log.Println("Chart: ", chart)
-
-	duration, err := time.ParseDuration("300s")
-	if err != nil {
-		panic(err)
-	}
-
-	upgradeManager := action.NewUpgrade(helmConfig)
-	upgradeManager.Force = true;
-	upgradeManager.Recreate = true;
-	upgradeManager.Wait = true;
-	upgradeManager.Timeout = duration
-	// Remove when finished testing
-	upgradeManager.DryRun = dryRun
-
-	vals := make(map[string]interface{})
-	err = goYaml.Unmarshal(chartValues, &vals)
+// newDeployer configures a Deployer for the environment, reporting its phases
+// to the result, the JSON log and the webhooks.
+func (invocation *invocation) newDeployer() (*deployer.Deployer, error) {
+	log.Println("Configuring helm...")
+	helmConfig, err := invocation.buildHelmConfig()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+	log.Println("Successfully configured helm!")
 
-	// Push values to upgrade request
-	res, err := upgradeManager.Run(releaseName, chart, vals)
-
+	kubeClient, err := invocation.kubeCtlClientset()
 	if err != nil {
 		return nil, err
 	}
-	return res, nil
+	return deployer.New(deployer.Options{
+		HelmConfig:       helmConfig,
+		KubeClient:       kubeClient,
+		Namespace:        invocation.environment.Namespace,
+		Logger:           log.Default(),
+		Out:              StdoutWriter(),
+		Environment:      invocation.environment,
+		ArtifactsDir:     invocation.diagnostics.artifactsDir,
+		FailureLogLines:  invocation.diagnostics.logLines,
+		OnPhase:          invocation.onPhase,
+		Colour:           true,
+		RepositoryConfig: invocation.dependencies.repositoryConfig,
+		RepositoryCache:  invocation.dependencies.repositoryCache,
+	}), nil
 }
 
-
-func shouldRollBack(helmConfig *action.Configuration, releaseName string) bool {
-	status := action.NewStatus(helmConfig)
-	releaseStatus, err := status.Run(releaseName)
-	runtime.PanicIfError(err)
-	return releaseStatus.Info.Status != release.StatusDeployed
-}
-
-
-func rollback(helmConfig *action.Configuration, releaseName string) error {
-	log.Printf("Gathering up to the last %d release(s)..", ROLLBACK_VERSION_POOL)
-
-	status := action.NewHistory(helmConfig)
-	status.Max = ROLLBACK_VERSION_POOL
-
-	releaseHistory, err := status.Run(releaseName)
-
-	runtime.PanicIfError(err)
-	if len(releaseHistory) == 0 {
-		return errors.New("No prior release(s) to roll back to!")
+func (invocation *invocation) onPhase(phase deployer.Phase) {
+	if phase.Event == deployer.PHASE_RELEASE_ROLLED_BACK {
+		invocation.notifyEvent(notify.Event{Event: notify.EVENT_ROLLED_BACK, Release: phase.Release, Revision: phase.Revision})
 	}
+	invocation.logPhase(PhaseEvent{Event: phase.Event, Release: phase.Release, Revision: phase.Revision, Colour: phase.Colour, Weight: phase.Weight})
+}
 
-	log.Printf("Found %d prior release(s), filtering for successful release(s)..", len(releaseHistory))
-	successfullyDeployedReleases := h3lm.FilterReleasesByStatusCode(releaseHistory, release.StatusDeployed)
-
-	if len(successfullyDeployedReleases) == 0 {
-		return errors.New("No successfully deployed prior release(s) to roll back to!")
-	}
-
-	log.Printf("Found %d prior successful release(s), finding the latest..", len(successfullyDeployedReleases))
-	latestSuccessfulRelease := h3lm.LatestRelease(successfullyDeployedReleases)
-
-	log.Println("Latest successful release:")
-	PrintRelease(latestSuccessfulRelease)
-
-	log.Println("Rolling back..")
-
-	duration, err := time.ParseDuration(fmt.Sprintf("%ds", ROLLBACK_TIMEOUT))
-	if err != nil {
-		panic(err)
+// recordReport copies what the deployer did into the result, which is
+// written whether or not it succeeded.
+func (invocation *invocation) recordReport(report *deployer.Report, err error) error {
+	if report != nil {
+		invocation.result.Report = *report
 	}
+	return err
+}
 
-	rollbackManager := action.NewRollback(helmConfig)
-	rollbackManager.Force = true;
-	rollbackManager.Recreate = true;
-	rollbackManager.Wait = true;
-	rollbackManager.Timeout = duration
-	rollbackManager.Version = latestSuccessfulRelease.Version;
-
-	err = rollbackManager.Run(releaseName)
-
-	if err != nil {
-		return fmt.Errorf("Failed to rollback: %s", err)
-	}
-	log.Printf("Successfully rolled %s back:", Green(releaseName))
-	return nil
+// CommonFlags are accepted by every command.
+func CommonFlags() []*Flag {
+	return append(EnvironmentFlags(),
+		&Flag{
+			Key:         LOG_FORMAT,
+			Default:     LOG_FORMAT_TEXT,
+			Description: "format of the log output (text or json), colour is disabled when stdout is not a terminal.",
+			Validator:   IsValidLogFormat,
+		},
+		ResultFileFlag())
 }
 
-func deleteHPA(offlineHPAName string) error {
-	hpaClient := kubeCtlHPAClient().HorizontalPodAutoscalers(apiv1.NamespaceDefault)
-	deletePolicy := metav1.DeletePropagationBackground
-	deletionError := hpaClient.Delete(context.TODO(), offlineHPAName, metav1.DeleteOptions{
-		PropagationPolicy: &deletePolicy,
-	})
-	if deletionError != nil {
-		log.Printf("Error deleting HPA (%s): %v", hpaClient, deletionError)
-		return deletionError
+func FailOnNoChangeFlag() *Flag {
+	return &Flag{
+		Key:         FAIL_ON_NO_CHANGE,
+		Default:     "false",
+		Description: "whether a deploy that changes nothing should fail instead of succeeding as unchanged (true or false).",
+		Validator:   deployment.IsValidBoolean,
 	}
-	log.Printf("Success! Removed the HPA (%s).", offlineHPAName)
-	return nil
 }
 
-func scaleReplicaSet(offlineDeploymentName string, scaleSize int32) error {
-	deploymentsClient := kubeCtlAppClient().Deployments(apiv1.NamespaceDefault)
-	retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		// Retrieve the latest version of Deployment before attempting update
-		// RetryOnConflict uses exponential backoff to avoid exhausting the apiserver
-		result, getErr := deploymentsClient.Get(context.TODO(), offlineDeploymentName, metav1.GetOptions{})
-		if getErr != nil {
-			panic(fmt.Errorf("Failed to get latest version of Deployment: %v", getErr))
-		}
-
-		var numberOfReplicas int32 = scaleSize
-		result.Spec.Replicas = &numberOfReplicas
 
-		_, updateErr := deploymentsClient.Update(context.TODO(), result, metav1.UpdateOptions{})
-		return updateErr
-	})
-
-	return retryErr
+// deploySpec reads whichever of the deploy flags the command accepts.
+func (invocation *invocation) deploySpec(cliFlags map[string]string) deployer.Spec {
+	maxRestarts, _ := strconv.Atoi(cliFlags[MAX_RESTARTS])
+	keepWarm, _ := strconv.Atoi(cliFlags[KEEP_WARM])
+	revision, _ := strconv.Atoi(cliFlags[REVISION])
+	stepPause, _ := time.ParseDuration(cliFlags[STEP_PAUSE])
+	spec := deployer.Spec{
+		ChartDir:       cliFlags[CHART_DIR],
+		AppName:        cliFlags[APP_NAME],
+		AppVersion:     cliFlags[APP_VERSION],
+		TargetEnv:      cliFlags[TARGET_ENV],
+		FailOnNoChange: cliFlags[FAIL_ON_NO_CHANGE] == "true",
+		SmokeTest:      cliFlags[SMOKE_TEST] == "true",
+		MaxRestarts:    int32(maxRestarts),
+		KeepWarm:       int32(keepWarm),
+		StepPause:      stepPause,
+		Revision:       revision,
+		Colour:         cliFlags[COLOUR],
+	}
+	if cliFlags[CANARY_STEPS] != "" {
+		spec.CanarySteps = deployment.CanarySteps(cliFlags[CANARY_STEPS])
+	}
+	spec.ValuesFiles = invocation.chartValues.files
+	spec.Set = invocation.chartValues.set
+	spec.SetString = invocation.chartValues.setString
+	spec.ShowValues = cliFlags[SHOW_VALUES] == "true"
+	spec.BuildDependencies = cliFlags[BUILD_DEPS] == "true"
+	spec.VerifyDependencies = cliFlags[VERIFY_DEPS] == "true"
+	return spec
 }
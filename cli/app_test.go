@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_Run_Returns_Validation_Error_When_Command_Missing(t *testing.T) {
+	os.Args = []string{"helm-deployer"}
+	assert.Equal(t, runtime.EXIT_CODE_VALIDATION, runtime.ExitCode(Run()))
+}
+
+func Test_Run_Returns_Validation_Error_When_Command_Unknown(t *testing.T) {
+	os.Args = []string{"helm-deployer", "poop"}
+	assert.Equal(t, runtime.EXIT_CODE_VALIDATION, runtime.ExitCode(Run()))
+}
+
+func Test_Run_Returns_Validation_Error_When_Flags_Invalid(t *testing.T) {
+	os.Args = []string{"helm-deployer", Command.ROLLBACK, "-app-name", "Not Valid!", "-target-env", "prod"}
+	assert.Equal(t, runtime.EXIT_CODE_VALIDATION, runtime.ExitCode(Run()))
+}
+
+func Test_DeploySpec_Reads_Deploy_Flags(t *testing.T) {
+	spec := newInvocation(Command.BLUEGREEN).deploySpec(map[string]string{
+		APP_NAME:          "some-api",
+		TARGET_ENV:        "prod",
+		FAIL_ON_NO_CHANGE: "true",
+		MAX_RESTARTS:      "2",
+		KEEP_WARM:         "1",
+		CANARY_STEPS:      "10,50,100",
+		STEP_PAUSE:        "30s",
+		BUILD_DEPS:        "true",
+		VERIFY_DEPS:       "false",
+	})
+	assert.Equal(t, "some-api", spec.AppName)
+	assert.Equal(t, "prod", spec.TargetEnv)
+	assert.True(t, spec.FailOnNoChange)
+	assert.False(t, spec.SmokeTest)
+	assert.Equal(t, int32(2), spec.MaxRestarts)
+	assert.Equal(t, int32(1), spec.KeepWarm)
+	assert.Equal(t, []int{10, 50, 100}, spec.CanarySteps)
+	assert.Equal(t, 30*time.Second, spec.StepPause)
+	assert.Equal(t, 0, spec.Revision)
+	assert.True(t, spec.BuildDependencies)
+	assert.False(t, spec.VerifyDependencies)
+	assert.Nil(t, spec.ValuesFiles)
+	assert.Nil(t, spec.Set)
+	assert.False(t, spec.ShowValues)
+}
+
+func Test_DeploySpec_Reads_Each_Repeated_Values_Flag_Verbatim(t *testing.T) {
+	dir := t.TempDir()
+	secrets, overrides := filepath.Join(dir, "secrets,prod.yaml"), filepath.Join(dir, "overrides.yaml")
+	assert.Nil(t, ioutil.WriteFile(secrets, []byte("replicas: 2\n"), 0644))
+	assert.Nil(t, ioutil.WriteFile(overrides, []byte("replicas: 3\n"), 0644))
+	os.Args = []string{"helm-deployer", "bluegreen",
+		"-values", secrets, "-values", overrides,
+		"-set", "image.tag=v1.2.3,replicas=2", "-set", "url=https://example.com/",
+		"-set-string", "build=0123",
+		"-show-values", "true"}
+	valuesFlags := ValuesFlags()
+	cliFlags, err := ParseFlags(valuesFlags)
+	assert.Nil(t, err)
+	invocation := newInvocation(Command.BLUEGREEN)
+	invocation.startChartValues(valuesFlags)
+
+	spec := invocation.deploySpec(cliFlags)
+	assert.Equal(t, []string{secrets, overrides}, spec.ValuesFiles)
+	assert.Equal(t, []string{"image.tag=v1.2.3,replicas=2", "url=https://example.com/"}, spec.Set)
+	assert.Equal(t, []string{"build=0123"}, spec.SetString)
+	assert.True(t, spec.ShowValues)
+}
+
+func Test_DeploySpec_Does_Not_Carry_Values_Over_From_An_Earlier_Run(t *testing.T) {
+	os.Args = []string{"helm-deployer", "bluegreen", "-set", "replicas=2"}
+	earlierFlags := ValuesFlags()
+	_, err := ParseFlags(earlierFlags)
+	assert.Nil(t, err)
+	earlier := newInvocation(Command.BLUEGREEN)
+	earlier.startChartValues(earlierFlags)
+
+	os.Args = []string{"helm-deployer", "bluegreen"}
+	laterFlags := ValuesFlags()
+	cliFlags, err := ParseFlags(laterFlags)
+	assert.Nil(t, err)
+	later := newInvocation(Command.BLUEGREEN)
+	later.startChartValues(laterFlags)
+
+	assert.Nil(t, later.deploySpec(cliFlags).Set)
+	assert.Equal(t, []string{"replicas=2"}, earlier.deploySpec(cliFlags).Set)
+}
@@ -1,19 +1,15 @@
 package cli
 
 import (
-	"errors"
-	"fmt"
+	"context"
 	"log"
 
-	"github.com/Hutchison-Technologies/helm-deployer/charts"
 	"github.com/Hutchison-Technologies/helm-deployer/deployment"
 	"github.com/Hutchison-Technologies/helm-deployer/filesystem"
-	"github.com/Hutchison-Technologies/helm-deployer/k8s"
-	"github.com/Hutchison-Technologies/helm-deployer/runtime"
 )
 
 func BlueGreenFlags() []*Flag {
-	return []*Flag{
+	return append([]*Flag{
 		&Flag{
 			Key:         CHART_DIR,
 			Default:     "./chart",
@@ -35,116 +31,56 @@ func BlueGreenFlags() []*Flag {
 		&Flag{
 			Key:         TARGET_ENV,
 			Default:     "",
-			Description: "name of the environment in which to deploy the service (prod or staging).",
+			Description: "name of the environment in which to deploy the service (one of those configured in helm-deployer.yaml, prod or staging by default).",
 			Validator:   deployment.IsValidTargetEnv,
 		},
-	}
+		&Flag{
+			Key:         SMOKE_TEST,
+			Default:     "false",
+			Description: "whether to request the chart's bluegreen.deployment.live_probe_path through the offline service before cutover (true or false).",
+			Validator:   deployment.IsValidBoolean,
+		},
+		&Flag{
+			Key:         MAX_RESTARTS,
+			Default:     "0",
+			Description: "number of container restarts tolerated per pod of the new colour before cutover (0 or more).",
+			Validator:   deployment.IsValidCount,
+		},
+		&Flag{
+			Key:         KEEP_WARM,
+			Default:     "0",
+			Description: "number of replicas to keep running in the offline colour after cutover (0 or more).",
+			Validator:   deployment.IsValidCount,
+		},
+		FailOnNoChangeFlag(),
+	}, append(ChartFlags(), append(DiagnosticsFlags(), CommonFlags()...)...)...)
 }
 
-func RunBlueGreenDeploy() error {
+func RunBlueGreenDeploy(ctx context.Context, invocation *invocation) error {
 	log.Println("Parsing CLI flags..")
-	cliFlags := parseCLIFlags(BlueGreenFlags())
+	commandFlags := BlueGreenFlags()
+	cliFlags, err := parseCLIFlags(commandFlags)
+	if err != nil {
+		return err
+	}
 	log.Println("Successfully parsed CLI flags:")
 	PrintMap(cliFlags)
-
-	log.Println("Asserting that this is a bluegreen microservice chart..")
-	assertChartIsBlueGreen(cliFlags[CHART_DIR])
-	log.Println("This is a bluegreen microservice chart!")
-
-	log.Println("Determining deploy colour..")
-	deployColour := determineDeployColour(cliFlags[TARGET_ENV], cliFlags[APP_NAME])
-	log.Printf("Determined deploy colour: %s", Green(deployColour))
-
-	log.Println("Loading chart values..")
-	chartValuesYaml := loadChartValues(cliFlags[CHART_DIR], cliFlags[TARGET_ENV])
-	log.Println("Successfully loaded chart values")
-
-	log.Println("Configuring helm...")
-	helmConfig := buildHelmConfig()
-	log.Println("Successfully configured helm!")
-
-	deploymentName := deployment.BlueGreenDeploymentName(cliFlags[TARGET_ENV], deployColour, cliFlags[APP_NAME]) //
-	log.Printf("Preparing to deploy %s..", Green(deploymentName))
-	deployedRelease := releaseWithValues(
-		deploymentName,
-		chartValuesYaml,
-		deployment.ChartValuesForDeployment(deployColour, cliFlags[APP_VERSION]),
-		helmConfig,
-		cliFlags[CHART_DIR])
-
-	log.Println("Now updating the online deployment replica set to a minimum of 1.")
-	scaleOnlineReplicaSetResult := scaleReplicaSet(deploymentName, 1)
-	if scaleOnlineReplicaSetResult != nil {
-		panic(fmt.Errorf("Failed to scale replica set HPA: %v", scaleOnlineReplicaSetResult))
+	invocation.startPhases(cliFlags)
+	invocation.startResult(cliFlags)
+	invocation.startChartValues(commandFlags)
+	invocation.startDiagnostics(cliFlags)
+	invocation.startDependencies(cliFlags)
+
+	log.Println("Loading environment config..")
+	if err := invocation.loadEnvironment(cliFlags); err != nil {
+		return err
 	}
-	log.Printf("Successfully deployed %s", Green(deploymentName))
-	PrintRelease(deployedRelease)
+	log.Println("Successfully loaded environment config")
+	invocation.notifyStarted()
 
-	log.Println("For the deployment to go live, the service selector colour will be updated")
-	serviceDeploymentName := deployment.ServiceReleaseName(cliFlags[TARGET_ENV], cliFlags[APP_NAME])
-	log.Printf("Preparing to deploy %s..", Green(serviceDeploymentName))
-	deployedServiceRelease := releaseWithValues(
-		serviceDeploymentName,
-		chartValuesYaml,
-		deployment.ChartValuesForServiceRelease(deployColour),
-		helmConfig,
-		cliFlags[CHART_DIR])
-	log.Printf("Successfully deployed %s, the service is now live!", Green(serviceDeploymentName))
-	PrintRelease(deployedServiceRelease)
-
-	log.Println("To reduce costing, number of pods in offline deployments will now be scaled to zero.")
-	currentOfflineColour := determineDeployColour(cliFlags[TARGET_ENV], cliFlags[APP_NAME])
-	log.Printf("Offline colour is %s", currentOfflineColour)
-
-	// Build strings for offline deployment and autoscaler
-	offlineDeploymentName := deployment.BlueGreenDeploymentName(cliFlags[TARGET_ENV], currentOfflineColour, cliFlags[APP_NAME])
-	offlineHPAName := fmt.Sprintf("%s-hpa", offlineDeploymentName)
-
-	log.Printf("We will first remove the Horizontal Pod Autoscaler (%s) from the offline service.", offlineHPAName)
-	deletionResult := deleteHPA(offlineHPAName)
-	if deletionResult != nil {
-		log.Printf("Failed to delete HPA: %v", deletionResult)
-		log.Println("This can happen if this is a  first deployment; skipping.")
-	}
-
-	log.Println("Now updating the offline service replica set to zero.")
-	scaleReplicaSetResult := scaleReplicaSet(offlineDeploymentName, 0)
-	if scaleReplicaSetResult != nil {
-		log.Printf("Failed to scale replica set HPA: %v", scaleReplicaSetResult)
-		log.Println("This can happen if this is a  first deployment; skipping.")
-	}
-	log.Println("Updates complete!")
-
-	return nil
-}
-
-func assertChartIsBlueGreen(chartDir string) {
-	chartYamlPath := charts.ChartYamlPath(chartDir)
-	log.Printf("Checking %s for blue-green-microservice dependency..", Green(chartYamlPath))
-	hasBlueGreenDependency := charts.HasDependency(chartYamlPath, "blue-green-microservice", "bluegreen")
-	if !hasBlueGreenDependency {
-		runtime.PanicIfError(errors.New(fmt.Sprintf("Dependency %s must be present and aliased to %s in the %s file in order to deploy using this program.", Green("blue-green-microservice"), Green("bluegreen"), Green(chartYamlPath))))
-	}
-}
-
-func determineDeployColour(targetEnv, appName string) string {
-	log.Println("Initialising kubectl..")
-	kubeClient := kubeCtlClient()
-	log.Println("Successfully initialised kubectl")
-
-	log.Printf("Getting the offline service of %s in %s", Green(appName), Green(targetEnv))
-	offlineService, err := deployment.GetOfflineService(kubeClient, targetEnv, appName)
+	bluegreenDeployer, err := invocation.newDeployer()
 	if err != nil {
-		log.Println(err.Error())
-	}
-	if err != nil || offlineService == nil {
-		log.Printf("Unable to locate offline service, this might be the first deploy, defaulting to: %s", Green(DEFAULT_COLOUR))
-	} else {
-		log.Printf("Found offline service %s, checking selector colour..", Green(offlineService.GetName()))
-		offlineColour := k8s.ServiceSelectorColour(offlineService)
-		if offlineColour != "" {
-			return offlineColour
-		}
+		return err
 	}
-	return DEFAULT_COLOUR
+	return invocation.recordReport(bluegreenDeployer.DeployBlueGreen(ctx, invocation.deploySpec(cliFlags)))
 }
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"context"
+	"log"
+
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/filesystem"
+)
+
+func CanaryFlags() []*Flag {
+	return append([]*Flag{
+		&Flag{
+			Key:         CHART_DIR,
+			Default:     "./chart",
+			Description: "directory containing the service-to-be-deployed's chart definition.",
+			Validator:   filesystem.IsDirectory,
+		},
+		&Flag{
+			Key:         APP_NAME,
+			Default:     "",
+			Description: "name of the service-to-be-deployed (lower-case, alphanumeric + dashes).",
+			Validator:   deployment.IsValidAppName,
+		},
+		&Flag{
+			Key:         APP_VERSION,
+			Default:     "",
+			Description: "semantic version of the service-to-be-deployed (vX.X.X, or X.X.X).",
+			Validator:   deployment.IsValidAppVersion,
+		},
+		&Flag{
+			Key:         TARGET_ENV,
+			Default:     "",
+			Description: "name of the environment in which to deploy the service (one of those configured in helm-deployer.yaml, prod or staging by default).",
+			Validator:   deployment.IsValidTargetEnv,
+		},
+		&Flag{
+			Key:         CANARY_STEPS,
+			Default:     deployment.DEFAULT_CANARY_STEPS,
+			Description: "comma-separated percentages of traffic to shift to the canary, rising to 100.",
+			Validator:   deployment.IsValidCanarySteps,
+		},
+		&Flag{
+			Key:         STEP_PAUSE,
+			Default:     "60s",
+			Description: "how long to watch the canary at each step before checking its health and moving on.",
+			Validator:   deployment.IsValidDuration,
+		},
+		&Flag{
+			Key:         MAX_RESTARTS,
+			Default:     "0",
+			Description: "number of container restarts tolerated per pod of the canary at each step (0 or more).",
+			Validator:   deployment.IsValidCount,
+		},
+		&Flag{
+			Key:         KEEP_WARM,
+			Default:     "0",
+			Description: "number of replicas to keep running in the stable colour once the canary is live (0 or more).",
+			Validator:   deployment.IsValidCount,
+		},
+		FailOnNoChangeFlag(),
+	}, append(ChartFlags(), append(DiagnosticsFlags(), CommonFlags()...)...)...)
+}
+
+func RunCanaryDeploy(ctx context.Context, invocation *invocation) error {
+	log.Println("Parsing CLI flags..")
+	commandFlags := CanaryFlags()
+	cliFlags, err := parseCLIFlags(commandFlags)
+	if err != nil {
+		return err
+	}
+	log.Println("Successfully parsed CLI flags:")
+	PrintMap(cliFlags)
+	invocation.startPhases(cliFlags)
+	invocation.startResult(cliFlags)
+	invocation.startChartValues(commandFlags)
+	invocation.startDiagnostics(cliFlags)
+	invocation.startDependencies(cliFlags)
+
+	log.Println("Loading environment config..")
+	if err := invocation.loadEnvironment(cliFlags); err != nil {
+		return err
+	}
+	log.Println("Successfully loaded environment config")
+	invocation.notifyStarted()
+
+	canaryDeployer, err := invocation.newDeployer()
+	if err != nil {
+		return err
+	}
+	return invocation.recordReport(canaryDeployer.DeployCanary(ctx, invocation.deploySpec(cliFlags)))
+}
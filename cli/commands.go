@@ -7,6 +7,10 @@ type list struct {
 	BLUEGREEN      alias
 	STANDARD_CHART alias
 	MICROSERVICE   alias
+	ROLLBACK       alias
+	SWAP           alias
+	PLAN           alias
+	CANARY         alias
 }
 
 var Command = &list{
@@ -14,6 +18,10 @@ var Command = &list{
 	BLUEGREEN:      "bluegreen",
 	STANDARD_CHART: "standard-chart",
 	MICROSERVICE:   "microservice",
+	ROLLBACK:       "rollback",
+	SWAP:           "swap",
+	PLAN:           "plan",
+	CANARY:         "canary",
 }
 
 func DetermineCommand(command string) string {
@@ -24,7 +32,19 @@ func DetermineCommand(command string) string {
 		return Command.STANDARD_CHART
 	case Command.MICROSERVICE:
 		return Command.MICROSERVICE
+	case Command.ROLLBACK:
+		return Command.ROLLBACK
+	case Command.SWAP:
+		return Command.SWAP
+	case Command.PLAN:
+		return Command.PLAN
+	case Command.CANARY:
+		return Command.CANARY
 	default:
 		return Command.UNKNOWN
 	}
 }
+
+func IsDeployCommand(command string) bool {
+	return command == Command.BLUEGREEN || command == Command.STANDARD_CHART || command == Command.MICROSERVICE || command == Command.CANARY
+}
@@ -21,3 +21,34 @@ func Test_DetermineCommand_Returns_BLUEGREEN_When_Given_Bluegreen_String(t *test
 func Test_DetermineCommand_Returns_STANDARD_CHART_When_Given_standard_chart_String(t *testing.T) {
 	assert.Equal(t, Command.STANDARD_CHART, DetermineCommand("standard-chart"))
 }
+
+func Test_DetermineCommand_Returns_ROLLBACK_When_Given_Rollback_String(t *testing.T) {
+	assert.Equal(t, Command.ROLLBACK, DetermineCommand("rollback"))
+}
+
+func Test_DetermineCommand_Returns_SWAP_When_Given_Swap_String(t *testing.T) {
+	assert.Equal(t, Command.SWAP, DetermineCommand("swap"))
+}
+
+func Test_DetermineCommand_Returns_PLAN_When_Given_Plan_String(t *testing.T) {
+	assert.Equal(t, Command.PLAN, DetermineCommand("plan"))
+}
+
+func Test_DetermineCommand_Returns_CANARY_When_Given_Canary_String(t *testing.T) {
+	assert.Equal(t, Command.CANARY, DetermineCommand("canary"))
+}
+
+func Test_IsDeployCommand_Returns_True_For_Deploy_Commands(t *testing.T) {
+	assert.True(t, IsDeployCommand(Command.BLUEGREEN))
+	assert.True(t, IsDeployCommand(Command.STANDARD_CHART))
+	assert.True(t, IsDeployCommand(Command.MICROSERVICE))
+	assert.True(t, IsDeployCommand(Command.CANARY))
+}
+
+func Test_IsDeployCommand_Returns_False_For_Other_Commands(t *testing.T) {
+	assert.False(t, IsDeployCommand(""))
+	assert.False(t, IsDeployCommand(Command.UNKNOWN))
+	assert.False(t, IsDeployCommand(Command.ROLLBACK))
+	assert.False(t, IsDeployCommand(Command.SWAP))
+	assert.False(t, IsDeployCommand(Command.PLAN))
+}
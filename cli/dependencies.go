@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/filesystem"
+)
+
+type dependencyOptions struct {
+	repositoryConfig string
+	repositoryCache  string
+}
+
+// ChartFlags are the flags of the commands that load a chart.
+func ChartFlags() []*Flag {
+	return append(ValuesFlags(), DependencyFlags()...)
+}
+
+func DependencyFlags() []*Flag {
+	return []*Flag{
+		&Flag{
+			Key:         BUILD_DEPS,
+			Default:     "false",
+			Description: "whether to vendor the chart's dependencies from its Chart.lock with helm dependency build before loading it, reading the repository indexes from the repository cache (true or false).",
+			Validator:   deployment.IsValidBoolean,
+		},
+		&Flag{
+			Key:         VERIFY_DEPS,
+			Default:     "false",
+			Description: "whether to check that the chart's Chart.lock is up to date and each archive it resolved is vendored in charts/, fetching nothing (true or false).",
+			Validator:   deployment.IsValidBoolean,
+		},
+		&Flag{
+			Key:         REPOSITORY_CONFIG,
+			Default:     "",
+			Description: "path to the helm repositories.yaml used to build dependencies ($HELM_REPOSITORY_CONFIG or helm's default when not given).",
+			Validator:   filesystem.IsFile,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         REPOSITORY_CACHE,
+			Default:     "",
+			Description: "directory of the helm repository cache whose indexes are used to build dependencies ($HELM_REPOSITORY_CACHE or helm's default when not given).",
+			Validator:   filesystem.IsDirectory,
+			Optional:    true,
+		},
+	}
+}
+
+// startDependencies records where helm's repositories are for building
+// dependencies.
+func (invocation *invocation) startDependencies(cliFlags map[string]string) {
+	invocation.dependencies.repositoryConfig = cliFlags[REPOSITORY_CONFIG]
+	invocation.dependencies.repositoryCache = cliFlags[REPOSITORY_CACHE]
+}
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"strconv"
+
+	"github.com/Hutchison-Technologies/helm-deployer/deployer"
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/filesystem"
+)
+
+type diagnosticsOptions struct {
+	artifactsDir string
+	logLines     int64
+}
+
+func DiagnosticsFlags() []*Flag {
+	return []*Flag{
+		&Flag{
+			Key:         ARTIFACTS_DIR,
+			Default:     "",
+			Description: "directory to write the pods, events and container logs of a failed release to.",
+			Validator:   filesystem.IsDirectory,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         FAILURE_LOG_LINES,
+			Default:     strconv.Itoa(deployer.DEFAULT_FAILURE_LOG_LINES),
+			Description: "number of log lines to collect from each crashing container of a failed release.",
+			Validator:   deployment.IsValidCount,
+		},
+	}
+}
+
+// startDiagnostics records where and how much to collect when a release fails.
+func (invocation *invocation) startDiagnostics(cliFlags map[string]string) {
+	invocation.diagnostics.artifactsDir = cliFlags[ARTIFACTS_DIR]
+	if logLines, err := strconv.ParseInt(cliFlags[FAILURE_LOG_LINES], 10, 64); err == nil {
+		invocation.diagnostics.logLines = logLines
+	}
+}
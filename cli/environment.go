@@ -0,0 +1,243 @@
+package cli
+
+import (
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/Hutchison-Technologies/helm-deployer/config"
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/filesystem"
+	"github.com/Hutchison-Technologies/helm-deployer/notify"
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+)
+
+func EnvironmentFlags() []*Flag {
+	return []*Flag{
+		&Flag{
+			Key:         NAMESPACE,
+			Default:     "",
+			Description: "kubernetes namespace to deploy into, overrides the environment's namespace (default when not configured).",
+			Validator:   deployment.IsValidNamespace,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         CREATE_NAMESPACE,
+			Default:     "",
+			Description: "whether to create the namespace on first install (true or false), overrides the environment's create_namespace.",
+			Validator:   deployment.IsValidBoolean,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         KUBECONFIG,
+			Default:     "",
+			Description: "path to the kubeconfig to deploy with, overrides the environment's kubeconfig ($KUBECONFIG or ~/.kube/config, or the in-cluster service account, when not configured).",
+			Validator:   filesystem.IsFile,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         KUBE_CONTEXT,
+			Default:     "",
+			Description: "kubeconfig context to deploy with, overrides the environment's kube_context (the current context when not configured).",
+			Validator:   deployment.IsValidKubeContext,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         WEBHOOK_URL,
+			Default:     "",
+			Description: "comma-separated webhook URLs to notify of the deploy, in addition to the environment's webhooks.",
+			Validator:   deployment.IsValidWebhookURLs,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         INSTALL_TIMEOUT,
+			Default:     "",
+			Description: "how long to wait for an install (a duration such as 300s), overrides the environment's install_timeout.",
+			Validator:   deployment.IsValidDuration,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         UPGRADE_TIMEOUT,
+			Default:     "",
+			Description: "how long to wait for an upgrade (a duration such as 300s), overrides the environment's upgrade_timeout.",
+			Validator:   deployment.IsValidDuration,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         ROLLBACK_TIMEOUT,
+			Default:     "",
+			Description: "how long to wait for a rollback (a duration such as 900s), overrides the environment's rollback_timeout.",
+			Validator:   deployment.IsValidDuration,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         FORCE,
+			Default:     "",
+			Description: "whether upgrades and rollbacks force resource updates (true or false), overrides the environment's force.",
+			Validator:   deployment.IsValidBoolean,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         RECREATE,
+			Default:     "",
+			Description: "whether upgrades and rollbacks recreate pods (true or false), overrides the environment's recreate.",
+			Validator:   deployment.IsValidBoolean,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         ATOMIC,
+			Default:     "",
+			Description: "whether helm itself rolls back a failed install or upgrade (true or false), overrides the environment's atomic.",
+			Validator:   deployment.IsValidBoolean,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         WAIT_FOR_JOBS,
+			Default:     "",
+			Description: "whether to wait for the chart's jobs to complete (true or false), overrides the environment's wait_for_jobs.",
+			Validator:   deployment.IsValidBoolean,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         MAX_HISTORY,
+			Default:     "",
+			Description: "number of revisions to keep per release (0 for unlimited), overrides the environment's max_history.",
+			Validator:   deployment.IsValidCount,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         CLEANUP_ON_FAIL,
+			Default:     "",
+			Description: "whether to delete resources created by a failed upgrade or rollback (true or false), overrides the environment's cleanup_on_fail.",
+			Validator:   deployment.IsValidBoolean,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         RECOVER_PENDING,
+			Default:     "",
+			Description: "whether to recover a release left pending for longer than the pending timeout before deploying (true or false), overrides the environment's recover_pending.",
+			Validator:   deployment.IsValidBoolean,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         PENDING_TIMEOUT,
+			Default:     "",
+			Description: "how long a release can be pending before it is taken to be stuck (a duration such as 1800s), overrides the environment's pending_timeout.",
+			Validator:   deployment.IsValidDuration,
+			Optional:    true,
+		},
+	}
+}
+
+// loadEnvironment loads the target environment, overridden by the flags, and
+// the notifier of its webhooks.
+func (invocation *invocation) loadEnvironment(cliFlags map[string]string) error {
+	deployerConfig, err := config.LoadForChart(cliFlags[CHART_DIR])
+	if err != nil {
+		return runtime.ValidationError(err)
+	}
+	log.Printf("Using environments from %s", Green(deployerConfig.Source()))
+
+	targetEnvironment, err := deployerConfig.Environment(cliFlags[TARGET_ENV])
+	if err != nil {
+		return runtime.ValidationError(err)
+	}
+	if cliFlags[NAMESPACE] != "" {
+		targetEnvironment.Namespace = cliFlags[NAMESPACE]
+	}
+	if cliFlags[CREATE_NAMESPACE] != "" {
+		targetEnvironment.CreateNamespace = cliFlags[CREATE_NAMESPACE] == "true"
+	}
+	if cliFlags[KUBECONFIG] != "" {
+		targetEnvironment.Kubeconfig = cliFlags[KUBECONFIG]
+	}
+	if cliFlags[KUBE_CONTEXT] != "" {
+		targetEnvironment.KubeContext = cliFlags[KUBE_CONTEXT]
+	}
+	applyReleaseOptionFlags(targetEnvironment, cliFlags)
+	if cliFlags[WEBHOOK_URL] != "" {
+		for _, webhookURL := range strings.Split(cliFlags[WEBHOOK_URL], ",") {
+			targetEnvironment.Webhooks = append(targetEnvironment.Webhooks, &notify.Webhook{URL: webhookURL})
+		}
+	}
+	invocation.environment = targetEnvironment
+	if len(targetEnvironment.Webhooks) > 0 {
+		invocation.notifier = notify.NewNotifier(targetEnvironment.Webhooks, log.Printf)
+	}
+	PrintEnvironment(targetEnvironment)
+	return nil
+}
+
+func applyReleaseOptionFlags(env *config.Environment, cliFlags map[string]string) {
+	if cliFlags[INSTALL_TIMEOUT] != "" {
+		env.InstallTimeout = cliFlags[INSTALL_TIMEOUT]
+	}
+	if cliFlags[UPGRADE_TIMEOUT] != "" {
+		env.UpgradeTimeout = cliFlags[UPGRADE_TIMEOUT]
+	}
+	if cliFlags[ROLLBACK_TIMEOUT] != "" {
+		env.RollbackTimeout = cliFlags[ROLLBACK_TIMEOUT]
+	}
+	if cliFlags[FORCE] != "" {
+		force := cliFlags[FORCE] == "true"
+		env.Force = &force
+	}
+	if cliFlags[RECREATE] != "" {
+		recreate := cliFlags[RECREATE] == "true"
+		env.Recreate = &recreate
+	}
+	if cliFlags[ATOMIC] != "" {
+		env.Atomic = cliFlags[ATOMIC] == "true"
+	}
+	if cliFlags[WAIT_FOR_JOBS] != "" {
+		waitForJobs := cliFlags[WAIT_FOR_JOBS] == "true"
+		env.WaitForJobs = &waitForJobs
+	}
+	if cliFlags[MAX_HISTORY] != "" {
+		env.MaxHistory, _ = strconv.Atoi(cliFlags[MAX_HISTORY])
+	}
+	if cliFlags[CLEANUP_ON_FAIL] != "" {
+		env.CleanupOnFail = cliFlags[CLEANUP_ON_FAIL] == "true"
+	}
+	if cliFlags[RECOVER_PENDING] != "" {
+		recoverPending := cliFlags[RECOVER_PENDING] == "true"
+		env.RecoverPending = &recoverPending
+	}
+	if cliFlags[PENDING_TIMEOUT] != "" {
+		env.PendingTimeout = cliFlags[PENDING_TIMEOUT]
+	}
+}
+
+func PrintEnvironment(env *config.Environment) {
+	PrintMap(map[string]string{
+		"environment":      env.Name,
+		"namespace":        env.Namespace,
+		"create_namespace": strconv.FormatBool(env.CreateNamespace),
+		"kubeconfig":       env.Kubeconfig,
+		"kube_context":     env.KubeContext,
+		"install_timeout":  env.InstallTimeout,
+		"upgrade_timeout":  env.UpgradeTimeout,
+		"rollback_timeout": env.RollbackTimeout,
+		"diff_check":       strconv.FormatBool(env.RequiresDiffCheck()),
+		"force":            strconv.FormatBool(env.UsesForce()),
+		"recreate":         strconv.FormatBool(env.UsesRecreate()),
+		"atomic":           strconv.FormatBool(env.Atomic),
+		"wait_for_jobs":    waitForJobsSummary(env),
+		"max_history":      strconv.Itoa(env.MaxHistory),
+		"cleanup_on_fail":  strconv.FormatBool(env.CleanupOnFail),
+		"recover_pending":  strconv.FormatBool(env.RecoversPending()),
+		"pending_timeout":  env.PendingTimeout,
+		"required_values":  strings.Join(env.RequiredValues, ","),
+		"webhooks":         strconv.Itoa(len(env.Webhooks)),
+
+		"min_blue_green_microservice_version": env.MinBlueGreenMicroserviceVersion,
+		"min_microservice_version":            env.MinMicroserviceVersion,
+	})
+}
+
+func waitForJobsSummary(env *config.Environment) string {
+	if env.InstallWaitsForJobs() != env.WaitsForJobs() {
+		return "installs only"
+	}
+	return strconv.FormatBool(env.WaitsForJobs())
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"github.com/Hutchison-Technologies/helm-deployer/config"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_ApplyReleaseOptionFlags_Leaves_Environment_When_No_Flags_Given(t *testing.T) {
+	env := &config.Environment{InstallTimeout: "300s", UpgradeTimeout: "300s", RollbackTimeout: "900s", MaxHistory: 5}
+	applyReleaseOptionFlags(env, map[string]string{})
+	assert.Equal(t, 300*time.Second, env.UpgradeTimeoutDuration())
+	assert.Equal(t, 5, env.MaxHistory)
+	assert.True(t, env.UsesForce())
+	assert.True(t, env.UsesRecreate())
+	assert.True(t, env.InstallWaitsForJobs())
+	assert.False(t, env.WaitsForJobs())
+	assert.False(t, env.Atomic)
+	assert.True(t, env.RecoversPending())
+}
+
+func Test_ApplyReleaseOptionFlags_Makes_Upgrades_Wait_For_Jobs_When_Turned_On(t *testing.T) {
+	env := &config.Environment{InstallTimeout: "300s", UpgradeTimeout: "300s", RollbackTimeout: "900s"}
+	applyReleaseOptionFlags(env, map[string]string{WAIT_FOR_JOBS: "true"})
+	assert.True(t, env.InstallWaitsForJobs())
+	assert.True(t, env.WaitsForJobs())
+}
+
+func Test_ApplyReleaseOptionFlags_Overrides_Environment(t *testing.T) {
+	env := &config.Environment{InstallTimeout: "300s", UpgradeTimeout: "300s", RollbackTimeout: "900s"}
+	applyReleaseOptionFlags(env, map[string]string{
+		INSTALL_TIMEOUT:  "1m",
+		UPGRADE_TIMEOUT:  "2m",
+		ROLLBACK_TIMEOUT: "3m",
+		FORCE:            "false",
+		RECREATE:         "false",
+		ATOMIC:           "true",
+		WAIT_FOR_JOBS:    "false",
+		MAX_HISTORY:      "10",
+		CLEANUP_ON_FAIL:  "true",
+		RECOVER_PENDING:  "false",
+		PENDING_TIMEOUT:  "1h",
+	})
+	assert.Equal(t, time.Minute, env.InstallTimeoutDuration())
+	assert.Equal(t, 2*time.Minute, env.UpgradeTimeoutDuration())
+	assert.Equal(t, 3*time.Minute, env.RollbackTimeoutDuration())
+	assert.False(t, env.UsesForce())
+	assert.False(t, env.UsesRecreate())
+	assert.True(t, env.Atomic)
+	assert.False(t, env.InstallWaitsForJobs())
+	assert.False(t, env.WaitsForJobs())
+	assert.Equal(t, 10, env.MaxHistory)
+	assert.True(t, env.CleanupOnFail)
+	assert.False(t, env.RecoversPending())
+	assert.Equal(t, time.Hour, env.PendingTimeoutDuration())
+}
@@ -13,19 +13,69 @@ type Flag struct {
 	Default     string
 	Description string
 	Validator   func(string) bool
-	Value       *string
+	Optional    bool
+	// Repeatable flags can be given more than once. Each value is validated
+	// and appended, verbatim, to Values.
+	Repeatable bool
+	Value      *string
+	Values     *[]string
 }
 
+// repeatedValue appends each use of a repeatable flag to values.
+type repeatedValue struct {
+	values *[]string
+}
+
+func (repeated *repeatedValue) String() string {
+	if repeated.values == nil {
+		return ""
+	}
+	return fmt.Sprintf("%q", *repeated.values)
+}
+
+func (repeated *repeatedValue) Set(value string) error {
+	*repeated.values = append(*repeated.values, value)
+	return nil
+}
+
+// ParseFlags parses the command's flags, returning each flag's value by key.
+// The values of a repeatable flag are only quoted into the map for printing,
+// they are read from its Values.
 func ParseFlags(cliFlags []*Flag) (map[string]string, error) {
-	flagSet := flag.NewFlagSet("", flag.ExitOnError)
+	flagSet := flag.NewFlagSet("", flag.ContinueOnError)
 	parsedValues := make(map[string]string)
 	for _, cliFlag := range cliFlags {
+		if cliFlag.Repeatable {
+			if cliFlag.Values == nil {
+				cliFlag.Values = new([]string)
+			}
+			*cliFlag.Values = nil
+			flagSet.Var(&repeatedValue{cliFlag.Values}, cliFlag.Key, cliFlag.Description)
+			continue
+		}
 		cliFlag.Value = flagSet.String(cliFlag.Key, cliFlag.Default, cliFlag.Description)
 	}
-	flagSet.Parse(os.Args[2:])
+	if err := flagSet.Parse(os.Args[2:]); err != nil {
+		return nil, err
+	}
 	errorMessages := make([]string, 0)
 	for _, cliFlag := range cliFlags {
-		if *cliFlag.Value == "" {
+		if cliFlag.Repeatable {
+			if len(*cliFlag.Values) == 0 && cliFlag.Optional {
+				continue
+			} else if len(*cliFlag.Values) == 0 {
+				errorMessages = append(errorMessages, fmt.Sprintf("Missing flag \033[32m-%s\033[97m, must be \033[33m%s\033[97m", cliFlag.Key, cliFlag.Description))
+				continue
+			}
+			for _, value := range *cliFlag.Values {
+				if !cliFlag.Validator(value) {
+					errorMessages = append(errorMessages, fmt.Sprintf("Invalid \033[32m-%s\033[97m: \033[31m%s\033[97m, must be \033[33m%s\033[97m", cliFlag.Key, value, cliFlag.Description))
+				}
+			}
+			parsedValues[cliFlag.Key] = fmt.Sprintf("%q", *cliFlag.Values)
+		} else if *cliFlag.Value == "" && cliFlag.Optional {
+			continue
+		} else if *cliFlag.Value == "" {
 			errorMessages = append(errorMessages, fmt.Sprintf("Missing flag \033[32m-%s\033[97m, must be \033[33m%s\033[97m", cliFlag.Key, cliFlag.Description))
 		} else if !cliFlag.Validator(*cliFlag.Value) {
 			errorMessages = append(errorMessages, fmt.Sprintf("Invalid \033[32m-%s\033[97m: \033[31m%s\033[97m, must be \033[33m%s\033[97m", cliFlag.Key, *cliFlag.Value, cliFlag.Description))
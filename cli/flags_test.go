@@ -3,6 +3,7 @@ package cli
 import (
 	"errors"
 	"github.com/stretchr/testify/assert"
+	"os"
 	"testing"
 )
 
@@ -31,3 +32,57 @@ func Test_HandleParseFlags_Returns_Given_Flags(t *testing.T) {
 	assert.Equal(t, someFlags, returnedFlags)
 	assert.Nil(t, err)
 }
+
+func Test_ParseFlags_Omits_Optional_Flag_When_Not_Given(t *testing.T) {
+	os.Args = []string{"helm-deployer", "rollback", "-required", "thing"}
+	parsedFlags, err := ParseFlags([]*Flag{
+		&Flag{Key: "required", Validator: func(string) bool { return true }},
+		&Flag{Key: "optional", Validator: func(string) bool { return false }, Optional: true},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"required": "thing"}, parsedFlags)
+}
+
+func Test_ParseFlags_Validates_Optional_Flag_When_Given(t *testing.T) {
+	os.Args = []string{"helm-deployer", "rollback", "-optional", "thing"}
+	_, err := ParseFlags([]*Flag{
+		&Flag{Key: "optional", Validator: func(string) bool { return false }, Optional: true},
+	})
+	assert.NotNil(t, err)
+}
+
+func Test_ParseFlags_Returns_Error_When_Flag_Unknown(t *testing.T) {
+	os.Args = []string{"helm-deployer", "rollback", "-unknown", "thing"}
+	_, err := ParseFlags([]*Flag{
+		&Flag{Key: "required", Validator: func(string) bool { return true }},
+	})
+	assert.NotNil(t, err)
+}
+
+func Test_ParseFlags_Collects_Each_Repeated_Flag_Value(t *testing.T) {
+	os.Args = []string{"helm-deployer", "deploy", "-set", "image.tag=v1,replicas=2", "-set", "url=https://example.com/"}
+	setFlag := &Flag{Key: "set", Validator: func(string) bool { return true }, Optional: true, Repeatable: true}
+	parsedFlags, err := ParseFlags([]*Flag{setFlag})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"image.tag=v1,replicas=2", "url=https://example.com/"}, *setFlag.Values)
+	assert.Contains(t, parsedFlags, "set")
+}
+
+func Test_ParseFlags_Validates_Each_Repeated_Flag_Value(t *testing.T) {
+	os.Args = []string{"helm-deployer", "deploy", "-set", "good", "-set", "bad"}
+	_, err := ParseFlags([]*Flag{
+		&Flag{Key: "set", Validator: func(value string) bool { return value == "good" }, Optional: true, Repeatable: true},
+	})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "bad")
+	assert.NotContains(t, err.Error(), "good")
+}
+
+func Test_ParseFlags_Omits_Repeatable_Flag_When_Not_Given(t *testing.T) {
+	os.Args = []string{"helm-deployer", "deploy"}
+	parsedFlags, err := ParseFlags([]*Flag{
+		&Flag{Key: "set", Validator: func(string) bool { return false }, Optional: true, Repeatable: true},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{}, parsedFlags)
+}
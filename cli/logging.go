@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Hutchison-Technologies/helm-deployer/deployer"
+)
+
+const (
+	LOG_FORMAT_TEXT = "text"
+	LOG_FORMAT_JSON = "json"
+)
+
+const (
+	PHASE_FLAGS_PARSED        = "flags_parsed"
+	PHASE_COLOUR_DETERMINED   = deployer.PHASE_COLOUR_DETERMINED
+	PHASE_RELEASE_DEPLOYED    = deployer.PHASE_RELEASE_DEPLOYED
+	PHASE_RELEASE_UNCHANGED   = deployer.PHASE_RELEASE_UNCHANGED
+	PHASE_RELEASE_ROLLED_BACK = deployer.PHASE_RELEASE_ROLLED_BACK
+	PHASE_RELEASE_RECOVERED   = deployer.PHASE_RELEASE_RECOVERED
+	PHASE_SERVICE_SWITCHED    = deployer.PHASE_SERVICE_SWITCHED
+	PHASE_OFFLINE_SCALED      = deployer.PHASE_OFFLINE_SCALED
+	PHASE_CANARY_STEP         = deployer.PHASE_CANARY_STEP
+)
+
+var logFormat = LOG_FORMAT_TEXT
+
+var colourEnabled = true
+
+// colourWriter strips colour codes from everything written to out when colour
+// is disabled, and wraps each write in a JSON log line in JSON mode.
+type colourWriter struct {
+	out    io.Writer
+	json   bool
+	colour bool
+}
+
+type logLine struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// PhaseEvent is logged, in JSON mode, as each phase of a command completes.
+type PhaseEvent struct {
+	Time       string `json:"time"`
+	Event      string `json:"event"`
+	App        string `json:"app,omitempty"`
+	Env        string `json:"env,omitempty"`
+	Release    string `json:"release,omitempty"`
+	Revision   int    `json:"revision,omitempty"`
+	Colour     string `json:"colour,omitempty"`
+	Weight     int    `json:"weight,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type phaseLogger struct {
+	out   io.Writer
+	app   string
+	env   string
+	start time.Time
+}
+
+func IsValidLogFormat(format string) bool {
+	return format == LOG_FORMAT_TEXT || format == LOG_FORMAT_JSON
+}
+
+// LogFormatFromArgs finds -log-format among args ahead of flag parsing, so
+// that everything logged before then is already in the requested format.
+func LogFormatFromArgs(args []string) string {
+	for i, arg := range args {
+		name := strings.TrimLeft(arg, "-")
+		if name == LOG_FORMAT && i+1 < len(args) {
+			return args[i+1]
+		}
+		if strings.HasPrefix(name, LOG_FORMAT+"=") {
+			return strings.TrimPrefix(name, LOG_FORMAT+"=")
+		}
+	}
+	return LOG_FORMAT_TEXT
+}
+
+// IsTerminal reports whether file is attached to a terminal.
+func IsTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ConfigureLogging sets the log format and disables colour when it would not
+// be rendered, either because stdout isn't a terminal or logs are JSON.
+func ConfigureLogging(format string, terminal bool) {
+	logFormat = format
+	colourEnabled = terminal && format != LOG_FORMAT_JSON
+	if format == LOG_FORMAT_JSON {
+		log.SetFlags(0)
+	} else {
+		log.SetFlags(log.LstdFlags)
+	}
+	log.SetOutput(&colourWriter{out: os.Stderr, json: format == LOG_FORMAT_JSON, colour: colourEnabled})
+}
+
+// StdoutWriter returns stdout, stripped of colour when colour is disabled.
+func StdoutWriter() io.Writer {
+	if colourEnabled {
+		return os.Stdout
+	}
+	return &colourWriter{out: os.Stdout, colour: false}
+}
+
+func (writer *colourWriter) Write(p []byte) (int, error) {
+	message := string(p)
+	if !writer.colour {
+		message = deployer.StripColour(message)
+	}
+	if writer.json {
+		line, err := json.Marshal(logLine{
+			Time:    time.Now().UTC().Format(time.RFC3339Nano),
+			Level:   "info",
+			Message: strings.TrimRight(message, "\n"),
+		})
+		if err != nil {
+			return 0, err
+		}
+		message = string(line) + "\n"
+	}
+	if _, err := io.WriteString(writer.out, message); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// startPhases records the app and environment of the command and logs that the
+// flags were parsed, timed from the start of the process.
+func (invocation *invocation) startPhases(cliFlags map[string]string) {
+	invocation.phases.app = cliFlags[APP_NAME]
+	invocation.phases.env = cliFlags[TARGET_ENV]
+	invocation.logPhase(PhaseEvent{Event: PHASE_FLAGS_PARSED})
+}
+
+// logPhase logs event, in JSON mode, with the time taken since the last phase.
+func (invocation *invocation) logPhase(event PhaseEvent) {
+	phases := invocation.phases
+	now := time.Now()
+	event.Time = now.UTC().Format(time.RFC3339Nano)
+	event.App = phases.app
+	event.Env = phases.env
+	event.DurationMs = now.Sub(phases.start).Milliseconds()
+	phases.start = now
+	invocation.result.recordPhase(event)
+
+	if logFormat != LOG_FORMAT_JSON {
+		return
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to log %s event: %s", event.Event, err)
+		return
+	}
+	phases.out.Write(append(line, '\n'))
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func Test_LogFormatFromArgs_Returns_Text_When_Not_Given(t *testing.T) {
+	assert.Equal(t, LOG_FORMAT_TEXT, LogFormatFromArgs([]string{"bluegreen", "-app-name", "some-api"}))
+}
+
+func Test_LogFormatFromArgs_Returns_Given_Format(t *testing.T) {
+	assert.Equal(t, LOG_FORMAT_JSON, LogFormatFromArgs([]string{"bluegreen", "-log-format", "json"}))
+	assert.Equal(t, LOG_FORMAT_JSON, LogFormatFromArgs([]string{"bluegreen", "-log-format=json"}))
+	assert.Equal(t, LOG_FORMAT_JSON, LogFormatFromArgs([]string{"bluegreen", "--log-format=json"}))
+}
+
+func Test_IsValidLogFormat(t *testing.T) {
+	assert.True(t, IsValidLogFormat("text"))
+	assert.True(t, IsValidLogFormat("json"))
+	assert.False(t, IsValidLogFormat("xml"))
+	assert.False(t, IsValidLogFormat(""))
+}
+
+func Test_ColourWriter_Strips_Colour_When_Disabled(t *testing.T) {
+	out := &bytes.Buffer{}
+	writer := &colourWriter{out: out, colour: false}
+	writer.Write([]byte(Orange("some-release") + "\n"))
+	assert.Equal(t, "some-release\n", out.String())
+}
+
+func Test_ColourWriter_Keeps_Colour_When_Enabled(t *testing.T) {
+	out := &bytes.Buffer{}
+	writer := &colourWriter{out: out, colour: true}
+	writer.Write([]byte(Orange("some-release")))
+	assert.Equal(t, Orange("some-release"), out.String())
+}
+
+func Test_ColourWriter_Writes_JSON_Lines(t *testing.T) {
+	out := &bytes.Buffer{}
+	writer := &colourWriter{out: out, json: true}
+	writer.Write([]byte(Green("Successfully deployed") + "\n"))
+
+	line := logLine{}
+	assert.Nil(t, json.Unmarshal(out.Bytes(), &line))
+	assert.Equal(t, "info", line.Level)
+	assert.Equal(t, "Successfully deployed", line.Message)
+	assert.NotEmpty(t, line.Time)
+}
+
+func Test_LogPhase_Writes_Event_In_JSON_Mode(t *testing.T) {
+	out := &bytes.Buffer{}
+	defer func(previousFormat string) {
+		logFormat = previousFormat
+	}(logFormat)
+	logFormat = LOG_FORMAT_JSON
+	invocation := newInvocation(Command.BLUEGREEN)
+	invocation.phases = &phaseLogger{out: out}
+
+	invocation.startPhases(map[string]string{APP_NAME: "some-api", TARGET_ENV: "prod"})
+	invocation.logPhase(PhaseEvent{Event: PHASE_RELEASE_DEPLOYED, Release: "prod-blue-some-api", Revision: 4, Colour: "blue"})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Equal(t, 2, len(lines))
+
+	event := PhaseEvent{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[1]), &event))
+	assert.Equal(t, PhaseEvent{
+		Time:       event.Time,
+		Event:      PHASE_RELEASE_DEPLOYED,
+		App:        "some-api",
+		Env:        "prod",
+		Release:    "prod-blue-some-api",
+		Revision:   4,
+		Colour:     "blue",
+		DurationMs: event.DurationMs,
+	}, event)
+}
+
+func Test_LogPhase_Writes_Nothing_In_Text_Mode(t *testing.T) {
+	out := &bytes.Buffer{}
+	defer func(previousFormat string) {
+		logFormat = previousFormat
+	}(logFormat)
+	logFormat = LOG_FORMAT_TEXT
+	invocation := newInvocation(Command.BLUEGREEN)
+	invocation.phases = &phaseLogger{out: out}
+
+	invocation.logPhase(PhaseEvent{Event: PHASE_RELEASE_DEPLOYED})
+	assert.Equal(t, "", out.String())
+}
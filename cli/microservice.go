@@ -1,18 +1,15 @@
 package cli
 
 import (
-	"errors"
-	"fmt"
+	"context"
 	"log"
 
-	"github.com/Hutchison-Technologies/helm-deployer/charts"
 	"github.com/Hutchison-Technologies/helm-deployer/deployment"
 	"github.com/Hutchison-Technologies/helm-deployer/filesystem"
-	"github.com/Hutchison-Technologies/helm-deployer/runtime"
 )
 
 func MicroserviceFlags() []*Flag {
-	return []*Flag{
+	return append([]*Flag{
 		&Flag{
 			Key:         CHART_DIR,
 			Default:     "./chart",
@@ -34,49 +31,38 @@ func MicroserviceFlags() []*Flag {
 		&Flag{
 			Key:         TARGET_ENV,
 			Default:     "",
-			Description: "name of the environment in which to deploy the service (prod or staging).",
+			Description: "name of the environment in which to deploy the service (one of those configured in helm-deployer.yaml, prod or staging by default).",
 			Validator:   deployment.IsValidTargetEnv,
 		},
-	}
+		FailOnNoChangeFlag(),
+	}, append(ChartFlags(), append(DiagnosticsFlags(), CommonFlags()...)...)...)
 }
 
-func RunMicroserviceDeploy() error {
+func RunMicroserviceDeploy(ctx context.Context, invocation *invocation) error {
 	log.Println("Parsing CLI flags..")
-	cliFlags := parseCLIFlags(MicroserviceFlags())
+	commandFlags := MicroserviceFlags()
+	cliFlags, err := parseCLIFlags(commandFlags)
+	if err != nil {
+		return err
+	}
 	log.Println("Successfully parsed CLI flags:")
 	PrintMap(cliFlags)
+	invocation.startPhases(cliFlags)
+	invocation.startResult(cliFlags)
+	invocation.startChartValues(commandFlags)
+	invocation.startDiagnostics(cliFlags)
+	invocation.startDependencies(cliFlags)
 
-	log.Println("Asserting that this is a microservice chart..")
-	assertChartIsMicroservice(cliFlags[CHART_DIR])
-	log.Println("This is a microservice chart!")
-
-	log.Println("Loading chart values..")
-	chartValuesYaml := loadChartValues(cliFlags[CHART_DIR], cliFlags[TARGET_ENV])
-	log.Println("Successfully loaded chart values")
-
-	log.Println("Connecting helm config..")
-	helmConfig := buildHelmConfig()
-	log.Println("Successfully configured helm!")
-
-	deploymentName := deployment.StandardChartDeploymentName(cliFlags[TARGET_ENV], cliFlags[APP_NAME])
-	log.Printf("Preparing to deploy %s..", Green(deploymentName))
-	deployedRelease := releaseWithValues(
-		deploymentName,
-		chartValuesYaml,
-		deployment.ChartValuesForMicroserviceDeployment(cliFlags[APP_VERSION]),
-		helmConfig,
-		cliFlags[CHART_DIR])
-	log.Printf("Successfully deployed %s, the service is now live!", Green(deploymentName))
-	PrintRelease(deployedRelease)
-
-	return nil
-}
+	log.Println("Loading environment config..")
+	if err := invocation.loadEnvironment(cliFlags); err != nil {
+		return err
+	}
+	log.Println("Successfully loaded environment config")
+	invocation.notifyStarted()
 
-func assertChartIsMicroservice(chartDir string) {
-	chartYamlPath := charts.ChartYamlPath(chartDir)
-	log.Printf("Checking %s for microservice dependency..", Green(chartYamlPath))
-	hasDependency := charts.HasDependency(chartYamlPath, "microservice", "")
-	if !hasDependency {
-		runtime.PanicIfError(errors.New(fmt.Sprintf("Dependency %s must be present in the %s file in order to deploy using this program.", Green("microservice"), Green(chartYamlPath))))
+	microserviceDeployer, err := invocation.newDeployer()
+	if err != nil {
+		return err
 	}
+	return invocation.recordReport(microserviceDeployer.DeployMicroservice(ctx, invocation.deploySpec(cliFlags)))
 }
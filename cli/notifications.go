@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"github.com/Hutchison-Technologies/helm-deployer/notify"
+)
+
+// notifyEvent sends event, filled in with the details of the run, to the
+// environment's webhooks.
+func (invocation *invocation) notifyEvent(event notify.Event) {
+	if invocation.notifier == nil {
+		return
+	}
+	result := invocation.result
+	event.Command = result.Command
+	event.App = result.App
+	event.Env = result.Env
+	event.Namespace = invocation.environment.Namespace
+	event.Version = result.Version
+	invocation.notifier.Notify(event)
+}
+
+func (invocation *invocation) notifyStarted() {
+	invocation.notifyEvent(notify.Event{Event: notify.EVENT_STARTED})
+}
+
+// notifyFinished reports the outcome of the run, once it is known. Plans
+// change nothing, so are not reported.
+func (invocation *invocation) notifyFinished() {
+	result := invocation.result
+	if result.Command == Command.PLAN {
+		return
+	}
+	event := notify.Event{
+		Event:   notify.EVENT_SUCCEEDED,
+		Outcome: result.Outcome,
+		Colour:  result.LiveColourAfter,
+	}
+	if result.Outcome == RESULT_FAILED {
+		event.Event = notify.EVENT_FAILED
+		event.Message = result.Error
+	}
+	if len(result.Releases) > 0 {
+		event.Release = result.Releases[0].Name
+		event.Revision = result.Releases[0].Revision
+	}
+	invocation.notifyEvent(event)
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/filesystem"
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+)
+
+func PlanFlags() []*Flag {
+	return append([]*Flag{
+		&Flag{
+			Key:         DEPLOY_TYPE,
+			Default:     "",
+			Description: fmt.Sprintf("deploy command to plan (%s, %s, %s or %s).", Command.BLUEGREEN, Command.CANARY, Command.MICROSERVICE, Command.STANDARD_CHART),
+			Validator:   IsDeployCommand,
+		},
+		&Flag{
+			Key:         CHART_DIR,
+			Default:     "./chart",
+			Description: "directory containing the service-to-be-deployed's chart definition.",
+			Validator:   filesystem.IsDirectory,
+		},
+		&Flag{
+			Key:         APP_NAME,
+			Default:     "",
+			Description: "name of the service-to-be-deployed (lower-case, alphanumeric + dashes).",
+			Validator:   deployment.IsValidAppName,
+		},
+		&Flag{
+			Key:         APP_VERSION,
+			Default:     "",
+			Description: "semantic version of the service-to-be-deployed (vX.X.X, or X.X.X), required for bluegreen and microservice.",
+			Validator:   deployment.IsValidAppVersion,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         TARGET_ENV,
+			Default:     "",
+			Description: "name of the environment in which to deploy the service (one of those configured in helm-deployer.yaml, prod or staging by default).",
+			Validator:   deployment.IsValidTargetEnv,
+		},
+	}, append(ChartFlags(), CommonFlags()...)...)
+}
+
+func RunPlan(ctx context.Context, invocation *invocation) error {
+	log.Println("Parsing CLI flags..")
+	commandFlags := PlanFlags()
+	cliFlags, err := parseCLIFlags(commandFlags)
+	if err != nil {
+		return err
+	}
+	log.Println("Successfully parsed CLI flags:")
+	PrintMap(cliFlags)
+	invocation.startPhases(cliFlags)
+	invocation.startResult(cliFlags)
+	invocation.startChartValues(commandFlags)
+	invocation.startDependencies(cliFlags)
+
+	log.Println("Loading environment config..")
+	if err := invocation.loadEnvironment(cliFlags); err != nil {
+		return err
+	}
+	log.Println("Successfully loaded environment config")
+
+	deployType := cliFlags[DEPLOY_TYPE]
+	if deployType != Command.STANDARD_CHART && cliFlags[APP_VERSION] == "" {
+		return runtime.ValidationError(fmt.Errorf("Missing flag %s, required to plan a %s deploy", Green("-"+APP_VERSION), Orange(deployType)))
+	}
+
+	planDeployer, err := invocation.newDeployer()
+	if err != nil {
+		return err
+	}
+	return invocation.recordReport(planDeployer.Plan(ctx, deployType, invocation.deploySpec(cliFlags)))
+}
@@ -7,8 +7,17 @@ import (
 	"strconv"
 )
 
+// maskedFlags are the flags whose values are secrets, such as tokens in a
+// webhook URL, and so are never printed.
+var maskedFlags = map[string]bool{
+	WEBHOOK_URL: true,
+}
+
 func PrintMap(m map[string]string) {
 	for key, value := range m {
+		if maskedFlags[key] && value != "" {
+			value = "********"
+		}
 		log.Printf("\t%s: %s", key, Green(value))
 	}
 }
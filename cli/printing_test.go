@@ -1,8 +1,10 @@
 package cli
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"log"
 	"regexp"
 	"testing"
 )
@@ -26,3 +28,15 @@ func Test_Orange_Returns_String_Affixed_With_ANSI_Escape_White_Code(t *testing.T
 	str := "some-str"
 	assert.Regexp(t, regexp.MustCompile(fmt.Sprintf("^.*%s\\033\\[97m$", str)), Orange(str))
 }
+
+func Test_PrintMap_Masks_The_Webhook_URL(t *testing.T) {
+	logged := &bytes.Buffer{}
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(logged)
+
+	PrintMap(map[string]string{APP_NAME: "some-api", WEBHOOK_URL: "https://hooks.example.com/secret-token"})
+
+	assert.Contains(t, logged.String(), "some-api")
+	assert.Contains(t, logged.String(), WEBHOOK_URL)
+	assert.NotContains(t, logged.String(), "secret-token")
+}
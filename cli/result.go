@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"time"
+
+	"github.com/Hutchison-Technologies/helm-deployer/deployer"
+	"github.com/Hutchison-Technologies/helm-deployer/filesystem"
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+	goYaml "github.com/ghodss/yaml"
+)
+
+const (
+	RESULT_SUCCEEDED       = "succeeded"
+	RESULT_UNCHANGED       = "unchanged"
+	RESULT_CHANGES_PLANNED = "changes_planned"
+	RESULT_FAILED          = "failed"
+)
+
+// Result summarises a run for -result-file.
+type Result struct {
+	Path     string `json:"-"`
+	Command  string `json:"command"`
+	App      string `json:"app,omitempty"`
+	Env      string `json:"env,omitempty"`
+	Version  string `json:"version,omitempty"`
+	Outcome  string `json:"outcome"`
+	ExitCode int    `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+	deployer.Report
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	DurationMs int64         `json:"duration_ms"`
+	Phases     []PhaseTiming `json:"phases"`
+}
+
+type PhaseTiming struct {
+	Event      string `json:"event"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+func NewResult(command string) *Result {
+	return &Result{
+		Command:   command,
+		Report:    *deployer.NewReport(),
+		Phases:    []PhaseTiming{},
+		StartedAt: time.Now().UTC(),
+	}
+}
+
+func ResultFileFlag() *Flag {
+	return &Flag{
+		Key:         RESULT_FILE,
+		Default:     "",
+		Description: "path to write a summary of the run to, as YAML when it ends in .yaml or .yml and JSON otherwise.",
+		Validator:   IsValidResultFile,
+		Optional:    true,
+	}
+}
+
+func IsValidResultFile(path string) bool {
+	return filesystem.IsDirectory(filepath.Dir(path)) && !filesystem.IsDirectory(path)
+}
+
+// startResult records who and what the run is for.
+func (invocation *invocation) startResult(cliFlags map[string]string) {
+	result := invocation.result
+	result.Path = cliFlags[RESULT_FILE]
+	result.App = cliFlags[APP_NAME]
+	result.Env = cliFlags[TARGET_ENV]
+	result.Version = cliFlags[APP_VERSION]
+}
+
+func (result *Result) recordPhase(event PhaseEvent) {
+	result.Phases = append(result.Phases, PhaseTiming{Event: event.Event, DurationMs: event.DurationMs})
+}
+
+// finish settles the outcome of the run from err.
+func (result *Result) finish(err error) {
+	result.FinishedAt = time.Now().UTC()
+	result.DurationMs = result.FinishedAt.Sub(result.StartedAt).Milliseconds()
+	result.ExitCode = runtime.ExitCode(err)
+	switch {
+	case result.ExitCode == runtime.EXIT_CODE_PLAN_HAS_CHANGES:
+		result.Outcome = RESULT_CHANGES_PLANNED
+	case err != nil:
+		result.Outcome = RESULT_FAILED
+		result.Error = deployer.StripColour(err.Error())
+	case result.Unchanged():
+		result.Outcome = RESULT_UNCHANGED
+	default:
+		result.Outcome = RESULT_SUCCEEDED
+	}
+}
+
+// Marshal renders the result as YAML when path ends in .yaml or .yml, and as
+// JSON otherwise.
+func (result *Result) Marshal(path string) ([]byte, error) {
+	contents, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		return goYaml.JSONToYAML(contents)
+	}
+	return append(contents, '\n'), nil
+}
+
+func (result *Result) Write() error {
+	if result.Path == "" {
+		return nil
+	}
+	contents, err := result.Marshal(result.Path)
+	if err != nil {
+		return fmt.Errorf("Failed to render the result file: %s", err)
+	}
+	if err := ioutil.WriteFile(result.Path, contents, 0644); err != nil {
+		return fmt.Errorf("Failed to write the result file %s: %s", result.Path, err)
+	}
+	return nil
+}
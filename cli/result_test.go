@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/Hutchison-Technologies/helm-deployer/deployer"
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Result_Finish_Reports_Failure(t *testing.T) {
+	runResult := NewResult(Command.BLUEGREEN)
+	runResult.finish(runtime.DeployFailedError(errors.New("Original deploy error: " + Green("boom"))))
+	assert.Equal(t, RESULT_FAILED, runResult.Outcome)
+	assert.Equal(t, runtime.EXIT_CODE_DEPLOY_FAILED, runResult.ExitCode)
+	assert.Equal(t, "Original deploy error: boom", runResult.Error)
+}
+
+func Test_Result_Finish_Reports_Unchanged_When_No_Release_Changed(t *testing.T) {
+	runResult := NewResult(Command.STANDARD_CHART)
+	runResult.Releases = []*deployer.ReleaseReport{{Name: "prod-some-api", Revision: 3, Status: "deployed"}}
+	runResult.finish(nil)
+	assert.Equal(t, RESULT_UNCHANGED, runResult.Outcome)
+}
+
+func Test_Result_Finish_Reports_Success_When_A_Release_Changed(t *testing.T) {
+	runResult := NewResult(Command.BLUEGREEN)
+	runResult.Releases = []*deployer.ReleaseReport{{Name: "prod-blue-some-api", Revision: 4, Changed: true}, {Name: "prod-service-some-api"}}
+	runResult.finish(nil)
+	assert.Equal(t, RESULT_SUCCEEDED, runResult.Outcome)
+}
+
+func Test_Result_Finish_Reports_Planned_Changes(t *testing.T) {
+	runResult := NewResult(Command.PLAN)
+	runResult.finish(deployer.ErrPlanHasChanges)
+	assert.Equal(t, RESULT_CHANGES_PLANNED, runResult.Outcome)
+	assert.Equal(t, runtime.EXIT_CODE_PLAN_HAS_CHANGES, runResult.ExitCode)
+}
+
+func Test_Result_Finish_Reports_Success_After_Rollbacks(t *testing.T) {
+	runResult := NewResult(Command.ROLLBACK)
+	runResult.RolledBack = true
+	runResult.Rollbacks = []*deployer.ReleaseReport{{Name: "prod-service-some-api", Revision: 7, Changed: true}}
+	runResult.finish(nil)
+	assert.Equal(t, RESULT_SUCCEEDED, runResult.Outcome)
+}
+
+func Test_Result_Write_Writes_JSON(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "result")
+	defer os.RemoveAll(dir)
+
+	runResult := NewResult(Command.BLUEGREEN)
+	runResult.Path = filepath.Join(dir, "out.json")
+	runResult.LiveColourBefore = "blue"
+	runResult.LiveColourAfter = "green"
+	runResult.finish(nil)
+	assert.Nil(t, runResult.Write())
+
+	contents, _ := ioutil.ReadFile(runResult.Path)
+	written := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(contents, &written))
+	assert.Equal(t, "bluegreen", written["command"])
+	assert.Equal(t, "blue", written["live_colour_before"])
+	assert.Equal(t, "green", written["live_colour_after"])
+}
+
+func Test_Result_Write_Writes_YAML(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "result")
+	defer os.RemoveAll(dir)
+
+	runResult := NewResult(Command.SWAP)
+	runResult.Path = filepath.Join(dir, "out.yaml")
+	runResult.finish(nil)
+	assert.Nil(t, runResult.Write())
+
+	contents, _ := ioutil.ReadFile(runResult.Path)
+	assert.True(t, strings.Contains(string(contents), "command: swap\n"))
+}
+
+func Test_Result_Write_Does_Nothing_Without_A_Path(t *testing.T) {
+	assert.Nil(t, NewResult(Command.SWAP).Write())
+}
+
+func Test_IsValidResultFile(t *testing.T) {
+	assert.True(t, IsValidResultFile("out.json"))
+	assert.True(t, IsValidResultFile(os.TempDir()+"/out.yaml"))
+	assert.False(t, IsValidResultFile(os.TempDir()))
+	assert.False(t, IsValidResultFile("/some/nonexistent/dir/out.json"))
+}
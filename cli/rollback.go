@@ -0,0 +1,78 @@
+package cli
+
+import (
+	"context"
+	"log"
+
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/filesystem"
+)
+
+func RollbackFlags() []*Flag {
+	return append([]*Flag{
+		&Flag{
+			Key:         CHART_DIR,
+			Default:     "",
+			Description: "directory containing the service's chart definition, used to find its helm-deployer.yaml.",
+			Validator:   filesystem.IsDirectory,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         APP_NAME,
+			Default:     "",
+			Description: "name of the service-to-be-rolled-back (lower-case, alphanumeric + dashes).",
+			Validator:   deployment.IsValidAppName,
+		},
+		&Flag{
+			Key:         TARGET_ENV,
+			Default:     "",
+			Description: "name of the environment in which to roll back the service (one of those configured in helm-deployer.yaml, prod or staging by default).",
+			Validator:   deployment.IsValidTargetEnv,
+		},
+		&Flag{
+			Key:         REVISION,
+			Default:     "",
+			Description: "release revision to roll back to (a positive integer), defaults to the previous successful revision.",
+			Validator:   deployment.IsValidRevision,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         COLOUR,
+			Default:     "",
+			Description: "colour of the bluegreen deployment release to roll back (blue or green), defaults to rolling back the live service release.",
+			Validator:   deployment.IsValidColour,
+			Optional:    true,
+		},
+		&Flag{
+			Key:         KEEP_WARM,
+			Default:     "0",
+			Description: "number of replicas to keep running in the previously live colour after rolling back the service release (0 or more).",
+			Validator:   deployment.IsValidCount,
+		},
+	}, CommonFlags()...)
+}
+
+func RunRollback(ctx context.Context, invocation *invocation) error {
+	log.Println("Parsing CLI flags..")
+	cliFlags, err := parseCLIFlags(RollbackFlags())
+	if err != nil {
+		return err
+	}
+	log.Println("Successfully parsed CLI flags:")
+	PrintMap(cliFlags)
+	invocation.startPhases(cliFlags)
+	invocation.startResult(cliFlags)
+
+	log.Println("Loading environment config..")
+	if err := invocation.loadEnvironment(cliFlags); err != nil {
+		return err
+	}
+	log.Println("Successfully loaded environment config")
+	invocation.notifyStarted()
+
+	rollbackDeployer, err := invocation.newDeployer()
+	if err != nil {
+		return err
+	}
+	return invocation.recordReport(rollbackDeployer.Rollback(ctx, invocation.deploySpec(cliFlags)))
+}
@@ -0,0 +1,43 @@
+package cli
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+)
+
+// cancelOnSignal returns a context cancelled by the first SIGINT or SIGTERM,
+// and a function to stop listening for them. A second signal exits at once,
+// leaving the release in whatever state it was in.
+func cancelOnSignal(parent context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+	signals := make(chan os.Signal, 2)
+	stopped := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			log.Printf("Received %s, settling the release in progress before exiting, signal again to exit now..", sig)
+			cancel()
+		case <-stopped:
+			return
+		}
+		select {
+		case sig := <-signals:
+			log.Printf("Received %s again, exiting without settling the release", sig)
+			os.Exit(runtime.EXIT_CODE_ROLLBACK_FAILED)
+		case <-stopped:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		close(stopped)
+		cancel()
+	}
+}
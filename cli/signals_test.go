@@ -0,0 +1,32 @@
+package cli
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CancelOnSignal_Cancels_On_SIGTERM(t *testing.T) {
+	ctx, stop := cancelOnSignal(context.Background())
+	defer stop()
+
+	assert.Nil(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	select {
+	case <-ctx.Done():
+		assert.Equal(t, context.Canceled, ctx.Err())
+	case <-time.After(5 * time.Second):
+		t.Fatal("context was not cancelled by SIGTERM")
+	}
+}
+
+func Test_CancelOnSignal_Stop_Cancels(t *testing.T) {
+	ctx, stop := cancelOnSignal(context.Background())
+
+	stop()
+
+	assert.Equal(t, context.Canceled, ctx.Err())
+}
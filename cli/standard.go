@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"context"
 	"log"
 
 	"github.com/Hutchison-Technologies/helm-deployer/deployment"
@@ -8,7 +9,7 @@ import (
 )
 
 func StandardChartFlags() []*Flag {
-	return []*Flag{
+	return append([]*Flag{
 		&Flag{
 			Key:         CHART_DIR,
 			Default:     "./chart",
@@ -24,35 +25,38 @@ func StandardChartFlags() []*Flag {
 		&Flag{
 			Key:         TARGET_ENV,
 			Default:     "",
-			Description: "name of the environment in which to deploy the service (prod or staging).",
+			Description: "name of the environment in which to deploy the service (one of those configured in helm-deployer.yaml, prod or staging by default).",
 			Validator:   deployment.IsValidTargetEnv,
 		},
-	}
+		FailOnNoChangeFlag(),
+	}, append(ChartFlags(), append(DiagnosticsFlags(), CommonFlags()...)...)...)
 }
 
-func RunStandardChartDeploy() error {
+func RunStandardChartDeploy(ctx context.Context, invocation *invocation) error {
 	log.Println("Parsing CLI flags..")
-	cliFlags := parseCLIFlags(StandardChartFlags())
+	commandFlags := StandardChartFlags()
+	cliFlags, err := parseCLIFlags(commandFlags)
+	if err != nil {
+		return err
+	}
 	log.Println("Successfully parsed CLI flags:")
 	PrintMap(cliFlags)
+	invocation.startPhases(cliFlags)
+	invocation.startResult(cliFlags)
+	invocation.startChartValues(commandFlags)
+	invocation.startDiagnostics(cliFlags)
+	invocation.startDependencies(cliFlags)
 
-	log.Println("Loading chart values..")
-	chartValuesYaml := loadChartValues(cliFlags[CHART_DIR], cliFlags[TARGET_ENV])
-	log.Println("Successfully loaded chart values")
-
-	log.Println("Connecting helm config..")
-	helmConfig := buildHelmConfig()
-	log.Println("Successfully configured helm!")
+	log.Println("Loading environment config..")
+	if err := invocation.loadEnvironment(cliFlags); err != nil {
+		return err
+	}
+	log.Println("Successfully loaded environment config")
+	invocation.notifyStarted()
 
-	deploymentName := deployment.StandardChartDeploymentName(cliFlags[TARGET_ENV], cliFlags[APP_NAME])
-	log.Printf("Preparing to deploy %s..", Green(deploymentName))
-	deployedRelease := releaseWithValues(
-		deploymentName,
-		chartValuesYaml,
-		[][]interface{}{},
-		helmConfig,
-		cliFlags[CHART_DIR])
-	log.Printf("Successfully deployed %s, the service is now live!", Green(deploymentName))
-	PrintRelease(deployedRelease)
-	return nil
+	standardChartDeployer, err := invocation.newDeployer()
+	if err != nil {
+		return err
+	}
+	return invocation.recordReport(standardChartDeployer.DeployStandardChart(ctx, invocation.deploySpec(cliFlags)))
 }
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"context"
+	"log"
+
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/filesystem"
+)
+
+func SwapFlags() []*Flag {
+	return append([]*Flag{
+		&Flag{
+			Key:         CHART_DIR,
+			Default:     "./chart",
+			Description: "directory containing the service-to-be-swapped's chart definition.",
+			Validator:   filesystem.IsDirectory,
+		},
+		&Flag{
+			Key:         APP_NAME,
+			Default:     "",
+			Description: "name of the service-to-be-swapped (lower-case, alphanumeric + dashes).",
+			Validator:   deployment.IsValidAppName,
+		},
+		&Flag{
+			Key:         TARGET_ENV,
+			Default:     "",
+			Description: "name of the environment in which to swap the service (one of those configured in helm-deployer.yaml, prod or staging by default).",
+			Validator:   deployment.IsValidTargetEnv,
+		},
+		&Flag{
+			Key:         KEEP_WARM,
+			Default:     "0",
+			Description: "number of replicas to keep running in the previously live colour after the swap (0 or more).",
+			Validator:   deployment.IsValidCount,
+		},
+	}, append(ChartFlags(), append(DiagnosticsFlags(), CommonFlags()...)...)...)
+}
+
+func RunBlueGreenSwap(ctx context.Context, invocation *invocation) error {
+	log.Println("Parsing CLI flags..")
+	commandFlags := SwapFlags()
+	cliFlags, err := parseCLIFlags(commandFlags)
+	if err != nil {
+		return err
+	}
+	log.Println("Successfully parsed CLI flags:")
+	PrintMap(cliFlags)
+	invocation.startPhases(cliFlags)
+	invocation.startResult(cliFlags)
+	invocation.startChartValues(commandFlags)
+	invocation.startDiagnostics(cliFlags)
+	invocation.startDependencies(cliFlags)
+
+	log.Println("Loading environment config..")
+	if err := invocation.loadEnvironment(cliFlags); err != nil {
+		return err
+	}
+	log.Println("Successfully loaded environment config")
+	invocation.notifyStarted()
+
+	swapDeployer, err := invocation.newDeployer()
+	if err != nil {
+		return err
+	}
+	return invocation.recordReport(swapDeployer.Swap(ctx, invocation.deploySpec(cliFlags)))
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/filesystem"
+)
+
+type valuesOptions struct {
+	files     []string
+	set       []string
+	setString []string
+}
+
+// ValuesFlags override the chart's values, which are merged from values.yaml,
+// common.yaml and <env>.yaml.
+func ValuesFlags() []*Flag {
+	return []*Flag{
+		&Flag{
+			Key:         VALUES,
+			Default:     "",
+			Description: "values file merged over the chart's <env>.yaml (may be repeated, later files winning).",
+			Validator:   filesystem.IsFile,
+			Optional:    true,
+			Repeatable:  true,
+		},
+		&Flag{
+			Key:         SET,
+			Default:     "",
+			Description: "values to set over the values files, as helm's --set: key.path=value,other=value (may be repeated).",
+			Validator:   deployment.IsValidSetValues,
+			Optional:    true,
+			Repeatable:  true,
+		},
+		&Flag{
+			Key:         SET_STRING,
+			Default:     "",
+			Description: "string values to set last, as helm's --set-string: key.path=value,other=value (may be repeated).",
+			Validator:   deployment.IsValidSetValues,
+			Optional:    true,
+			Repeatable:  true,
+		},
+		&Flag{
+			Key:         SHOW_VALUES,
+			Default:     "false",
+			Description: "whether to print the merged chart values before deploying (true or false).",
+			Validator:   deployment.IsValidBoolean,
+		},
+	}
+}
+
+// startChartValues reads the values flags, which are repeatable, from the
+// command's flags once they have been parsed.
+func (invocation *invocation) startChartValues(commandFlags []*Flag) {
+	for _, commandFlag := range commandFlags {
+		if commandFlag.Values == nil {
+			continue
+		}
+		switch commandFlag.Key {
+		case VALUES:
+			invocation.chartValues.files = *commandFlag.Values
+		case SET:
+			invocation.chartValues.set = *commandFlag.Values
+		case SET_STRING:
+			invocation.chartValues.setString = *commandFlag.Values
+		}
+	}
+}
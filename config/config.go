@@ -0,0 +1,274 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/filesystem"
+	"github.com/Hutchison-Technologies/helm-deployer/notify"
+	"github.com/Masterminds/semver/v3"
+	goYaml "github.com/ghodss/yaml"
+)
+
+const (
+	CONFIG_FILE_NAME         = "helm-deployer.yaml"
+	DEFAULT_TIMEOUT          = "300s"
+	DEFAULT_ROLLBACK_TIMEOUT = "900s"
+	DEFAULT_PENDING_TIMEOUT  = "1800s"
+	DEFAULT_NAMESPACE        = "default"
+
+	// The oldest blue-green-microservice and microservice charts with the
+	// values the deployer sets, such as bluegreen.is_service_release.
+	DEFAULT_MIN_BLUE_GREEN_MICROSERVICE_VERSION = "0.11.34"
+	DEFAULT_MIN_MICROSERVICE_VERSION            = "0.3.44"
+)
+
+type Environment struct {
+	Name            string   `json:"-"`
+	Namespace       string   `json:"namespace,omitempty"`
+	CreateNamespace bool     `json:"create_namespace,omitempty"`
+	Kubeconfig      string   `json:"kubeconfig,omitempty"`
+	KubeContext     string   `json:"kube_context,omitempty"`
+	Timeout         string   `json:"timeout,omitempty"`
+	InstallTimeout  string   `json:"install_timeout,omitempty"`
+	UpgradeTimeout  string   `json:"upgrade_timeout,omitempty"`
+	RollbackTimeout string   `json:"rollback_timeout,omitempty"`
+	DiffCheck       *bool    `json:"diff_check,omitempty"`
+	Force           *bool    `json:"force,omitempty"`
+	Recreate        *bool    `json:"recreate,omitempty"`
+	Atomic          bool     `json:"atomic,omitempty"`
+	WaitForJobs     *bool    `json:"wait_for_jobs,omitempty"`
+	MaxHistory      int      `json:"max_history,omitempty"`
+	CleanupOnFail   bool     `json:"cleanup_on_fail,omitempty"`
+	RecoverPending  *bool    `json:"recover_pending,omitempty"`
+	PendingTimeout  string   `json:"pending_timeout,omitempty"`
+	RequiredValues  []string `json:"required_values,omitempty"`
+
+	MinBlueGreenMicroserviceVersion string `json:"min_blue_green_microservice_version,omitempty"`
+	MinMicroserviceVersion          string `json:"min_microservice_version,omitempty"`
+
+	Webhooks []*notify.Webhook `json:"webhooks,omitempty"`
+}
+
+type Config struct {
+	Path         string                  `json:"-"`
+	Environments map[string]*Environment `json:"environments"`
+}
+
+func Default() *Config {
+	return &Config{
+		Path: "",
+		Environments: map[string]*Environment{
+			"prod":    &Environment{},
+			"staging": &Environment{},
+		},
+	}
+}
+
+func ConfigPath(dir string) string {
+	return filepath.Join(dir, CONFIG_FILE_NAME)
+}
+
+func Find(dirs ...string) string {
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		if path := ConfigPath(dir); filesystem.IsFile(path) {
+			return path
+		}
+	}
+	return ""
+}
+
+func LoadForChart(chartDir string) (*Config, error) {
+	path := Find(chartDir, ".")
+	if path == "" {
+		return withDefaults(Default())
+	}
+	return Load(path)
+}
+
+func Load(path string) (*Config, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read config at \033[31m%s\033[97m, %s", path, err.Error())
+	}
+
+	config := &Config{}
+	if err := goYaml.Unmarshal(contents, config); err != nil {
+		return nil, fmt.Errorf("Could not parse config at \033[31m%s\033[97m, %s", path, err.Error())
+	}
+	config.Path = path
+
+	if len(config.Environments) == 0 {
+		return nil, fmt.Errorf("Config at \033[31m%s\033[97m must define at least one environment", path)
+	}
+	return withDefaults(config)
+}
+
+func withDefaults(config *Config) (*Config, error) {
+	errorMessages := make([]string, 0)
+	for name, environment := range config.Environments {
+		if environment == nil {
+			environment = &Environment{}
+			config.Environments[name] = environment
+		}
+		environment.Name = name
+		if !deployment.IsValidTargetEnv(name) {
+			errorMessages = append(errorMessages, fmt.Sprintf("Invalid environment name \033[31m%s\033[97m, must be lower-case, alphanumeric + dashes", name))
+		}
+		if environment.Namespace == "" {
+			environment.Namespace = DEFAULT_NAMESPACE
+		}
+		if !deployment.IsValidNamespace(environment.Namespace) {
+			errorMessages = append(errorMessages, fmt.Sprintf("Invalid namespace \033[31m%s\033[97m for environment %s, must be lower-case, alphanumeric + dashes", environment.Namespace, name))
+		}
+		if environment.Timeout == "" {
+			environment.Timeout = DEFAULT_TIMEOUT
+		}
+		if environment.InstallTimeout == "" {
+			environment.InstallTimeout = environment.Timeout
+		}
+		if environment.UpgradeTimeout == "" {
+			environment.UpgradeTimeout = environment.Timeout
+		}
+		if environment.RollbackTimeout == "" {
+			environment.RollbackTimeout = DEFAULT_ROLLBACK_TIMEOUT
+		}
+		if environment.PendingTimeout == "" {
+			environment.PendingTimeout = DEFAULT_PENDING_TIMEOUT
+		}
+		if environment.MinBlueGreenMicroserviceVersion == "" {
+			environment.MinBlueGreenMicroserviceVersion = DEFAULT_MIN_BLUE_GREEN_MICROSERVICE_VERSION
+		}
+		if environment.MinMicroserviceVersion == "" {
+			environment.MinMicroserviceVersion = DEFAULT_MIN_MICROSERVICE_VERSION
+		}
+		for key, value := range map[string]string{
+			"timeout":          environment.Timeout,
+			"install_timeout":  environment.InstallTimeout,
+			"upgrade_timeout":  environment.UpgradeTimeout,
+			"rollback_timeout": environment.RollbackTimeout,
+			"pending_timeout":  environment.PendingTimeout,
+		} {
+			if _, err := time.ParseDuration(value); err != nil {
+				errorMessages = append(errorMessages, fmt.Sprintf("Invalid %s \033[31m%s\033[97m for environment %s, must be a duration such as 300s", key, value, name))
+			}
+		}
+		for key, value := range map[string]string{
+			"min_blue_green_microservice_version": environment.MinBlueGreenMicroserviceVersion,
+			"min_microservice_version":            environment.MinMicroserviceVersion,
+		} {
+			if _, err := semver.NewVersion(value); err != nil {
+				errorMessages = append(errorMessages, fmt.Sprintf("Invalid %s \033[31m%s\033[97m for environment %s, must be a chart version such as 0.11.34", key, value, name))
+			}
+		}
+		for i, webhook := range environment.Webhooks {
+			if webhook == nil {
+				errorMessages = append(errorMessages, fmt.Sprintf("Invalid webhook %d for environment %s, must not be empty", i+1, name))
+			} else if err := webhook.Validate(); err != nil {
+				errorMessages = append(errorMessages, fmt.Sprintf("Invalid webhook %d for environment %s, %s", i+1, name, err))
+			}
+		}
+		for _, key := range environment.RequiredValues {
+			if !deployment.IsValidValuesKey(key) {
+				errorMessages = append(errorMessages, fmt.Sprintf("Invalid required_values key \033[31m%s\033[97m for environment %s, must be a dotted path such as bluegreen.deployment.image", key, name))
+			}
+		}
+		if environment.MaxHistory < 0 {
+			errorMessages = append(errorMessages, fmt.Sprintf("Invalid max_history \033[31m%d\033[97m for environment %s, must be 0 (unlimited) or more", environment.MaxHistory, name))
+		}
+	}
+
+	if len(errorMessages) > 0 {
+		sort.Strings(errorMessages)
+		return nil, errors.New(fmt.Sprintf("Error loading config %s:\n\t%s", config.Source(), strings.Join(errorMessages, "\n\t")))
+	}
+	return config, nil
+}
+
+func (config *Config) Source() string {
+	if config.Path == "" {
+		return "(built-in defaults)"
+	}
+	return config.Path
+}
+
+func (config *Config) EnvironmentNames() []string {
+	names := make([]string, 0, len(config.Environments))
+	for name := range config.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (config *Config) Environment(name string) (*Environment, error) {
+	environment, ok := config.Environments[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown target environment \033[31m%s\033[97m, must be one of \033[33m%s\033[97m as configured by %s", name, strings.Join(config.EnvironmentNames(), ", "), config.Source())
+	}
+	return environment, nil
+}
+
+func (environment *Environment) TimeoutDuration() time.Duration {
+	duration, _ := time.ParseDuration(environment.Timeout)
+	return duration
+}
+
+func (environment *Environment) InstallTimeoutDuration() time.Duration {
+	duration, _ := time.ParseDuration(environment.InstallTimeout)
+	return duration
+}
+
+func (environment *Environment) UpgradeTimeoutDuration() time.Duration {
+	duration, _ := time.ParseDuration(environment.UpgradeTimeout)
+	return duration
+}
+
+func (environment *Environment) RollbackTimeoutDuration() time.Duration {
+	duration, _ := time.ParseDuration(environment.RollbackTimeout)
+	return duration
+}
+
+func (environment *Environment) PendingTimeoutDuration() time.Duration {
+	duration, _ := time.ParseDuration(environment.PendingTimeout)
+	return duration
+}
+
+func (environment *Environment) RequiresDiffCheck() bool {
+	return environment.DiffCheck == nil || *environment.DiffCheck
+}
+
+func (environment *Environment) UsesForce() bool {
+	return environment.Force == nil || *environment.Force
+}
+
+func (environment *Environment) UsesRecreate() bool {
+	return environment.Recreate == nil || *environment.Recreate
+}
+
+// InstallWaitsForJobs reports whether installs wait for the chart's jobs to
+// complete, which they do unless wait_for_jobs is false.
+func (environment *Environment) InstallWaitsForJobs() bool {
+	return environment.WaitForJobs == nil || *environment.WaitForJobs
+}
+
+// WaitsForJobs reports whether upgrades and rollbacks wait for the chart's
+// jobs to complete, which they only do when wait_for_jobs is true.
+func (environment *Environment) WaitsForJobs() bool {
+	return environment.WaitForJobs != nil && *environment.WaitForJobs
+}
+
+// RecoversPending reports whether releases left pending for longer than the
+// pending timeout are recovered before deploying.
+func (environment *Environment) RecoversPending() bool {
+	return environment.RecoverPending == nil || *environment.RecoverPending
+}
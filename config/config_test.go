@@ -0,0 +1,152 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const TEST_CONFIG_DIR = "../testdata"
+
+func Test_ConfigPath_Returns_Path_To_Config_File_In_Dir(t *testing.T) {
+	assert.Equal(t, "/some/dir/helm-deployer.yaml", ConfigPath("/some/dir"))
+}
+
+func Test_Find_Returns_Empty_String_When_No_Config_Exists(t *testing.T) {
+	assert.Equal(t, "", Find("/some/nonexistent/dir", ""))
+}
+
+func Test_Find_Returns_First_Config_Found(t *testing.T) {
+	assert.Equal(t, ConfigPath(TEST_CONFIG_DIR), Find("/some/nonexistent/dir", TEST_CONFIG_DIR, "../testdata/bad-config"))
+}
+
+func Test_Default_Returns_Prod_And_Staging(t *testing.T) {
+	assert.Equal(t, []string{"prod", "staging"}, Default().EnvironmentNames())
+}
+
+func Test_Load_Returns_Error_When_File_Does_Not_Exist(t *testing.T) {
+	_, err := Load("/some/nonexistent/helm-deployer.yaml")
+	assert.NotNil(t, err)
+}
+
+func Test_Load_Returns_Error_When_Config_Invalid(t *testing.T) {
+	_, err := Load(ConfigPath("../testdata/bad-config"))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "Prod_Env")
+	assert.Contains(t, err.Error(), "soon")
+	assert.Contains(t, err.Error(), "Not_A_Namespace")
+	assert.Contains(t, err.Error(), "max_history")
+	assert.Contains(t, err.Error(), "carrier-pigeon")
+	assert.Contains(t, err.Error(), "bluegreen..image")
+	assert.Contains(t, err.Error(), "min_blue_green_microservice_version")
+}
+
+func Test_Load_Returns_Configured_Environments(t *testing.T) {
+	config, err := Load(ConfigPath(TEST_CONFIG_DIR))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"customer-a", "prod", "uat"}, config.EnvironmentNames())
+}
+
+func Test_Load_Applies_Environment_Settings_And_Defaults(t *testing.T) {
+	config, _ := Load(ConfigPath(TEST_CONFIG_DIR))
+
+	prod, err := config.Environment("prod")
+	assert.Nil(t, err)
+	assert.Equal(t, "prod", prod.Name)
+	assert.Equal(t, "apps", prod.Namespace)
+	assert.True(t, prod.CreateNamespace)
+	assert.Equal(t, "prod-cluster", prod.KubeContext)
+	assert.Equal(t, 600*time.Second, prod.TimeoutDuration())
+	assert.Equal(t, 900*time.Second, prod.RollbackTimeoutDuration())
+	assert.True(t, prod.RequiresDiffCheck())
+
+	uat, _ := config.Environment("uat")
+	assert.Equal(t, "default", uat.Namespace)
+	assert.False(t, uat.CreateNamespace)
+	assert.Equal(t, 300*time.Second, uat.TimeoutDuration())
+	assert.False(t, uat.RequiresDiffCheck())
+
+	customer, _ := config.Environment("customer-a")
+	assert.Equal(t, 1200*time.Second, customer.RollbackTimeoutDuration())
+}
+
+func Test_Load_Applies_Release_Options_And_Defaults(t *testing.T) {
+	config, _ := Load(ConfigPath(TEST_CONFIG_DIR))
+
+	prod, _ := config.Environment("prod")
+	assert.Equal(t, 600*time.Second, prod.InstallTimeoutDuration())
+	assert.Equal(t, 1200*time.Second, prod.UpgradeTimeoutDuration())
+	assert.True(t, prod.Atomic)
+	assert.Equal(t, 10, prod.MaxHistory)
+	assert.True(t, prod.UsesForce())
+	assert.True(t, prod.UsesRecreate())
+	assert.True(t, prod.InstallWaitsForJobs())
+	assert.False(t, prod.WaitsForJobs())
+	assert.False(t, prod.CleanupOnFail)
+	assert.True(t, prod.RecoversPending())
+	assert.Equal(t, 1800*time.Second, prod.PendingTimeoutDuration())
+	assert.Equal(t, []string{"bluegreen.deployment.image", "bluegreen.ingress.host"}, prod.RequiredValues)
+
+	uat, _ := config.Environment("uat")
+	assert.Equal(t, 300*time.Second, uat.InstallTimeoutDuration())
+	assert.Equal(t, 300*time.Second, uat.UpgradeTimeoutDuration())
+	assert.False(t, uat.Atomic)
+	assert.Equal(t, 0, uat.MaxHistory)
+	assert.False(t, uat.UsesForce())
+	assert.False(t, uat.UsesRecreate())
+	assert.False(t, uat.InstallWaitsForJobs())
+	assert.False(t, uat.WaitsForJobs())
+	assert.True(t, uat.CleanupOnFail)
+	assert.False(t, uat.RecoversPending())
+	assert.Empty(t, uat.RequiredValues)
+
+	customer, _ := config.Environment("customer-a")
+	assert.Equal(t, 3600*time.Second, customer.PendingTimeoutDuration())
+}
+
+func Test_Load_Applies_Minimum_Dependency_Versions_And_Defaults(t *testing.T) {
+	config, _ := Load(ConfigPath(TEST_CONFIG_DIR))
+
+	prod, _ := config.Environment("prod")
+	assert.Equal(t, DEFAULT_MIN_BLUE_GREEN_MICROSERVICE_VERSION, prod.MinBlueGreenMicroserviceVersion)
+	assert.Equal(t, DEFAULT_MIN_MICROSERVICE_VERSION, prod.MinMicroserviceVersion)
+
+	customer, _ := config.Environment("customer-a")
+	assert.Equal(t, "0.12.0", customer.MinBlueGreenMicroserviceVersion)
+	assert.Equal(t, DEFAULT_MIN_MICROSERVICE_VERSION, customer.MinMicroserviceVersion)
+}
+
+func Test_Environment_Returns_Error_Listing_Configured_Environments(t *testing.T) {
+	config, _ := Load(ConfigPath(TEST_CONFIG_DIR))
+	_, err := config.Environment("staging")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "customer-a, prod, uat")
+}
+
+func Test_LoadForChart_Returns_Defaults_When_No_Config_Exists(t *testing.T) {
+	config, err := LoadForChart("/some/nonexistent/dir")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"prod", "staging"}, config.EnvironmentNames())
+	staging, _ := config.Environment("staging")
+	assert.Equal(t, 300*time.Second, staging.TimeoutDuration())
+}
+
+func Test_LoadForChart_Returns_Chart_Config(t *testing.T) {
+	config, err := LoadForChart(TEST_CONFIG_DIR)
+	assert.Nil(t, err)
+	assert.Equal(t, ConfigPath(TEST_CONFIG_DIR), config.Path)
+}
+
+func Test_Load_Applies_Webhooks(t *testing.T) {
+	config, _ := Load(ConfigPath(TEST_CONFIG_DIR))
+
+	prod, _ := config.Environment("prod")
+	assert.Equal(t, 2, len(prod.Webhooks))
+	assert.Equal(t, "https://hooks.example.com/deploys", prod.Webhooks[0].URL)
+	assert.Equal(t, "${SLACK_WEBHOOK_URL}", prod.Webhooks[1].URL)
+	assert.Equal(t, "slack", prod.Webhooks[1].Format)
+
+	uat, _ := config.Environment("uat")
+	assert.Equal(t, 0, len(uat.Webhooks))
+}
@@ -0,0 +1,125 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Hutchison-Technologies/helm-deployer/charts"
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+)
+
+// ErrHealthGateFailed is wrapped by the error of a bluegreen deploy whose new
+// colour failed its health gate, leaving the service on the live colour.
+var ErrHealthGateFailed = errors.New("Health gate failed")
+
+func (r *run) deployBlueGreen(spec Spec) error {
+	if err := r.prepareDependencies(spec); err != nil {
+		return err
+	}
+
+	r.logger.Println("Asserting that this is a bluegreen microservice chart..")
+	if err := r.assertChartIsBlueGreen(spec.ChartDir); err != nil {
+		return err
+	}
+	r.logger.Println("This is a bluegreen microservice chart!")
+
+	r.logger.Println("Loading chart values..")
+	chartValuesYaml, err := r.loadChartValues(spec)
+	if err != nil {
+		return err
+	}
+	r.logger.Println("Successfully loaded chart values")
+
+	if err := r.validateReleaseValues(chartValuesYaml, spec.ChartDir, blueGreenReleaseEdits(spec)); err != nil {
+		return err
+	}
+
+	r.logger.Println("Determining deploy colour..")
+	deployColour, err := r.determineDeployColour(spec.TargetEnv, spec.AppName)
+	if err != nil {
+		return err
+	}
+	r.logger.Printf("Determined deploy colour: %s", green(deployColour))
+	r.logPhase(Phase{Event: PHASE_COLOUR_DETERMINED, Colour: deployColour})
+	r.report.LiveColourBefore = r.currentLiveColour(spec.TargetEnv, spec.AppName)
+	r.report.LiveColourAfter = r.report.LiveColourBefore
+
+	deploymentName := deployment.BlueGreenDeploymentName(spec.TargetEnv, deployColour, spec.AppName)
+	r.logger.Printf("Preparing to deploy %s..", green(deploymentName))
+	deployedRelease, changed, err := r.releaseWithValues(
+		deploymentName,
+		chartValuesYaml,
+		deployment.ChartValuesForDeployment(deployColour, spec.AppVersion),
+		spec.ChartDir)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		r.logger.Printf("%s is unchanged, skipping the colour flip", green(deploymentName))
+		return r.reportUnchanged(deploymentName, deployedRelease, spec.FailOnNoChange)
+	}
+
+	r.logger.Println("Now updating the online deployment replica set to a minimum of 1.")
+	scaleOnlineReplicaSetResult := r.scaleReplicaSet(deploymentName, 1)
+	if scaleOnlineReplicaSetResult != nil {
+		return runtime.ClusterError(fmt.Errorf("Failed to scale replica set HPA: %v", scaleOnlineReplicaSetResult))
+	}
+	r.logger.Printf("Successfully deployed %s", green(deploymentName))
+	r.printRelease(deployedRelease)
+	r.logPhase(Phase{Event: PHASE_RELEASE_DEPLOYED, Release: deploymentName, Revision: deployedRelease.Version, Colour: deployColour})
+
+	r.logger.Printf("Running the pre-cutover health gate against %s..", green(deploymentName))
+	gateErr := r.runHealthGate(spec.TargetEnv, deployColour, spec.AppName, chartValuesYaml, spec.SmokeTest, spec.MaxRestarts)
+	if gateErr != nil {
+		r.logger.Printf("Health gate failed: %s", gateErr)
+		r.logger.Printf("The service release will not be touched, scaling %s back down..", green(deploymentName))
+		r.detach()
+		r.scaleDownDeployment(deploymentName, 0)
+		return runtime.DeployFailedError(fmt.Errorf("%w for %s: %s", ErrHealthGateFailed, deploymentName, gateErr))
+	}
+	r.logger.Println("Health gate passed!")
+
+	r.logger.Printf("Restoring the Horizontal Pod Autoscaler of %s before it goes live..", green(deploymentName))
+	restoreResult := r.restoreHPA(deploymentName)
+	if restoreResult != nil {
+		r.logger.Printf("Failed to restore HPA: %v", restoreResult)
+		r.logger.Println("The chart's HPA will be used as deployed; continuing.")
+	}
+
+	r.logger.Println("For the deployment to go live, the service selector colour will be updated")
+	serviceDeploymentName := deployment.ServiceReleaseName(spec.TargetEnv, spec.AppName)
+	r.logger.Printf("Preparing to deploy %s..", green(serviceDeploymentName))
+	deployedServiceRelease, _, err := r.releaseWithValues(
+		serviceDeploymentName,
+		chartValuesYaml,
+		deployment.ChartValuesForServiceRelease(deployColour),
+		spec.ChartDir)
+	if err != nil {
+		return err
+	}
+	r.logger.Printf("Successfully deployed %s, the service is now live!", green(serviceDeploymentName))
+	r.printRelease(deployedServiceRelease)
+	r.logPhase(Phase{Event: PHASE_SERVICE_SWITCHED, Release: serviceDeploymentName, Revision: deployedServiceRelease.Version, Colour: deployColour})
+	r.report.LiveColourAfter = deployColour
+
+	if err := r.retireOfflineDeployment(spec.TargetEnv, spec.AppName, spec.KeepWarm); err != nil {
+		return err
+	}
+	r.logger.Println("Updates complete!")
+
+	return nil
+}
+
+func (r *run) assertChartIsBlueGreen(chartDir string) error {
+	chartYamlPath := charts.ChartYamlPath(chartDir)
+	r.logger.Printf("Checking %s for blue-green-microservice dependency..", green(chartYamlPath))
+	hasBlueGreenDependency, err := charts.HasDependency(chartYamlPath, "blue-green-microservice", "bluegreen")
+	if err != nil {
+		return runtime.ValidationError(err)
+	}
+	if !hasBlueGreenDependency {
+		return runtime.ValidationError(errors.New(fmt.Sprintf("Dependency %s must be present and aliased to %s in the %s file in order to deploy using this program.", green("blue-green-microservice"), green("bluegreen"), green(chartYamlPath))))
+	}
+	return r.assertDependencyVersion(chartDir, "blue-green-microservice", r.env.MinBlueGreenMicroserviceVersion)
+}
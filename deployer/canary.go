@@ -0,0 +1,259 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/k8s"
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// canary describes a canary deploy of the offline colour of a bluegreen chart
+// next to the stable, live, colour.
+type canary struct {
+	*run
+	targetEnv      string
+	appName        string
+	stableColour   string
+	canaryColour   string
+	stableReplicas int32
+	maxRestarts    int32
+	pause          time.Duration
+}
+
+func (r *run) deployCanary(spec Spec) error {
+	if err := r.prepareDependencies(spec); err != nil {
+		return err
+	}
+
+	r.logger.Println("Asserting that this is a bluegreen microservice chart..")
+	if err := r.assertChartIsBlueGreen(spec.ChartDir); err != nil {
+		return err
+	}
+	r.logger.Println("This is a bluegreen microservice chart!")
+
+	r.logger.Println("Loading chart values..")
+	chartValuesYaml, err := r.loadChartValues(spec)
+	if err != nil {
+		return err
+	}
+	r.logger.Println("Successfully loaded chart values")
+
+	if err := r.validateReleaseValues(chartValuesYaml, spec.ChartDir, blueGreenReleaseEdits(spec)); err != nil {
+		return err
+	}
+
+	targetEnv, appName := spec.TargetEnv, spec.AppName
+	r.logger.Println("Determining the stable and canary colours..")
+	stableColour := r.currentLiveColour(targetEnv, appName)
+	if stableColour == "" {
+		return runtime.ValidationError(fmt.Errorf("No live colour found for %s in %s, a canary needs a stable release to run next to, use %s for the first deploy", green(appName), green(targetEnv), orange(DeployType.BLUEGREEN)))
+	}
+	canaryColour, err := r.determineDeployColour(targetEnv, appName)
+	if err != nil {
+		return err
+	}
+	if canaryColour == stableColour {
+		return runtime.ValidationError(fmt.Errorf("The live and offline services both select %s, unable to run a canary", green(stableColour)))
+	}
+	r.logger.Printf("Stable colour: %s, canary colour: %s", green(stableColour), green(canaryColour))
+	r.logPhase(Phase{Event: PHASE_COLOUR_DETERMINED, Colour: canaryColour})
+	r.report.LiveColourBefore = stableColour
+	r.report.LiveColourAfter = stableColour
+
+	if err := r.assertLiveServiceCanBeShared(targetEnv, appName); err != nil {
+		return err
+	}
+
+	stableDeploymentName := deployment.BlueGreenDeploymentName(targetEnv, stableColour, appName)
+	stableReplicas, err := r.deploymentReplicas(stableDeploymentName)
+	if err != nil {
+		return err
+	}
+	r.logger.Printf("%s is running %d replica(s), the canary will share them", green(stableDeploymentName), stableReplicas)
+
+	canaryDeploymentName := deployment.BlueGreenDeploymentName(targetEnv, canaryColour, appName)
+	r.logger.Printf("Preparing to deploy %s..", green(canaryDeploymentName))
+	deployedRelease, changed, err := r.releaseWithValues(
+		canaryDeploymentName,
+		chartValuesYaml,
+		deployment.ChartValuesForDeployment(canaryColour, spec.AppVersion),
+		spec.ChartDir)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		r.logger.Printf("%s is unchanged, skipping the canary", green(canaryDeploymentName))
+		return r.reportUnchanged(canaryDeploymentName, deployedRelease, spec.FailOnNoChange)
+	}
+	r.logger.Printf("Successfully deployed %s", green(canaryDeploymentName))
+	r.printRelease(deployedRelease)
+	r.logPhase(Phase{Event: PHASE_RELEASE_DEPLOYED, Release: canaryDeploymentName, Revision: deployedRelease.Version, Colour: canaryColour})
+
+	steps := spec.CanarySteps
+	if len(steps) == 0 {
+		steps = deployment.CanarySteps(deployment.DEFAULT_CANARY_STEPS)
+	}
+	c := &canary{
+		run:            r,
+		targetEnv:      targetEnv,
+		appName:        appName,
+		stableColour:   stableColour,
+		canaryColour:   canaryColour,
+		stableReplicas: stableReplicas,
+		maxRestarts:    spec.MaxRestarts,
+		pause:          spec.StepPause,
+	}
+
+	r.logger.Println("The autoscalers of both colours will be removed while traffic is shifted")
+	r.removeHPA(stableDeploymentName)
+	r.removeHPA(canaryDeploymentName)
+
+	if stepErr := c.shiftTraffic(steps); stepErr != nil {
+		r.logger.Printf("Canary failed: %s", stepErr)
+		return c.revert(stepErr)
+	}
+
+	r.logger.Println("The canary is taking all of the traffic, the service selector colour will now be updated")
+	serviceDeploymentName := deployment.ServiceReleaseName(targetEnv, appName)
+	r.logger.Printf("Preparing to deploy %s..", green(serviceDeploymentName))
+	deployedServiceRelease, _, err := r.releaseWithValues(
+		serviceDeploymentName,
+		chartValuesYaml,
+		deployment.ChartValuesForServiceRelease(canaryColour),
+		spec.ChartDir)
+	if err != nil {
+		return c.revert(err)
+	}
+	r.logger.Printf("Successfully deployed %s, the canary is now live!", green(serviceDeploymentName))
+	r.printRelease(deployedServiceRelease)
+	r.logPhase(Phase{Event: PHASE_SERVICE_SWITCHED, Release: serviceDeploymentName, Revision: deployedServiceRelease.Version, Colour: canaryColour})
+	r.report.LiveColourAfter = canaryColour
+
+	r.logger.Printf("Restoring the Horizontal Pod Autoscaler of %s now it is live..", green(canaryDeploymentName))
+	if restoreErr := r.restoreHPA(canaryDeploymentName); restoreErr != nil {
+		r.logger.Printf("Failed to restore HPA: %v", restoreErr)
+		r.logger.Println("The chart's HPA will be used as deployed; continuing.")
+	}
+
+	if err := r.retireOfflineDeployment(targetEnv, appName, spec.KeepWarm); err != nil {
+		return err
+	}
+	r.logger.Println("Updates complete!")
+
+	return nil
+}
+
+// shiftTraffic steps the canary's share of the replicas through weights,
+// checking its health after pausing at each. The live service is only opened
+// up to both colours once the canary is ready at the first weight, so that it
+// never takes more than that share of the traffic.
+func (c *canary) shiftTraffic(weights []int) error {
+	canaryDeploymentName := deployment.BlueGreenDeploymentName(c.targetEnv, c.canaryColour, c.appName)
+	stableDeploymentName := deployment.BlueGreenDeploymentName(c.targetEnv, c.stableColour, c.appName)
+	for i, weight := range weights {
+		canaryReplicas, stableReplicas := deployment.CanaryReplicas(c.stableReplicas, weight)
+		c.logger.Printf("Shifting %d%% of traffic to the canary: %s at %d replica(s)..", weight, green(canaryDeploymentName), canaryReplicas)
+		if err := c.scaleReplicaSet(canaryDeploymentName, canaryReplicas); err != nil {
+			return fmt.Errorf("Failed to scale %s: %s", canaryDeploymentName, err)
+		}
+		if err := c.waitForReadyReplicas(canaryDeploymentName, canaryReplicas); err != nil {
+			return fmt.Errorf("%s did not become ready at %d%%: %s", canaryDeploymentName, weight, err)
+		}
+		if i == 0 {
+			c.logger.Println("Opening the live service up to both colours..")
+			if err := c.patchLiveServiceColour(c.targetEnv, c.appName, ""); err != nil {
+				return err
+			}
+			c.logger.Println("The live service now routes traffic to both colours")
+		}
+		if stableReplicas > 0 {
+			c.logger.Printf("Scaling %s to %d replica(s)..", green(stableDeploymentName), stableReplicas)
+			if err := c.scaleReplicaSet(stableDeploymentName, stableReplicas); err != nil {
+				return fmt.Errorf("Failed to scale %s: %s", stableDeploymentName, err)
+			}
+		}
+
+		c.logger.Printf("Pausing for %s before checking the canary..", c.pause)
+		select {
+		case <-time.After(c.pause):
+		case <-c.ctx.Done():
+			return fmt.Errorf("Cancelled at %d%%: %w", weight, c.ctx.Err())
+		}
+		c.logger.Printf("Checking pod readiness and restart counts of %s..", green(canaryDeploymentName))
+		if err := c.checkDeploymentPods(canaryDeploymentName, c.maxRestarts); err != nil {
+			return fmt.Errorf("Health check failed at %d%%: %s", weight, err)
+		}
+		c.logger.Printf("The canary is healthy at %d%%", weight)
+		c.logPhase(Phase{Event: PHASE_CANARY_STEP, Release: canaryDeploymentName, Colour: c.canaryColour, Weight: weight})
+	}
+	return nil
+}
+
+// revert puts all of the traffic back on the stable colour and rolls the
+// canary's release back, even when the run was cancelled, returning cause as a
+// failed deploy when that worked and a failed rollback when it didn't.
+func (c *canary) revert(cause error) error {
+	c.detach()
+	c.logger.Printf("Reverting to the stable colour %s..", green(c.stableColour))
+	stableDeploymentName := deployment.BlueGreenDeploymentName(c.targetEnv, c.stableColour, c.appName)
+	canaryDeploymentName := deployment.BlueGreenDeploymentName(c.targetEnv, c.canaryColour, c.appName)
+
+	c.logger.Printf("Scaling %s back to %d replica(s)..", green(stableDeploymentName), c.stableReplicas)
+	if err := c.scaleReplicaSet(stableDeploymentName, c.stableReplicas); err != nil {
+		return runtime.RollbackFailedError(fmt.Errorf("Canary error: %s, failed to scale %s back up: %s", cause, stableDeploymentName, err))
+	}
+	if err := c.waitForReadyReplicas(stableDeploymentName, c.stableReplicas); err != nil {
+		c.logger.Printf("%s is not fully ready yet: %s", green(stableDeploymentName), err)
+	}
+
+	c.logger.Printf("Pointing the live service back at %s..", green(c.stableColour))
+	if err := c.patchLiveServiceColour(c.targetEnv, c.appName, c.stableColour); err != nil {
+		return runtime.RollbackFailedError(fmt.Errorf("Canary error: %s, failed to point the live service back at %s: %s", cause, c.stableColour, err))
+	}
+	c.report.LiveColourAfter = c.stableColour
+	c.logger.Printf("The live service is routing traffic to %s again", green(c.stableColour))
+
+	if err := c.restoreHPA(stableDeploymentName); err != nil {
+		c.logger.Printf("Failed to restore the HPA of %s: %v", stableDeploymentName, err)
+	}
+
+	targetRelease, err := c.rollbackTarget(canaryDeploymentName, 0)
+	if err != nil {
+		c.logger.Printf("Not rolling %s back: %s", green(canaryDeploymentName), err)
+	} else if err := c.rollbackToRevision(canaryDeploymentName, targetRelease.Version); err != nil {
+		c.logger.Printf("Failed to roll %s back: %s", green(canaryDeploymentName), err)
+	}
+	c.scaleDownDeployment(canaryDeploymentName, 0)
+
+	return runtime.DeployFailedError(fmt.Errorf("Canary of %s failed and was reverted: %s", canaryDeploymentName, cause))
+}
+
+// assertLiveServiceCanBeShared checks the live service will still only select
+// the app's pods once its colour is removed.
+func (r *run) assertLiveServiceCanBeShared(targetEnv, appName string) error {
+	liveService, err := deployment.GetLiveService(r.ctx, r.kube.CoreV1(), r.namespace, targetEnv, appName)
+	if err != nil {
+		return runtime.ClusterError(err)
+	}
+	if !k8s.SelectsBeyondColour(liveService) {
+		return runtime.ValidationError(errors.New(fmt.Sprintf("The selector of %s only has a colour, removing it to share traffic would select every pod in the namespace", green(liveService.GetName()))))
+	}
+	return nil
+}
+
+// patchLiveServiceColour points the live service at colour, or at every colour
+// when colour is empty.
+func (r *run) patchLiveServiceColour(targetEnv, appName, colour string) error {
+	liveServiceName := deployment.LiveServiceName(targetEnv, appName)
+	_, err := r.kube.CoreV1().Services(r.namespace).Patch(r.ctx, liveServiceName, types.MergePatchType, k8s.ServiceSelectorColourPatch(colour), metav1.PatchOptions{})
+	if err != nil {
+		return runtime.ClusterError(fmt.Errorf("Failed to update the selector of %s: %s", liveServiceName, err))
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Hutchison-Technologies/helm-deployer/h3lm"
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// runHelm runs a helm action on releaseName that helm gives up on after
+// timeout. Helm's actions can't be cancelled, so the action runs in the
+// background: when the run is cancelled first, the action is waited for, for no
+// longer than its timeout, so that it doesn't write to the release while it is
+// settled. Once the action has returned, the release is settled and rolled
+// back. An action still running after that has its revision marked failed but
+// not rolled back, since helm may yet write to it.
+func (r *run) runHelm(releaseName string, timeout time.Duration, helmAction func() (*release.Release, error)) (*release.Release, error) {
+	if r.ctx.Err() != nil {
+		r.detach()
+		return nil, runtime.DeployFailedError(fmt.Errorf("Not touching %s, the deploy was cancelled: %w", releaseName, r.cancelled))
+	}
+
+	type outcome struct {
+		release *release.Release
+		err     error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		rel, err := helmAction()
+		done <- outcome{rel, err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.release, result.err
+	case <-r.ctx.Done():
+		cause := r.ctx.Err()
+		r.logger.Printf("The deploy was cancelled (%s) while %s was in flight", cause, green(releaseName))
+		r.detach()
+
+		r.logger.Printf("Waiting up to %s for helm to finish with %s before settling it..", timeout, green(releaseName))
+		select {
+		case result := <-done:
+			var completed *release.Release
+			if result.err == nil {
+				completed = result.release
+			}
+			return nil, r.settleCancelledRelease(releaseName, completed, cause)
+		case <-time.After(timeout):
+			r.logger.Printf("Helm is still working on %s after %s, marking it failed without rolling it back", green(releaseName), timeout)
+			return nil, r.abandonCancelledRelease(releaseName, cause)
+		}
+	}
+}
+
+// detach lets the cleanup that follows a cancelled run still reach the
+// cluster, by swapping the run's context for one that isn't cancelled.
+func (r *run) detach() {
+	if r.cancelled == nil && r.ctx.Err() != nil {
+		r.cancelled = r.ctx.Err()
+		r.ctx = context.Background()
+	}
+}
+
+// settleCancelledRelease marks the revision of releaseName that was in flight
+// failed, once the cancelled action has returned, whether it is still pending
+// or the action completed it, so that the next deploy isn't blocked by it, and
+// rolls back to the latest revision deployed before it when there is one.
+func (r *run) settleCancelledRelease(releaseName string, completed *release.Release, cause error) error {
+	inFlight, err := r.helmConfig.Releases.Last(releaseName)
+	if err != nil {
+		r.logger.Printf("%s has no pending revision, nothing to settle", green(releaseName))
+		return runtime.DeployFailedError(fmt.Errorf("%s was cancelled: %w", releaseName, cause))
+	}
+	switch {
+	case inFlight.Info.Status.IsPending(), completed != nil && completed.Version == inFlight.Version:
+		if err := r.markCancelledRevisionFailed(releaseName, inFlight, cause); err != nil {
+			return err
+		}
+	case inFlight.Info.Status == release.StatusFailed:
+		r.logger.Printf("Revision %d of %s failed after the deploy was cancelled", inFlight.Version, green(releaseName))
+	default:
+		r.logger.Printf("%s has no pending revision, nothing to settle", green(releaseName))
+		return runtime.DeployFailedError(fmt.Errorf("%s was cancelled: %w", releaseName, cause))
+	}
+
+	return r.rollbackCancelledRelease(releaseName, inFlight, cause)
+}
+
+// abandonCancelledRelease marks the revision of releaseName that helm is still
+// working on failed, so that the next deploy isn't blocked by it.
+func (r *run) abandonCancelledRelease(releaseName string, cause error) error {
+	inFlight, err := r.helmConfig.Releases.Last(releaseName)
+	if err != nil || !inFlight.Info.Status.IsPending() {
+		r.logger.Printf("%s has no pending revision, nothing to settle", green(releaseName))
+		return runtime.DeployFailedError(fmt.Errorf("%s was cancelled: %w", releaseName, cause))
+	}
+	if err := r.markCancelledRevisionFailed(releaseName, inFlight, cause); err != nil {
+		return err
+	}
+	return runtime.DeployFailedError(fmt.Errorf("%s was cancelled and marked failed while helm was still working on it: %w", releaseName, cause))
+}
+
+func (r *run) markCancelledRevisionFailed(releaseName string, inFlight *release.Release, cause error) error {
+	r.logger.Printf("Marking revision %d of %s failed..", inFlight.Version, green(releaseName))
+	inFlight.SetStatus(release.StatusFailed, fmt.Sprintf("Cancelled: %s", cause))
+	if err := r.helmConfig.Releases.Update(inFlight); err != nil {
+		return runtime.RollbackFailedError(fmt.Errorf("%s was cancelled: %s, unable to mark revision %d failed: %s", releaseName, cause, inFlight.Version, err))
+	}
+	return nil
+}
+
+// rollbackCancelledRelease rolls releaseName back to the latest revision
+// deployed before inFlight, when there is one.
+func (r *run) rollbackCancelledRelease(releaseName string, inFlight *release.Release, cause error) error {
+	history, err := r.helmConfig.Releases.History(releaseName)
+	if err != nil {
+		return runtime.RollbackFailedError(fmt.Errorf("%s was cancelled: %s, unable to read its history: %s", releaseName, cause, err))
+	}
+	// A completed upgrade supersedes the revision that was deployed before it.
+	earlierReleases := h3lm.FilterReleasesBeforeVersion(history, inFlight.Version)
+	deployedReleases := append(
+		h3lm.FilterReleasesByStatusCode(earlierReleases, release.StatusDeployed),
+		h3lm.FilterReleasesByStatusCode(earlierReleases, release.StatusSuperseded)...)
+	if len(deployedReleases) == 0 {
+		r.logger.Printf("%s has no deployed revision to roll back to, leaving it failed", green(releaseName))
+		return runtime.DeployFailedError(fmt.Errorf("%s was cancelled and marked failed: %w", releaseName, cause))
+	}
+
+	target := h3lm.LatestRelease(deployedReleases)
+	if err := r.rollbackToRevision(releaseName, target.Version); err != nil {
+		return runtime.RollbackFailedError(fmt.Errorf("%s was cancelled: %s, rollback error: %s", releaseName, cause, err))
+	}
+	return runtime.DeployFailedError(fmt.Errorf("%s was cancelled and rolled back to revision %d: %w", releaseName, target.Version, cause))
+}
@@ -0,0 +1,56 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/k8s"
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+)
+
+// colours are the colours a bluegreen chart is deployed as.
+var colours = []string{"blue", "green"}
+
+// currentLiveColour returns the colour selected by the live service, or an
+// empty string when there is no live service yet.
+func (r *run) currentLiveColour(targetEnv, appName string) string {
+	liveService, err := deployment.GetLiveService(r.ctx, r.kube.CoreV1(), r.namespace, targetEnv, appName)
+	if err != nil || liveService == nil {
+		return ""
+	}
+	return k8s.ServiceSelectorColour(liveService)
+}
+
+func (r *run) determineDeployColour(targetEnv, appName string) (string, error) {
+	r.logger.Printf("Getting the offline service of %s in %s", green(appName), green(targetEnv))
+	offlineService, err := deployment.GetOfflineService(r.ctx, r.kube.CoreV1(), r.namespace, targetEnv, appName)
+	if err != nil {
+		r.logger.Println(err.Error())
+	}
+	if err != nil || offlineService == nil {
+		r.logger.Printf("Unable to locate offline service, this might be the first deploy, defaulting to: %s", green(DEFAULT_COLOUR))
+	} else {
+		r.logger.Printf("Found offline service %s, checking selector colour..", green(offlineService.GetName()))
+		offlineColour := k8s.ServiceSelectorColour(offlineService)
+		if offlineColour != "" {
+			return offlineColour, nil
+		}
+	}
+	return DEFAULT_COLOUR, nil
+}
+
+func (r *run) determineLiveColour(targetEnv, appName string) (string, error) {
+	r.logger.Printf("Getting the live service of %s in %s", green(appName), green(targetEnv))
+	liveService, err := deployment.GetLiveService(r.ctx, r.kube.CoreV1(), r.namespace, targetEnv, appName)
+	if err != nil {
+		return "", runtime.ClusterError(err)
+	}
+
+	r.logger.Printf("Found live service %s, checking selector colour..", green(liveService.GetName()))
+	liveColour := k8s.ServiceSelectorColour(liveService)
+	if liveColour == "" {
+		return "", runtime.ValidationError(errors.New(fmt.Sprintf("Live service %s has no selector colour", green(liveService.GetName()))))
+	}
+	return liveColour, nil
+}
@@ -0,0 +1,103 @@
+package deployer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Hutchison-Technologies/helm-deployer/charts"
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+
+	helmcli "helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/downloader"
+	"helm.sh/helm/v3/pkg/getter"
+)
+
+// assertDependencyVersion checks that the Chart.lock of chartDir resolves
+// depName to minimum or later, and that the resolved archive is vendored.
+func (r *run) assertDependencyVersion(chartDir, depName, minimum string) error {
+	r.logger.Printf("Checking %s for the resolved %s version..", green(charts.ChartLockPath(chartDir)), depName)
+	version, err := charts.LockedDependencyVersion(chartDir, depName)
+	if err != nil {
+		return runtime.ValidationError(err)
+	}
+	if !charts.IsVersionAtLeast(version, minimum) {
+		return runtime.ValidationError(fmt.Errorf("Dependency %s resolves to %s in %s, but at least %s is needed in order to deploy using this program.", green(depName), orange(version), green(charts.ChartLockPath(chartDir)), green(minimum)))
+	}
+	r.logger.Printf("Resolved %s %s", depName, green(version))
+	return nil
+}
+
+// prepareDependencies builds and verifies the vendored dependencies of the
+// chart, as spec asks, before anything loads it.
+func (r *run) prepareDependencies(spec Spec) error {
+	if spec.BuildDependencies {
+		if err := r.buildDependencies(spec.ChartDir); err != nil {
+			return err
+		}
+	}
+	if spec.VerifyDependencies {
+		return r.verifyDependencies(spec.ChartDir)
+	}
+	return nil
+}
+
+// buildDependencies runs helm's dependency build to vendor the dependencies
+// locked in Chart.lock, unless they are all vendored already. The repository
+// indexes are read from the repository cache rather than updated, so only the
+// archives of dependencies outside file:// repositories are downloaded.
+func (r *run) buildDependencies(chartDir string) error {
+	r.logger.Printf("Building the dependencies of %s from %s..", green(chartDir), green(charts.ChartLockPath(chartDir)))
+	lock, err := charts.ReadLock(chartDir)
+	if err != nil {
+		return runtime.ValidationError(err)
+	}
+
+	missing := []string{}
+	for _, dep := range lock.Dependencies {
+		if charts.CheckVendoredDependency(chartDir, dep) != nil {
+			missing = append(missing, fmt.Sprintf("%s %s", dep.Name, dep.Version))
+		}
+	}
+	if len(missing) == 0 {
+		r.logger.Printf("The dependencies of %s are vendored", green(chartDir))
+		return nil
+	}
+
+	r.logger.Printf("%s not vendored, running helm dependency build..", strings.Join(missing, ", "))
+	settings := helmcli.New()
+	settings.RepositoryConfig = r.repositoryConfig
+	settings.RepositoryCache = r.repositoryCache
+	manager := &downloader.Manager{
+		Out:              r.logger.Writer(),
+		ChartPath:        chartDir,
+		SkipUpdate:       true,
+		Getters:          getter.All(settings),
+		RepositoryConfig: r.repositoryConfig,
+		RepositoryCache:  r.repositoryCache,
+	}
+	if err := manager.Build(); err != nil {
+		return runtime.ValidationError(fmt.Errorf("Could not build the dependencies of %s, %s", chartDir, err))
+	}
+	return nil
+}
+
+// verifyDependencies checks that Chart.lock is in sync with Chart.yaml and
+// that each archive it resolved is vendored, without fetching anything.
+func (r *run) verifyDependencies(chartDir string) error {
+	r.logger.Printf("Verifying the vendored dependencies of %s against %s..", green(chartDir), green(charts.ChartLockPath(chartDir)))
+	lock, err := charts.ReadLock(chartDir)
+	if err != nil {
+		return runtime.ValidationError(err)
+	}
+	problems := []string{}
+	for _, dep := range lock.Dependencies {
+		if err := charts.CheckVendoredDependency(chartDir, dep); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return runtime.ValidationError(fmt.Errorf("The vendored dependencies of %s don't match its lock:\n\t%s", chartDir, strings.Join(problems, "\n\t")))
+	}
+	r.logger.Printf("All %d dependencies of %s are vendored as locked", len(lock.Dependencies), green(chartDir))
+	return nil
+}
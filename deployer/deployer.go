@@ -0,0 +1,260 @@
+// Package deployer deploys bluegreen, canary, microservice and standard
+// charts with helm, using clients supplied by the caller, so that deploys can
+// be driven from Go as well as from the helm-deployer command.
+package deployer
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Hutchison-Technologies/helm-deployer/config"
+
+	"helm.sh/helm/v3/pkg/action"
+	helmcli "helm.sh/helm/v3/pkg/cli"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	DEFAULT_COLOUR            = "blue"
+	DEFAULT_SCALE_UP_TIMEOUT  = 300 * time.Second
+	DEFAULT_FAILURE_LOG_LINES = 50
+	ROLLBACK_VERSION_POOL     = 50
+)
+
+const (
+	RECOVERY_ROLLED_BACK = "rolled_back"
+	RECOVERY_UNINSTALLED = "uninstalled"
+)
+
+const (
+	PHASE_COLOUR_DETERMINED   = "colour_determined"
+	PHASE_RELEASE_DEPLOYED    = "release_deployed"
+	PHASE_RELEASE_UNCHANGED   = "release_unchanged"
+	PHASE_RELEASE_ROLLED_BACK = "release_rolled_back"
+	PHASE_RELEASE_RECOVERED   = "release_recovered"
+	PHASE_SERVICE_SWITCHED    = "service_switched"
+	PHASE_OFFLINE_SCALED      = "offline_scaled"
+	PHASE_CANARY_STEP         = "canary_step"
+)
+
+// Options configure a Deployer. HelmConfig and KubeClient are required, the
+// rest have defaults.
+type Options struct {
+	HelmConfig *action.Configuration
+	KubeClient kubernetes.Interface
+	// Namespace to deploy into, defaults to the Environment's namespace.
+	Namespace string
+	// Logger receives progress, defaults to stderr.
+	Logger *log.Logger
+	// Out receives plans, defaults to stdout.
+	Out io.Writer
+	// Environment supplies the install, upgrade and rollback timeouts and
+	// helm options, defaults to the default environment.
+	Environment *config.Environment
+	// ScaleUpTimeout bounds waits for deployments to become ready.
+	ScaleUpTimeout time.Duration
+	// ArtifactsDir, when set, is where diagnostics of failed releases are
+	// written.
+	ArtifactsDir string
+	// FailureLogLines is the number of log lines collected from each crashing
+	// container of a failed release.
+	FailureLogLines int64
+	// OnPhase, when set, is called as each phase of a deploy completes.
+	OnPhase func(Phase)
+	// Colour keeps the ANSI colour codes that highlight releases and colours
+	// in what is written to Logger and Out, which are stripped by default.
+	Colour bool
+	// RepositoryConfig and RepositoryCache are helm's repositories.yaml and
+	// repository cache, used to build dependencies. They default to helm's,
+	// which $HELM_REPOSITORY_CONFIG and $HELM_REPOSITORY_CACHE override.
+	RepositoryConfig string
+	RepositoryCache  string
+}
+
+// Deployer runs deploys against the cluster and helm configuration it was
+// created with. Cancelling the context of a deploy stops it, marking a release
+// caught mid-flight failed and, once helm has finished with it, rolling it back
+// to its latest deployed revision when it has one.
+type Deployer struct {
+	helmConfig       *action.Configuration
+	kube             kubernetes.Interface
+	namespace        string
+	logger           *log.Logger
+	out              io.Writer
+	env              *config.Environment
+	scaleUpTimeout   time.Duration
+	artifactsDir     string
+	failureLogLines  int64
+	onPhase          func(Phase)
+	repositoryConfig string
+	repositoryCache  string
+}
+
+// Spec describes what to deploy. Commands ignore the fields they don't use.
+type Spec struct {
+	ChartDir   string
+	AppName    string
+	AppVersion string
+	TargetEnv  string
+	// FailOnNoChange fails a deploy that changes nothing.
+	FailOnNoChange bool
+	// SmokeTest, MaxRestarts and KeepWarm configure bluegreen, canary and swap
+	// cutovers.
+	SmokeTest   bool
+	MaxRestarts int32
+	KeepWarm    int32
+	// CanarySteps and StepPause configure canary deploys.
+	CanarySteps []int
+	StepPause   time.Duration
+	// Revision and Colour select what to roll back to, see Rollback.
+	Revision int
+	Colour   string
+	// ValuesFiles, Set and SetString override the chart's values, in that
+	// order, after values.yaml, common.yaml and <env>.yaml. Set and SetString
+	// take helm's --set syntax.
+	ValuesFiles []string
+	Set         []string
+	SetString   []string
+	// ShowValues writes the merged values to Out before deploying.
+	ShowValues bool
+	// BuildDependencies vendors the dependencies locked in Chart.lock before
+	// the chart is loaded, and VerifyDependencies checks that they are.
+	BuildDependencies  bool
+	VerifyDependencies bool
+}
+
+// Phase is reported to Options.OnPhase as each phase of a deploy completes.
+type Phase struct {
+	Event    string
+	Release  string
+	Revision int
+	Colour   string
+	Weight   int
+}
+
+// run is the state of a single call to a Deployer.
+type run struct {
+	*Deployer
+	ctx    context.Context
+	report *Report
+	// cancelled is why ctx was cancelled, once the run has been detached from
+	// it to clean up.
+	cancelled error
+}
+
+func New(options Options) *Deployer {
+	deployer := &Deployer{
+		helmConfig:       options.HelmConfig,
+		kube:             options.KubeClient,
+		namespace:        options.Namespace,
+		logger:           options.Logger,
+		out:              options.Out,
+		env:              options.Environment,
+		scaleUpTimeout:   options.ScaleUpTimeout,
+		artifactsDir:     options.ArtifactsDir,
+		failureLogLines:  options.FailureLogLines,
+		onPhase:          options.OnPhase,
+		repositoryConfig: options.RepositoryConfig,
+		repositoryCache:  options.RepositoryCache,
+	}
+	if deployer.env == nil {
+		deployer.env = &config.Environment{
+			Namespace:       config.DEFAULT_NAMESPACE,
+			Timeout:         config.DEFAULT_TIMEOUT,
+			InstallTimeout:  config.DEFAULT_TIMEOUT,
+			UpgradeTimeout:  config.DEFAULT_TIMEOUT,
+			RollbackTimeout: config.DEFAULT_ROLLBACK_TIMEOUT,
+			PendingTimeout:  config.DEFAULT_PENDING_TIMEOUT,
+
+			MinBlueGreenMicroserviceVersion: config.DEFAULT_MIN_BLUE_GREEN_MICROSERVICE_VERSION,
+			MinMicroserviceVersion:          config.DEFAULT_MIN_MICROSERVICE_VERSION,
+		}
+	}
+	if deployer.namespace == "" {
+		deployer.namespace = deployer.env.Namespace
+	}
+	if deployer.logger == nil {
+		deployer.logger = log.New(os.Stderr, "", log.LstdFlags)
+	}
+	if deployer.out == nil {
+		deployer.out = os.Stdout
+	}
+	if !options.Colour {
+		deployer.logger = log.New(&plainWriter{deployer.logger.Writer()}, deployer.logger.Prefix(), deployer.logger.Flags())
+		deployer.out = &plainWriter{deployer.out}
+	}
+	if deployer.scaleUpTimeout <= 0 {
+		deployer.scaleUpTimeout = DEFAULT_SCALE_UP_TIMEOUT
+	}
+	if deployer.failureLogLines <= 0 {
+		deployer.failureLogLines = DEFAULT_FAILURE_LOG_LINES
+	}
+	if deployer.repositoryConfig == "" || deployer.repositoryCache == "" {
+		settings := helmcli.New()
+		if deployer.repositoryConfig == "" {
+			deployer.repositoryConfig = settings.RepositoryConfig
+		}
+		if deployer.repositoryCache == "" {
+			deployer.repositoryCache = settings.RepositoryCache
+		}
+	}
+	return deployer
+}
+
+func (deployer *Deployer) start(ctx context.Context) *run {
+	return &run{Deployer: deployer, ctx: ctx, report: NewReport()}
+}
+
+// DeployBlueGreen deploys the offline colour of a bluegreen chart, checks its
+// health and switches the service over to it.
+func (deployer *Deployer) DeployBlueGreen(ctx context.Context, spec Spec) (*Report, error) {
+	r := deployer.start(ctx)
+	return r.report, r.deployBlueGreen(spec)
+}
+
+// DeployCanary deploys the offline colour of a bluegreen chart next to the live
+// colour, shifting traffic to it through spec.CanarySteps.
+func (deployer *Deployer) DeployCanary(ctx context.Context, spec Spec) (*Report, error) {
+	r := deployer.start(ctx)
+	return r.report, r.deployCanary(spec)
+}
+
+func (deployer *Deployer) DeployMicroservice(ctx context.Context, spec Spec) (*Report, error) {
+	r := deployer.start(ctx)
+	return r.report, r.deployMicroservice(spec)
+}
+
+func (deployer *Deployer) DeployStandardChart(ctx context.Context, spec Spec) (*Report, error) {
+	r := deployer.start(ctx)
+	return r.report, r.deployStandardChart(spec)
+}
+
+// Swap sends the traffic of a bluegreen service back to its offline colour.
+func (deployer *Deployer) Swap(ctx context.Context, spec Spec) (*Report, error) {
+	r := deployer.start(ctx)
+	return r.report, r.swap(spec)
+}
+
+// Rollback rolls back the bluegreen release of spec.Colour when given, else the
+// bluegreen service release or the standard release, to spec.Revision or the
+// previous successful revision.
+func (deployer *Deployer) Rollback(ctx context.Context, spec Spec) (*Report, error) {
+	r := deployer.start(ctx)
+	return r.report, r.rollback(spec)
+}
+
+// Plan diffs what deploying spec as deployType would change, returning
+// ErrPlanHasChanges when there is anything to change.
+func (deployer *Deployer) Plan(ctx context.Context, deployType string, spec Spec) (*Report, error) {
+	r := deployer.start(ctx)
+	return r.report, r.plan(deployType, spec)
+}
+
+func (r *run) logPhase(phase Phase) {
+	if r.onPhase != nil {
+		r.onPhase(phase)
+	}
+}
@@ -0,0 +1,81 @@
+package deployer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"testing"
+
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func testRun() *run {
+	return New(Options{
+		KubeClient: fake.NewSimpleClientset(),
+		Logger:     log.New(ioutil.Discard, "", 0),
+		Out:        ioutil.Discard,
+	}).start(context.TODO())
+}
+
+func Test_New_Applies_Defaults(t *testing.T) {
+	deployer := New(Options{})
+	assert.Equal(t, "default", deployer.namespace)
+	assert.Equal(t, DEFAULT_SCALE_UP_TIMEOUT, deployer.scaleUpTimeout)
+	assert.Equal(t, int64(DEFAULT_FAILURE_LOG_LINES), deployer.failureLogLines)
+	assert.NotNil(t, deployer.logger)
+	assert.NotNil(t, deployer.out)
+}
+
+func Test_New_Prefers_The_Given_Namespace(t *testing.T) {
+	assert.Equal(t, "apps", New(Options{Namespace: "apps"}).namespace)
+}
+
+func Test_New_Strips_Colour_From_The_Logger_And_Out_By_Default(t *testing.T) {
+	logged, out := &bytes.Buffer{}, &bytes.Buffer{}
+	deployer := New(Options{Logger: log.New(logged, "", 0), Out: out})
+	deployer.logger.Printf("Deploying: %s..", green("prod-blue-some-api"))
+	fmt.Fprintln(deployer.out, orange("Plan:"))
+	assert.Equal(t, "Deploying: prod-blue-some-api..\n", logged.String())
+	assert.Equal(t, "Plan:\n", out.String())
+}
+
+func Test_New_Keeps_Colour_When_Asked(t *testing.T) {
+	logged := &bytes.Buffer{}
+	deployer := New(Options{Logger: log.New(logged, "", 0), Colour: true})
+	deployer.logger.Print(green("prod-blue-some-api"))
+	assert.Equal(t, green("prod-blue-some-api")+"\n", logged.String())
+}
+
+func Test_StripColour_Removes_ANSI_Escapes(t *testing.T) {
+	assert.Equal(t, "Deploying: prod-blue-some-api..", StripColour("Deploying: "+green("prod-blue-some-api")+".."))
+}
+
+func Test_ReportUnchanged_Succeeds_By_Default(t *testing.T) {
+	assert.Nil(t, testRun().reportUnchanged("prod-some-api", nil, false))
+}
+
+func Test_ReportUnchanged_Returns_No_Changes_Error_When_Failing_On_No_Change(t *testing.T) {
+	assert.Equal(t, runtime.EXIT_CODE_NO_CHANGES, runtime.ExitCode(testRun().reportUnchanged("prod-some-api", nil, true)))
+}
+
+func Test_ReportUnchanged_Reports_The_Phase(t *testing.T) {
+	phases := []Phase{}
+	r := New(Options{Logger: log.New(ioutil.Discard, "", 0), OnPhase: func(phase Phase) { phases = append(phases, phase) }}).start(context.TODO())
+	assert.Nil(t, r.reportUnchanged("prod-some-api", nil, false))
+	assert.Equal(t, []Phase{{Event: PHASE_RELEASE_UNCHANGED, Release: "prod-some-api"}}, phases)
+}
+
+func Test_Plan_Requires_An_App_Version(t *testing.T) {
+	_, err := New(Options{Logger: log.New(ioutil.Discard, "", 0)}).Plan(context.TODO(), DeployType.BLUEGREEN, Spec{AppName: "some-api", TargetEnv: "prod"})
+	assert.Equal(t, runtime.EXIT_CODE_VALIDATION, runtime.ExitCode(err))
+}
+
+func Test_IsClusterFailure_Includes_Helms_Pending_Operation_Error(t *testing.T) {
+	assert.True(t, isClusterFailure(fmt.Errorf("Failed to dry-run prod-some-api: %w", errors.New(HELM_PENDING_OPERATION_ERROR))))
+	assert.False(t, isClusterFailure(errors.New("values don't meet the specifications of the schema")))
+}
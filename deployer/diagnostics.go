@@ -0,0 +1,211 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Hutchison-Technologies/helm-deployer/k8s"
+
+	"helm.sh/helm/v3/pkg/action"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const FAILURE_EVENT_LIMIT = 20
+
+// FailureReport describes the state of a failed release's Deployments: their
+// ReplicaSets, the pods that aren't ready, recent events and the logs of
+// crashing containers.
+type FailureReport struct {
+	ReleaseName string
+	LogLines    int64
+	Summary     []string
+	Logs        map[string]string
+}
+
+// reportFailure logs, and writes to the artifacts directory when given, what
+// went wrong with the latest revision of a failed release. It never fails:
+// anything that can't be collected is logged and skipped.
+func (r *run) reportFailure(releaseName string) {
+	r.logger.Printf("Collecting diagnostics for %s..", green(releaseName))
+	failedRelease, err := action.NewGet(r.helmConfig).Run(releaseName)
+	if err != nil {
+		r.logger.Printf("Unable to collect diagnostics, failed to get %s: %s", releaseName, err)
+		return
+	}
+	report, err := CollectFailureReport(r.ctx, r.kube, r.namespace, releaseName, failedRelease.Manifest, r.failureLogLines)
+	if err != nil {
+		r.logger.Printf("Unable to collect diagnostics: %s", err)
+		return
+	}
+	report.Print(r.logger)
+	if r.artifactsDir == "" {
+		return
+	}
+	dir, err := report.Write(r.artifactsDir)
+	if err != nil {
+		r.logger.Printf("Failed to write diagnostics: %s", err)
+		return
+	}
+	r.logger.Printf("Wrote diagnostics to %s", green(dir))
+}
+
+// CollectFailureReport gathers the state of the Deployments in
+// releaseManifest. Problems reading any one resource are noted in the report
+// rather than returned.
+func CollectFailureReport(ctx context.Context, kube kubernetes.Interface, namespace, releaseName, releaseManifest string, logLines int64) (*FailureReport, error) {
+	deploymentNames, err := k8s.FindDeploymentNamesInManifest(releaseManifest)
+	if err != nil {
+		return nil, err
+	}
+	report := &FailureReport{ReleaseName: releaseName, LogLines: logLines, Summary: []string{}, Logs: map[string]string{}}
+	if len(deploymentNames) == 0 {
+		report.add("%s has no Deployments", releaseName)
+		return report, nil
+	}
+
+	objectNames := []string{}
+	for _, deploymentName := range deploymentNames {
+		objectNames = append(objectNames, report.collectDeployment(ctx, kube, namespace, deploymentName)...)
+	}
+
+	events, err := kube.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		report.add("Failed to list events: %s", err)
+		return report, nil
+	}
+	recentEvents := k8s.RecentEvents(events.Items, objectNames, FAILURE_EVENT_LIMIT)
+	report.add("Recent events (%d):", len(recentEvents))
+	for i := range recentEvents {
+		report.add("\t%s", k8s.DescribeEvent(&recentEvents[i]))
+	}
+	return report, nil
+}
+
+// collectDeployment reports on a Deployment, its ReplicaSets and its pods,
+// returning the names of everything it looked at so their events can be found.
+func (report *FailureReport) collectDeployment(ctx context.Context, kube kubernetes.Interface, namespace, deploymentName string) []string {
+	objectNames := []string{deploymentName}
+	dep, err := kube.AppsV1().Deployments(namespace).Get(ctx, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		report.add("Failed to get Deployment %s: %s", deploymentName, err)
+		return objectNames
+	}
+	desired := int32(1)
+	if dep.Spec.Replicas != nil {
+		desired = *dep.Spec.Replicas
+	}
+	report.add("Deployment %s: %d/%d replicas ready, %d updated", deploymentName, dep.Status.ReadyReplicas, desired, dep.Status.UpdatedReplicas)
+
+	selector, err := metav1.LabelSelectorAsSelector(dep.Spec.Selector)
+	if err != nil {
+		report.add("Failed to read the pod selector of %s: %s", deploymentName, err)
+		return objectNames
+	}
+	listOptions := metav1.ListOptions{LabelSelector: selector.String()}
+
+	replicaSets, err := kube.AppsV1().ReplicaSets(namespace).List(ctx, listOptions)
+	if err != nil {
+		report.add("Failed to list the ReplicaSets of %s: %s", deploymentName, err)
+	} else {
+		for i := range replicaSets.Items {
+			replicaSet := &replicaSets.Items[i]
+			if !metav1.IsControlledBy(replicaSet, dep) {
+				continue
+			}
+			objectNames = append(objectNames, replicaSet.GetName())
+			report.add("\tReplicaSet %s (revision %s): %d/%d replicas ready", replicaSet.GetName(), replicaSet.GetAnnotations()["deployment.kubernetes.io/revision"], replicaSet.Status.ReadyReplicas, replicaSetDesired(replicaSet))
+		}
+	}
+
+	pods, err := kube.CoreV1().Pods(namespace).List(ctx, listOptions)
+	if err != nil {
+		report.add("Failed to list the pods of %s: %s", deploymentName, err)
+		return objectNames
+	}
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		objectNames = append(objectNames, pod.GetName())
+		if k8s.IsPodReady(pod) {
+			continue
+		}
+		report.add("\tPod %s is not ready, phase: %s", pod.GetName(), pod.Status.Phase)
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			report.add("\t\t%s", k8s.DescribeContainerState(containerStatus))
+			if k8s.IsContainerCrashing(containerStatus) {
+				report.collectLogs(ctx, kube, namespace, pod, containerStatus)
+			}
+		}
+	}
+	return objectNames
+}
+
+// collectLogs tails a crashing container's logs, from its previous run when it
+// has restarted, since that is the run that crashed.
+func (report *FailureReport) collectLogs(ctx context.Context, kube kubernetes.Interface, namespace string, pod *corev1.Pod, containerStatus corev1.ContainerStatus) {
+	logOptions := &corev1.PodLogOptions{
+		Container: containerStatus.Name,
+		TailLines: &report.LogLines,
+		Previous:  containerStatus.RestartCount > 0,
+	}
+	logs, err := kube.CoreV1().Pods(namespace).GetLogs(pod.GetName(), logOptions).DoRaw(ctx)
+	if err != nil {
+		report.add("\t\tFailed to get the logs of %s/%s: %s", pod.GetName(), containerStatus.Name, err)
+		return
+	}
+	report.Logs[fmt.Sprintf("%s_%s", pod.GetName(), containerStatus.Name)] = string(logs)
+}
+
+func (report *FailureReport) add(format string, args ...interface{}) {
+	report.Summary = append(report.Summary, fmt.Sprintf(format, args...))
+}
+
+func (report *FailureReport) logNames() []string {
+	names := make([]string, 0, len(report.Logs))
+	for name := range report.Logs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (report *FailureReport) Print(logger *log.Logger) {
+	logger.Printf("Diagnostics for %s:\n%s", green(report.ReleaseName), strings.Join(report.Summary, "\n"))
+	for _, name := range report.logNames() {
+		logger.Printf("Last %d log lines of %s:\n%s", report.LogLines, green(name), report.Logs[name])
+	}
+}
+
+// Write saves the report under dir/<release name>, as summary.txt and a
+// <pod>_<container>.log per crashing container, returning the directory used.
+func (report *FailureReport) Write(dir string) (string, error) {
+	releaseDir := filepath.Join(dir, report.ReleaseName)
+	if err := os.MkdirAll(releaseDir, 0755); err != nil {
+		return "", err
+	}
+	summary := strings.Join(report.Summary, "\n") + "\n"
+	if err := ioutil.WriteFile(filepath.Join(releaseDir, "summary.txt"), []byte(StripColour(summary)), 0644); err != nil {
+		return "", err
+	}
+	for name, logs := range report.Logs {
+		if err := ioutil.WriteFile(filepath.Join(releaseDir, name+".log"), []byte(logs), 0644); err != nil {
+			return "", err
+		}
+	}
+	return releaseDir, nil
+}
+
+func replicaSetDesired(replicaSet *appsv1.ReplicaSet) int32 {
+	if replicaSet.Spec.Replicas == nil {
+		return 1
+	}
+	return *replicaSet.Spec.Replicas
+}
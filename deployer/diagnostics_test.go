@@ -0,0 +1,135 @@
+package deployer
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const DIAGNOSTICS_TEST_MANIFEST = `---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: prod-blue-some-api
+`
+
+func diagnosticsTestClientset() *fake.Clientset {
+	isController := true
+	replicas := int32(2)
+	labels := map[string]string{"app": "some-api"}
+	dep := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod-blue-some-api", Namespace: "default", UID: types.UID("dep-uid")},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+		},
+		Status: appsv1.DeploymentStatus{ReadyReplicas: 1, UpdatedReplicas: 2},
+	}
+	replicaSet := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "prod-blue-some-api-abc123",
+			Namespace:       "default",
+			Labels:          labels,
+			Annotations:     map[string]string{"deployment.kubernetes.io/revision": "3"},
+			OwnerReferences: []metav1.OwnerReference{{Name: dep.Name, UID: dep.UID, Controller: &isController}},
+		},
+		Spec:   appsv1.ReplicaSetSpec{Replicas: &replicas},
+		Status: appsv1.ReplicaSetStatus{ReadyReplicas: 1},
+	}
+	readyPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod-blue-some-api-abc123-ready", Namespace: "default", Labels: labels},
+		Status: corev1.PodStatus{
+			Phase:      corev1.PodRunning,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}
+	crashingPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod-blue-some-api-abc123-crashing", Namespace: "default", Labels: labels},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			ContainerStatuses: []corev1.ContainerStatus{{
+				Name:         "api",
+				RestartCount: 4,
+				State:        corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			}},
+		},
+	}
+	event := &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "crashing-event", Namespace: "default"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: crashingPod.Name},
+		Type:           "Warning",
+		Reason:         "BackOff",
+		Message:        "Back-off restarting failed container",
+		Count:          4,
+	}
+	otherEvent := &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "other-event", Namespace: "default"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "someone-elses-pod"},
+		Reason:         "Unrelated",
+	}
+	return fake.NewSimpleClientset(dep, replicaSet, readyPod, crashingPod, event, otherEvent)
+}
+
+func Test_CollectFailureReport_Reports_The_Release_Deployments(t *testing.T) {
+	clientset := diagnosticsTestClientset()
+
+	report, err := CollectFailureReport(context.TODO(), clientset, "default", "prod-blue-some-api", DIAGNOSTICS_TEST_MANIFEST, 50)
+
+	assert.Nil(t, err)
+	summary := strings.Join(report.Summary, "\n")
+	assert.Contains(t, summary, "Deployment prod-blue-some-api: 1/2 replicas ready, 2 updated")
+	assert.Contains(t, summary, "ReplicaSet prod-blue-some-api-abc123 (revision 3): 1/2 replicas ready")
+	assert.Contains(t, summary, "Pod prod-blue-some-api-abc123-crashing is not ready")
+	assert.Contains(t, summary, "container api is waiting (CrashLoopBackOff)")
+	assert.NotContains(t, summary, "prod-blue-some-api-abc123-ready")
+	assert.Contains(t, summary, "Recent events (1):")
+	assert.Contains(t, summary, "Back-off restarting failed container")
+	assert.NotContains(t, summary, "Unrelated")
+	assert.Equal(t, []string{"prod-blue-some-api-abc123-crashing_api"}, report.logNames())
+}
+
+func Test_CollectFailureReport_Notes_Missing_Deployments(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+
+	report, err := CollectFailureReport(context.TODO(), clientset, "default", "prod-blue-some-api", DIAGNOSTICS_TEST_MANIFEST, 50)
+
+	assert.Nil(t, err)
+	assert.Contains(t, report.Summary[0], "Failed to get Deployment prod-blue-some-api")
+}
+
+func Test_CollectFailureReport_Returns_Error_When_Manifest_Invalid(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+
+	_, err := CollectFailureReport(context.TODO(), clientset, "default", "prod-blue-some-api", "kind: [", 50)
+
+	assert.NotNil(t, err)
+}
+
+func Test_FailureReport_Write_Writes_Summary_And_Logs(t *testing.T) {
+	dir := t.TempDir()
+	report := &FailureReport{
+		ReleaseName: "prod-blue-some-api",
+		Summary:     []string{"Deployment \033[32mprod-blue-some-api\033[97m: 0/1 replicas ready"},
+		Logs:        map[string]string{"pod-1_api": "panic: oh no\n"},
+	}
+
+	releaseDir, err := report.Write(dir)
+
+	assert.Nil(t, err)
+	assert.Equal(t, filepath.Join(dir, "prod-blue-some-api"), releaseDir)
+	summary, err := ioutil.ReadFile(filepath.Join(releaseDir, "summary.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, "Deployment prod-blue-some-api: 0/1 replicas ready\n", string(summary))
+	logs, err := ioutil.ReadFile(filepath.Join(releaseDir, "pod-1_api.log"))
+	assert.Nil(t, err)
+	assert.Equal(t, "panic: oh no\n", string(logs))
+}
@@ -0,0 +1,1171 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Hutchison-Technologies/helm-deployer/config"
+	"github.com/Hutchison-Technologies/helm-deployer/h3lm"
+	"github.com/Hutchison-Technologies/helm-deployer/k8s"
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+
+	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/helmpath"
+	"helm.sh/helm/v3/pkg/kube"
+	kubefake "helm.sh/helm/v3/pkg/kube/fake"
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	"helm.sh/helm/v3/pkg/repo"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	helmtime "helm.sh/helm/v3/pkg/time"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const (
+	E2E_NAMESPACE       = "apps"
+	E2E_TARGET_ENV      = "prod"
+	E2E_APP_NAME        = "some-api"
+	E2E_BLUEGREEN_CHART = "../testdata/bluegreen-chart"
+	E2E_STANDARD_CHART  = "../testdata/standard-chart"
+	E2E_SCHEMA_CHART    = "../testdata/schema-chart"
+)
+
+// fakeCluster is a fake clientset whose Deployments roll out at once: their
+// status follows their replicas and a ready pod is created for each replica.
+type fakeCluster struct {
+	*fake.Clientset
+}
+
+func newFakeCluster() *fakeCluster {
+	cluster := &fakeCluster{Clientset: fake.NewSimpleClientset()}
+	cluster.PrependReactor("*", "deployments", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		objectAction, ok := action.(interface{ GetObject() k8sruntime.Object })
+		if !ok {
+			return false, nil, nil
+		}
+		if dep, ok := objectAction.GetObject().(*appsv1.Deployment); ok {
+			cluster.rollOut(action.GetNamespace(), dep)
+		}
+		return false, nil, nil
+	})
+	return cluster
+}
+
+func (cluster *fakeCluster) rollOut(namespace string, dep *appsv1.Deployment) {
+	replicas := int32(1)
+	if dep.Spec.Replicas != nil {
+		replicas = *dep.Spec.Replicas
+	}
+	dep.Status.Replicas = replicas
+	dep.Status.ReadyReplicas = replicas
+	dep.Status.UpdatedReplicas = replicas
+
+	pods := corev1.SchemeGroupVersion.WithResource("pods")
+	for i := int32(0); ; i++ {
+		name := fmt.Sprintf("%s-%d", dep.GetName(), i)
+		_, err := cluster.Tracker().Get(pods, namespace, name)
+		exists := err == nil
+		if i >= replicas {
+			if !exists {
+				return
+			}
+			cluster.Tracker().Delete(pods, namespace, name)
+			continue
+		}
+		if !exists {
+			cluster.Tracker().Add(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: dep.Spec.Template.Labels},
+				Status: corev1.PodStatus{
+					Phase:      corev1.PodRunning,
+					Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+				},
+			})
+		}
+	}
+}
+
+// apply creates, or replaces, each resource in a release manifest.
+func (cluster *fakeCluster) apply(manifest string) error {
+	for _, document := range releaseutil.SplitManifests(manifest) {
+		obj, gvk, err := scheme.Codecs.UniversalDeserializer().Decode([]byte(document), nil, nil)
+		if err != nil {
+			return err
+		}
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		accessor.SetNamespace(E2E_NAMESPACE)
+		if dep, ok := obj.(*appsv1.Deployment); ok {
+			cluster.rollOut(E2E_NAMESPACE, dep)
+		}
+
+		resource, _ := meta.UnsafeGuessKindToResource(*gvk)
+		err = cluster.Tracker().Create(resource, obj, E2E_NAMESPACE)
+		if k8serrors.IsAlreadyExists(err) {
+			err = cluster.Tracker().Update(resource, obj, E2E_NAMESPACE)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// helmKubeClient stands in for helm's connection to the cluster, applying the
+// manifest it last built to the fake cluster when helm waits for a release.
+// waitError fails the next wait, after applying, like a release that never
+// becomes ready, and onWait is called during the next wait.
+type helmKubeClient struct {
+	kubefake.PrintingKubeClient
+	cluster   *fakeCluster
+	manifest  string
+	waitError error
+	onWait    func()
+	// reachError fails every check that the cluster is reachable.
+	reachError error
+}
+
+func (client *helmKubeClient) IsReachable() error {
+	return client.reachError
+}
+
+func (client *helmKubeClient) Build(reader io.Reader, validate bool) (kube.ResourceList, error) {
+	manifest, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	client.manifest = string(manifest)
+	return kube.ResourceList{}, nil
+}
+
+func (client *helmKubeClient) Wait(resources kube.ResourceList, timeout time.Duration) error {
+	if err := client.cluster.apply(client.manifest); err != nil {
+		return err
+	}
+	if onWait := client.onWait; onWait != nil {
+		client.onWait = nil
+		onWait()
+	}
+	waitError := client.waitError
+	client.waitError = nil
+	return waitError
+}
+
+func (client *helmKubeClient) WaitWithJobs(resources kube.ResourceList, timeout time.Duration) error {
+	return client.Wait(resources, timeout)
+}
+
+type e2e struct {
+	t          *testing.T
+	cluster    *fakeCluster
+	kubeClient *helmKubeClient
+	helmConfig *action.Configuration
+	deployer   *Deployer
+	phases     []Phase
+	// repositoryCache is the deployer's empty helm repository cache.
+	repositoryCache string
+}
+
+func newE2E(t *testing.T) *e2e {
+	cluster := newFakeCluster()
+	kubeClient := &helmKubeClient{PrintingKubeClient: kubefake.PrintingKubeClient{Out: ioutil.Discard}, cluster: cluster}
+	test := &e2e{
+		t:          t,
+		cluster:    cluster,
+		kubeClient: kubeClient,
+		helmConfig: &action.Configuration{
+			Releases:     storage.Init(driver.NewMemory()),
+			KubeClient:   kubeClient,
+			Capabilities: chartutil.DefaultCapabilities,
+			Log:          func(string, ...interface{}) {},
+		},
+	}
+	repositoryCache, err := ioutil.TempDir("", "repository-cache")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	t.Cleanup(func() { os.RemoveAll(repositoryCache) })
+	test.repositoryCache = repositoryCache
+	test.useEnvironment(nil)
+	return test
+}
+
+// useEnvironment replaces the deployer with one deploying with env.
+func (test *e2e) useEnvironment(env *config.Environment) {
+	test.deployer = New(Options{
+		HelmConfig:  test.helmConfig,
+		KubeClient:  test.cluster,
+		Namespace:   E2E_NAMESPACE,
+		Logger:      log.New(ioutil.Discard, "", 0),
+		Out:         ioutil.Discard,
+		Environment: env,
+		OnPhase:     func(phase Phase) { test.phases = append(test.phases, phase) },
+
+		RepositoryConfig: filepath.Join(test.repositoryCache, "repositories.yaml"),
+		RepositoryCache:  test.repositoryCache,
+	})
+}
+
+func (test *e2e) spec(appVersion string) Spec {
+	return Spec{ChartDir: E2E_BLUEGREEN_CHART, AppName: E2E_APP_NAME, AppVersion: appVersion, TargetEnv: E2E_TARGET_ENV}
+}
+
+// deployBlueGreen deploys appVersion, failing the test unless it succeeds.
+func (test *e2e) deployBlueGreen(appVersion string) *Report {
+	test.phases = nil
+	report, err := test.deployer.DeployBlueGreen(context.TODO(), test.spec(appVersion))
+	if !assert.Nil(test.t, err) {
+		test.t.FailNow()
+	}
+	return report
+}
+
+func (test *e2e) liveColour() string {
+	service, err := test.cluster.CoreV1().Services(E2E_NAMESPACE).Get(context.TODO(), "prod-some-api", metav1.GetOptions{})
+	assert.Nil(test.t, err)
+	return k8s.ServiceSelectorColour(service)
+}
+
+func (test *e2e) deployment(colour string) *appsv1.Deployment {
+	dep, err := test.cluster.AppsV1().Deployments(E2E_NAMESPACE).Get(context.TODO(), "prod-"+colour+"-some-api", metav1.GetOptions{})
+	if !assert.Nil(test.t, err) {
+		test.t.FailNow()
+	}
+	return dep
+}
+
+func (test *e2e) replicas(colour string) int32 {
+	return *test.deployment(colour).Spec.Replicas
+}
+
+func (test *e2e) hasHPA(colour string) bool {
+	_, err := test.cluster.AutoscalingV1().HorizontalPodAutoscalers(E2E_NAMESPACE).Get(context.TODO(), "prod-"+colour+"-some-api-hpa", metav1.GetOptions{})
+	return err == nil
+}
+
+func (test *e2e) version(colour string) string {
+	image := test.deployment(colour).Spec.Template.Spec.Containers[0].Image
+	return image[strings.LastIndex(image, ":")+1:]
+}
+
+func (test *e2e) release(releaseName string) *release.Release {
+	rel, err := action.NewGet(test.helmConfig).Run(releaseName)
+	if !assert.Nil(test.t, err) {
+		test.t.FailNow()
+	}
+	return rel
+}
+
+func (test *e2e) phaseEvents() []string {
+	events := []string{}
+	for _, phase := range test.phases {
+		events = append(events, phase.Event)
+	}
+	return events
+}
+
+func (test *e2e) deployStandardChart() (*Report, error) {
+	test.phases = nil
+	return test.deployer.DeployStandardChart(context.TODO(), Spec{ChartDir: E2E_STANDARD_CHART, AppName: E2E_APP_NAME, TargetEnv: E2E_TARGET_ENV})
+}
+
+// strand records a new revision of releaseName left in status, age ago, by a
+// deploy that never finished.
+func (test *e2e) strand(releaseName string, status release.Status, age time.Duration) *release.Release {
+	chart, err := loader.Load(E2E_STANDARD_CHART)
+	if !assert.Nil(test.t, err) {
+		test.t.FailNow()
+	}
+	stranded := &release.Release{
+		Name:      releaseName,
+		Namespace: E2E_NAMESPACE,
+		Version:   1,
+		Chart:     chart,
+		Info:      &release.Info{Status: status, LastDeployed: helmtime.Now().Add(-age)},
+	}
+	if last, err := test.helmConfig.Releases.Last(releaseName); err == nil {
+		stranded.Version = last.Version + 1
+		stranded.Manifest = last.Manifest
+	}
+	assert.Nil(test.t, test.helmConfig.Releases.Create(stranded))
+	return stranded
+}
+
+func releaseNames(releases []*ReleaseReport) []string {
+	names := []string{}
+	for _, releaseReport := range releases {
+		names = append(names, releaseReport.Name)
+	}
+	return names
+}
+
+func Test_E2E_BlueGreen_First_Deploy_Goes_Live_On_Blue(t *testing.T) {
+	test := newE2E(t)
+
+	report := test.deployBlueGreen("v1.0.0")
+
+	assert.Equal(t, "", report.LiveColourBefore)
+	assert.Equal(t, "blue", report.LiveColourAfter)
+	assert.Equal(t, []string{"prod-blue-some-api", "prod-service-some-api"}, releaseNames(report.Releases))
+	assert.True(t, report.Releases[0].Changed)
+	assert.Equal(t, []string{PHASE_COLOUR_DETERMINED, PHASE_RELEASE_DEPLOYED, PHASE_SERVICE_SWITCHED, PHASE_OFFLINE_SCALED}, test.phaseEvents())
+	assert.Equal(t, "blue", test.liveColour())
+	// The live colour is left on one replica for its HPA to scale from.
+	assert.Equal(t, int32(1), test.replicas("blue"))
+	assert.True(t, test.hasHPA("blue"))
+	assert.Equal(t, "v1.0.0", test.version("blue"))
+	assert.Equal(t, release.StatusDeployed, test.release("prod-blue-some-api").Info.Status)
+}
+
+func Test_E2E_BlueGreen_Alternates_Colours(t *testing.T) {
+	test := newE2E(t)
+	test.deployBlueGreen("v1.0.0")
+
+	report := test.deployBlueGreen("v1.1.0")
+	assert.Equal(t, "blue", report.LiveColourBefore)
+	assert.Equal(t, "green", report.LiveColourAfter)
+	assert.Equal(t, "green", test.liveColour())
+	assert.Equal(t, "v1.1.0", test.version("green"))
+	assert.Equal(t, int32(1), test.replicas("green"))
+	assert.Equal(t, int32(0), test.replicas("blue"))
+	assert.False(t, test.hasHPA("blue"))
+
+	report = test.deployBlueGreen("v1.2.0")
+	assert.Equal(t, "blue", report.LiveColourAfter)
+	assert.Equal(t, "blue", test.liveColour())
+	assert.Equal(t, "v1.2.0", test.version("blue"))
+	assert.Equal(t, int32(1), test.replicas("blue"))
+	assert.True(t, test.hasHPA("blue"))
+	assert.Equal(t, int32(0), test.replicas("green"))
+	assert.Equal(t, 2, test.release("prod-blue-some-api").Version)
+	assert.Equal(t, 3, test.release("prod-service-some-api").Version)
+}
+
+func Test_E2E_BlueGreen_Keeps_The_Offline_Colour_Warm(t *testing.T) {
+	test := newE2E(t)
+	test.deployBlueGreen("v1.0.0")
+
+	spec := test.spec("v1.1.0")
+	spec.KeepWarm = 1
+	_, err := test.deployer.DeployBlueGreen(context.TODO(), spec)
+
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), test.replicas("blue"))
+}
+
+func Test_E2E_BlueGreen_Rolls_Back_A_Failed_Upgrade(t *testing.T) {
+	test := newE2E(t)
+	test.deployBlueGreen("v1.0.0")
+	test.deployBlueGreen("v1.1.0")
+
+	test.kubeClient.waitError = errors.New("timed out waiting for the condition")
+	report, err := test.deployer.DeployBlueGreen(context.TODO(), test.spec("v1.2.0"))
+
+	assert.Equal(t, runtime.EXIT_CODE_DEPLOY_FAILED, runtime.ExitCode(err))
+	assert.True(t, report.RolledBack)
+	assert.Equal(t, "prod-blue-some-api", report.Rollbacks[0].Name)
+	assert.Equal(t, 1, report.Rollbacks[0].Revision)
+	assert.Equal(t, "failed", report.Releases[0].Status)
+	assert.Equal(t, "green", report.LiveColourAfter)
+	assert.Equal(t, "green", test.liveColour())
+	assert.Equal(t, "v1.0.0", test.version("blue"))
+	assert.Equal(t, 3, test.release("prod-blue-some-api").Version)
+	assert.Equal(t, release.StatusDeployed, test.release("prod-blue-some-api").Info.Status)
+	assert.Equal(t, 2, test.release("prod-service-some-api").Version)
+}
+
+// failingProxyResponse answers every request proxied to a service with err.
+type failingProxyResponse struct {
+	err error
+}
+
+func (response failingProxyResponse) DoRaw(context.Context) ([]byte, error) {
+	return nil, response.err
+}
+
+func (response failingProxyResponse) Stream(context.Context) (io.ReadCloser, error) {
+	return nil, response.err
+}
+
+// assertHealthGateFailed asserts that err failed the health gate of the green
+// colour, which was scaled back down without touching the service release.
+func (test *e2e) assertHealthGateFailed(err error) {
+	assert.Equal(test.t, runtime.EXIT_CODE_DEPLOY_FAILED, runtime.ExitCode(err))
+	assert.True(test.t, errors.Is(err, ErrHealthGateFailed))
+	assert.Equal(test.t, "blue", test.liveColour())
+	assert.Equal(test.t, 1, test.release("prod-service-some-api").Version)
+	assert.Equal(test.t, int32(0), test.replicas("green"))
+	assert.NotContains(test.t, test.phaseEvents(), PHASE_SERVICE_SWITCHED)
+}
+
+func Test_E2E_BlueGreen_Stays_On_The_Live_Colour_When_The_Smoke_Test_Fails(t *testing.T) {
+	test := newE2E(t)
+	test.deployBlueGreen("v1.0.0")
+	test.cluster.PrependProxyReactor("services", func(action k8stesting.Action) (bool, rest.ResponseWrapper, error) {
+		return true, failingProxyResponse{errors.New("the server is currently unable to handle the request")}, nil
+	})
+	test.phases = nil
+	spec := test.spec("v1.1.0")
+	spec.SmokeTest = true
+
+	report, err := test.deployer.DeployBlueGreen(context.TODO(), spec)
+
+	test.assertHealthGateFailed(err)
+	assert.Contains(t, err.Error(), "Smoke test of / through prod-some-api-offline failed")
+	assert.Equal(t, "blue", report.LiveColourAfter)
+}
+
+func Test_E2E_BlueGreen_Stays_On_The_Live_Colour_When_The_New_Colour_Restarts_Too_Often(t *testing.T) {
+	test := newE2E(t)
+	test.deployBlueGreen("v1.0.0")
+	test.kubeClient.onWait = func() {
+		pods := corev1.SchemeGroupVersion.WithResource("pods")
+		obj, err := test.cluster.Tracker().Get(pods, E2E_NAMESPACE, "prod-green-some-api-0")
+		if !assert.Nil(t, err) {
+			return
+		}
+		pod := obj.(*corev1.Pod)
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "some-api", RestartCount: 3}}
+		assert.Nil(t, test.cluster.Tracker().Update(pods, pod, E2E_NAMESPACE))
+	}
+	test.phases = nil
+	spec := test.spec("v1.1.0")
+	spec.MaxRestarts = 2
+
+	report, err := test.deployer.DeployBlueGreen(context.TODO(), spec)
+
+	test.assertHealthGateFailed(err)
+	assert.Contains(t, err.Error(), "pod prod-green-some-api-0 has restarted 3 time(s), more than the allowed 2")
+	assert.Equal(t, "blue", report.LiveColourAfter)
+}
+
+func Test_E2E_BlueGreen_Skips_The_Colour_Flip_When_Nothing_Changed(t *testing.T) {
+	test := newE2E(t)
+	test.deployBlueGreen("v1.0.0")
+	test.deployBlueGreen("v1.1.0")
+	_, err := test.deployer.Swap(context.TODO(), test.spec(""))
+	assert.Nil(t, err)
+
+	report := test.deployBlueGreen("v1.1.0")
+
+	assert.True(t, report.Unchanged())
+	assert.Equal(t, []string{"prod-green-some-api"}, releaseNames(report.Releases))
+	assert.Equal(t, []string{PHASE_COLOUR_DETERMINED, PHASE_RELEASE_UNCHANGED}, test.phaseEvents())
+	assert.Equal(t, "blue", test.liveColour())
+	assert.Equal(t, 1, test.release("prod-green-some-api").Version)
+}
+
+// useTimeout makes helm give up on installs and upgrades after timeout.
+func (test *e2e) useTimeout(timeout string) {
+	test.useEnvironment(&config.Environment{
+		Namespace:       E2E_NAMESPACE,
+		InstallTimeout:  timeout,
+		UpgradeTimeout:  timeout,
+		RollbackTimeout: config.DEFAULT_ROLLBACK_TIMEOUT,
+		PendingTimeout:  config.DEFAULT_PENDING_TIMEOUT,
+
+		MinBlueGreenMicroserviceVersion: config.DEFAULT_MIN_BLUE_GREEN_MICROSERVICE_VERSION,
+		MinMicroserviceVersion:          config.DEFAULT_MIN_MICROSERVICE_VERSION,
+	})
+}
+
+// cancelDuringWait cancels the returned context while helm waits for the next
+// release, which then never finishes, like a deploy aborted mid-upgrade.
+func (test *e2e) cancelDuringWait() context.Context {
+	ctx, cancel := context.WithCancel(context.TODO())
+	test.kubeClient.onWait = func() {
+		cancel()
+		select {}
+	}
+	return ctx
+}
+
+// cancelDuringWaitThenReturn cancels the deploy while helm waits for a
+// release, after which helm's wait returns waitError once delay has passed.
+func (test *e2e) cancelDuringWaitThenReturn(delay time.Duration, waitError error) context.Context {
+	ctx, cancel := context.WithCancel(context.TODO())
+	test.kubeClient.onWait = func() {
+		cancel()
+		time.Sleep(delay)
+	}
+	test.kubeClient.waitError = waitError
+	return ctx
+}
+
+func Test_E2E_BlueGreen_Rolls_Back_An_Upgrade_Completed_After_Cancelling(t *testing.T) {
+	test := newE2E(t)
+	test.deployBlueGreen("v1.0.0")
+	test.deployBlueGreen("v1.1.0")
+
+	report, err := test.deployer.DeployBlueGreen(test.cancelDuringWaitThenReturn(20*time.Millisecond, nil), test.spec("v1.2.0"))
+
+	assert.Equal(t, runtime.EXIT_CODE_DEPLOY_FAILED, runtime.ExitCode(err))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.True(t, report.RolledBack)
+	assert.Equal(t, 1, report.Rollbacks[0].Revision)
+	assert.Equal(t, "green", test.liveColour())
+	assert.Equal(t, 3, test.release("prod-blue-some-api").Version)
+	assert.Equal(t, release.StatusDeployed, test.release("prod-blue-some-api").Info.Status)
+	assert.Equal(t, "v1.0.0", test.version("blue"))
+	cancelled, err := test.helmConfig.Releases.Get("prod-blue-some-api", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, release.StatusFailed, cancelled.Info.Status)
+}
+
+func Test_E2E_BlueGreen_Rolls_Back_An_Upgrade_Failed_After_Cancelling(t *testing.T) {
+	test := newE2E(t)
+	test.deployBlueGreen("v1.0.0")
+	test.deployBlueGreen("v1.1.0")
+
+	report, err := test.deployer.DeployBlueGreen(test.cancelDuringWaitThenReturn(20*time.Millisecond, errors.New("timed out waiting for the condition")), test.spec("v1.2.0"))
+
+	assert.Equal(t, runtime.EXIT_CODE_DEPLOY_FAILED, runtime.ExitCode(err))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.True(t, report.RolledBack)
+	assert.Equal(t, 1, report.Rollbacks[0].Revision)
+	assert.Equal(t, 3, test.release("prod-blue-some-api").Version)
+	assert.Equal(t, release.StatusDeployed, test.release("prod-blue-some-api").Info.Status)
+}
+
+func Test_E2E_BlueGreen_Waits_For_An_Upgrade_Finishing_Long_After_Cancelling(t *testing.T) {
+	test := newE2E(t)
+	test.deployBlueGreen("v1.0.0")
+	test.deployBlueGreen("v1.1.0")
+
+	report, err := test.deployer.DeployBlueGreen(test.cancelDuringWaitThenReturn(500*time.Millisecond, nil), test.spec("v1.2.0"))
+
+	assert.Equal(t, runtime.EXIT_CODE_DEPLOY_FAILED, runtime.ExitCode(err))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.True(t, report.RolledBack)
+	assert.Equal(t, 1, report.Rollbacks[0].Revision)
+	assert.Equal(t, "green", test.liveColour())
+	assert.Equal(t, "v1.0.0", test.version("blue"))
+	history, err := test.helmConfig.Releases.History("prod-blue-some-api")
+	assert.Nil(t, err)
+	deployed := h3lm.FilterReleasesByStatusCode(history, release.StatusDeployed)
+	if assert.Len(t, deployed, 1) {
+		assert.Equal(t, 3, deployed[0].Version)
+	}
+	cancelled, err := test.helmConfig.Releases.Get("prod-blue-some-api", 2)
+	assert.Nil(t, err)
+	assert.Equal(t, release.StatusFailed, cancelled.Info.Status)
+}
+
+func Test_E2E_BlueGreen_Marks_An_Upgrade_Still_Running_After_Its_Timeout_Failed(t *testing.T) {
+	test := newE2E(t)
+	test.useTimeout("300ms")
+	test.deployBlueGreen("v1.0.0")
+	test.deployBlueGreen("v1.1.0")
+	ctx := test.cancelDuringWait()
+
+	report, err := test.deployer.DeployBlueGreen(ctx, test.spec("v1.2.0"))
+
+	assert.Equal(t, runtime.EXIT_CODE_DEPLOY_FAILED, runtime.ExitCode(err))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.False(t, report.RolledBack)
+	assert.Equal(t, "failed", report.Releases[0].Status)
+	assert.Equal(t, "green", test.liveColour())
+	assert.Equal(t, 2, test.release("prod-blue-some-api").Version)
+	assert.Equal(t, release.StatusFailed, test.release("prod-blue-some-api").Info.Status)
+
+	test.deployBlueGreen("v1.3.0")
+	assert.Equal(t, "blue", test.liveColour())
+	assert.Equal(t, "v1.3.0", test.version("blue"))
+}
+
+func Test_E2E_StandardChart_Marks_A_Cancelled_Install_Failed(t *testing.T) {
+	test := newE2E(t)
+	test.useTimeout("300ms")
+	spec := Spec{ChartDir: E2E_STANDARD_CHART, AppName: E2E_APP_NAME, TargetEnv: E2E_TARGET_ENV}
+	ctx := test.cancelDuringWait()
+
+	report, err := test.deployer.DeployStandardChart(ctx, spec)
+
+	assert.Equal(t, runtime.EXIT_CODE_DEPLOY_FAILED, runtime.ExitCode(err))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.False(t, report.RolledBack)
+	assert.Equal(t, 1, test.release("prod-some-api").Version)
+	assert.Equal(t, release.StatusFailed, test.release("prod-some-api").Info.Status)
+}
+
+func Test_E2E_StandardChart_Deploys_Nothing_Once_Cancelled(t *testing.T) {
+	test := newE2E(t)
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+
+	_, err := test.deployer.DeployStandardChart(ctx, Spec{ChartDir: E2E_STANDARD_CHART, AppName: E2E_APP_NAME, TargetEnv: E2E_TARGET_ENV})
+
+	assert.Equal(t, runtime.EXIT_CODE_DEPLOY_FAILED, runtime.ExitCode(err))
+	assert.True(t, errors.Is(err, context.Canceled))
+	_, err = action.NewGet(test.helmConfig).Run("prod-some-api")
+	assert.NotNil(t, err)
+}
+
+func Test_E2E_StandardChart_Recovers_A_Stuck_Upgrade(t *testing.T) {
+	test := newE2E(t)
+	_, err := test.deployStandardChart()
+	assert.Nil(t, err)
+	test.strand("prod-some-api", release.StatusPendingUpgrade, time.Hour)
+
+	report, err := test.deployStandardChart()
+
+	assert.Nil(t, err)
+	assert.Equal(t, []*RecoveryReport{{Name: "prod-some-api", Revision: 2, Status: "pending-upgrade", Action: RECOVERY_ROLLED_BACK, RolledBackTo: 1}}, report.Recoveries)
+	assert.False(t, report.RolledBack)
+	assert.Equal(t, PHASE_RELEASE_RECOVERED, test.phases[0].Event)
+	assert.Equal(t, 3, test.release("prod-some-api").Version)
+	assert.Equal(t, release.StatusDeployed, test.release("prod-some-api").Info.Status)
+}
+
+func Test_E2E_StandardChart_Recovers_A_Stuck_Install(t *testing.T) {
+	test := newE2E(t)
+	test.strand("prod-some-api", release.StatusPendingInstall, time.Hour)
+
+	report, err := test.deployStandardChart()
+
+	assert.Nil(t, err)
+	assert.Equal(t, []*RecoveryReport{{Name: "prod-some-api", Revision: 1, Status: "pending-install", Action: RECOVERY_UNINSTALLED}}, report.Recoveries)
+	assert.Equal(t, 1, test.release("prod-some-api").Version)
+	assert.Equal(t, release.StatusDeployed, test.release("prod-some-api").Info.Status)
+	_, err = test.cluster.CoreV1().ConfigMaps(E2E_NAMESPACE).Get(context.TODO(), "prod-some-api-config", metav1.GetOptions{})
+	assert.Nil(t, err)
+}
+
+func Test_E2E_StandardChart_Leaves_A_Recently_Pending_Release_Alone(t *testing.T) {
+	test := newE2E(t)
+	_, err := test.deployStandardChart()
+	assert.Nil(t, err)
+	test.strand("prod-some-api", release.StatusPendingUpgrade, time.Minute)
+
+	report, err := test.deployStandardChart()
+
+	assert.Equal(t, runtime.EXIT_CODE_DEPLOY_FAILED, runtime.ExitCode(err))
+	assert.True(t, errors.Is(err, errReleasePending))
+	assert.Empty(t, report.Recoveries)
+	assert.False(t, report.RolledBack)
+	assert.Equal(t, release.StatusPendingUpgrade, test.release("prod-some-api").Info.Status)
+}
+
+func Test_E2E_StandardChart_Does_Not_Recover_When_Opted_Out(t *testing.T) {
+	test := newE2E(t)
+	recoverPending := false
+	test.useEnvironment(&config.Environment{
+		Namespace:       E2E_NAMESPACE,
+		InstallTimeout:  config.DEFAULT_TIMEOUT,
+		UpgradeTimeout:  config.DEFAULT_TIMEOUT,
+		RollbackTimeout: config.DEFAULT_ROLLBACK_TIMEOUT,
+		RecoverPending:  &recoverPending,
+		PendingTimeout:  config.DEFAULT_PENDING_TIMEOUT,
+
+		MinBlueGreenMicroserviceVersion: config.DEFAULT_MIN_BLUE_GREEN_MICROSERVICE_VERSION,
+		MinMicroserviceVersion:          config.DEFAULT_MIN_MICROSERVICE_VERSION,
+	})
+	_, err := test.deployStandardChart()
+	assert.Nil(t, err)
+	test.strand("prod-some-api", release.StatusPendingUpgrade, time.Hour)
+
+	report, err := test.deployStandardChart()
+
+	assert.Equal(t, runtime.EXIT_CODE_DEPLOY_FAILED, runtime.ExitCode(err))
+	assert.True(t, errors.Is(err, errReleasePending))
+	assert.Empty(t, report.Recoveries)
+	assert.False(t, report.RolledBack)
+	assert.Equal(t, release.StatusPendingUpgrade, test.release("prod-some-api").Info.Status)
+}
+
+func Test_E2E_Plan_Refuses_A_Stuck_Release(t *testing.T) {
+	test := newE2E(t)
+	_, err := test.deployStandardChart()
+	assert.Nil(t, err)
+	test.strand("prod-some-api", release.StatusPendingUpgrade, time.Hour)
+
+	_, err = test.deployer.Plan(context.TODO(), DeployType.STANDARD_CHART, Spec{ChartDir: E2E_STANDARD_CHART, AppName: E2E_APP_NAME, TargetEnv: E2E_TARGET_ENV})
+
+	assert.Equal(t, runtime.EXIT_CODE_VALIDATION, runtime.ExitCode(err))
+	assert.Equal(t, release.StatusPendingUpgrade, test.release("prod-some-api").Info.Status)
+}
+
+func Test_E2E_Plan_Reports_A_Pending_Release_As_A_Cluster_Error(t *testing.T) {
+	test := newE2E(t)
+	_, err := test.deployStandardChart()
+	assert.Nil(t, err)
+	test.strand("prod-some-api", release.StatusPendingUpgrade, time.Minute)
+
+	_, err = test.deployer.Plan(context.TODO(), DeployType.STANDARD_CHART, Spec{ChartDir: E2E_STANDARD_CHART, AppName: E2E_APP_NAME, TargetEnv: E2E_TARGET_ENV})
+
+	assert.Equal(t, runtime.EXIT_CODE_CLUSTER, runtime.ExitCode(err))
+	assert.True(t, errors.Is(err, errReleasePending))
+	assert.Equal(t, release.StatusPendingUpgrade, test.release("prod-some-api").Info.Status)
+}
+
+func Test_E2E_StandardChart_Reports_No_Diff(t *testing.T) {
+	test := newE2E(t)
+	spec := Spec{ChartDir: E2E_STANDARD_CHART, AppName: E2E_APP_NAME, TargetEnv: E2E_TARGET_ENV}
+	report, err := test.deployer.DeployStandardChart(context.TODO(), spec)
+	assert.Nil(t, err)
+	assert.False(t, report.Unchanged())
+
+	report, err = test.deployer.DeployStandardChart(context.TODO(), spec)
+	assert.Nil(t, err)
+	assert.True(t, report.Unchanged())
+	assert.Equal(t, 1, report.Releases[0].Revision)
+	assert.False(t, report.Releases[0].Diff.HasChanges())
+
+	spec.FailOnNoChange = true
+	_, err = test.deployer.DeployStandardChart(context.TODO(), spec)
+	assert.Equal(t, runtime.EXIT_CODE_NO_CHANGES, runtime.ExitCode(err))
+	assert.Equal(t, 1, test.release("prod-some-api").Version)
+}
+
+func Test_E2E_BlueGreen_Deploys_Blue_When_The_Offline_Service_Is_Missing(t *testing.T) {
+	test := newE2E(t)
+	test.deployBlueGreen("v1.0.0")
+	test.deployBlueGreen("v1.1.0")
+	assert.Nil(t, test.cluster.CoreV1().Services(E2E_NAMESPACE).Delete(context.TODO(), "prod-some-api-offline", metav1.DeleteOptions{}))
+
+	report := test.deployBlueGreen("v1.2.0")
+
+	assert.Equal(t, "prod-blue-some-api", report.Releases[0].Name)
+	assert.Equal(t, "blue", test.liveColour())
+	_, err := test.cluster.CoreV1().Services(E2E_NAMESPACE).Get(context.TODO(), "prod-some-api-offline", metav1.GetOptions{})
+	assert.Nil(t, err)
+}
+
+func Test_E2E_Swap_Refuses_When_The_Offline_Service_Is_Missing(t *testing.T) {
+	test := newE2E(t)
+	test.deployBlueGreen("v1.0.0")
+	assert.Nil(t, test.cluster.CoreV1().Services(E2E_NAMESPACE).Delete(context.TODO(), "prod-some-api-offline", metav1.DeleteOptions{}))
+
+	_, err := test.deployer.Swap(context.TODO(), test.spec(""))
+
+	assert.Equal(t, runtime.EXIT_CODE_VALIDATION, runtime.ExitCode(err))
+	assert.Equal(t, "blue", test.liveColour())
+}
+
+func Test_E2E_Swap_Brings_The_Offline_Colour_Back(t *testing.T) {
+	test := newE2E(t)
+	test.deployBlueGreen("v1.0.0")
+	test.deployBlueGreen("v1.1.0")
+
+	report, err := test.deployer.Swap(context.TODO(), test.spec(""))
+
+	assert.Nil(t, err)
+	assert.Equal(t, "green", report.LiveColourBefore)
+	assert.Equal(t, "blue", report.LiveColourAfter)
+	assert.Equal(t, "blue", test.liveColour())
+	assert.Equal(t, "v1.0.0", test.version("blue"))
+	assert.Equal(t, int32(0), test.replicas("green"))
+	assert.True(t, test.hasHPA("blue"))
+}
+
+func Test_E2E_Swap_Restores_The_Metrics_And_Behavior_Of_An_autoscaling_v2beta2_HPA(t *testing.T) {
+	test := newE2E(t)
+	test.deployBlueGreen("v1.0.0")
+	hpas := test.cluster.AutoscalingV2beta2().HorizontalPodAutoscalers(E2E_NAMESPACE)
+	var stabilizationWindowSeconds int32 = 600
+	assert.Nil(t, test.cluster.AutoscalingV1().HorizontalPodAutoscalers(E2E_NAMESPACE).Delete(context.TODO(), "prod-blue-some-api-hpa", metav1.DeleteOptions{}))
+	_, err := hpas.Create(context.TODO(), &autoscalingv2beta2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod-blue-some-api-hpa"},
+		Spec: autoscalingv2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "prod-blue-some-api"},
+			MaxReplicas:    25,
+			Behavior: &autoscalingv2beta2.HorizontalPodAutoscalerBehavior{
+				ScaleDown: &autoscalingv2beta2.HPAScalingRules{StabilizationWindowSeconds: &stabilizationWindowSeconds},
+			},
+		},
+	}, metav1.CreateOptions{})
+	assert.Nil(t, err)
+	test.deployBlueGreen("v1.1.0")
+	_, err = hpas.Get(context.TODO(), "prod-blue-some-api-hpa", metav1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err))
+
+	_, err = test.deployer.Swap(context.TODO(), test.spec(""))
+
+	assert.Nil(t, err)
+	restored, err := hpas.Get(context.TODO(), "prod-blue-some-api-hpa", metav1.GetOptions{})
+	if assert.Nil(t, err) {
+		assert.Equal(t, int32(600), *restored.Spec.Behavior.ScaleDown.StabilizationWindowSeconds)
+	}
+	_, err = test.cluster.CoreV1().ConfigMaps(E2E_NAMESPACE).Get(context.TODO(), "prod-blue-some-api-hpa-snapshot", metav1.GetOptions{})
+	assert.True(t, k8serrors.IsNotFound(err))
+}
+
+func Test_E2E_Rollback_Switches_Back_To_The_Previous_Colour(t *testing.T) {
+	test := newE2E(t)
+	test.deployBlueGreen("v1.0.0")
+	test.deployBlueGreen("v1.1.0")
+
+	report, err := test.deployer.Rollback(context.TODO(), test.spec(""))
+
+	assert.Nil(t, err)
+	assert.True(t, report.RolledBack)
+	assert.Equal(t, "prod-service-some-api", report.Rollbacks[0].Name)
+	assert.Equal(t, "green", report.LiveColourBefore)
+	assert.Equal(t, "blue", report.LiveColourAfter)
+	assert.Equal(t, "blue", test.liveColour())
+	assert.Equal(t, 3, test.release("prod-service-some-api").Version)
+	assert.True(t, test.hasHPA("blue"))
+	assert.Equal(t, int32(0), test.replicas("green"))
+	assert.False(t, test.hasHPA("green"))
+}
+
+func (test *e2e) canarySpec(appVersion string) Spec {
+	spec := test.spec(appVersion)
+	spec.CanarySteps = []int{50, 100}
+	return spec
+}
+
+// onServiceOpened calls onOpened with the canary deployment as the live
+// service's selector is opened up to both colours.
+func (test *e2e) onServiceOpened(onOpened func(canary *appsv1.Deployment)) {
+	test.cluster.PrependReactor("patch", "services", func(action k8stesting.Action) (bool, k8sruntime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		if patch.GetName() == "prod-some-api" && strings.Contains(string(patch.GetPatch()), `"colour":null`) {
+			canary, err := test.cluster.Tracker().Get(appsv1.SchemeGroupVersion.WithResource("deployments"), E2E_NAMESPACE, "prod-green-some-api")
+			if !assert.Nil(test.t, err) {
+				return false, nil, nil
+			}
+			onOpened(canary.(*appsv1.Deployment))
+		}
+		return false, nil, nil
+	})
+}
+
+func Test_E2E_Canary_Shifts_Traffic_To_The_Offline_Colour(t *testing.T) {
+	test := newE2E(t)
+	test.deployBlueGreen("v1.0.0")
+	stableReplicas := test.replicas("blue")
+	var canaryWhenOpened *appsv1.Deployment
+	test.onServiceOpened(func(canary *appsv1.Deployment) { canaryWhenOpened = canary })
+	test.phases = nil
+
+	report, err := test.deployer.DeployCanary(context.TODO(), test.canarySpec("v1.1.0"))
+
+	assert.Nil(t, err)
+	if assert.NotNil(t, canaryWhenOpened) {
+		assert.Equal(t, int32(1), *canaryWhenOpened.Spec.Replicas)
+		assert.Equal(t, int32(1), canaryWhenOpened.Status.ReadyReplicas)
+	}
+	assert.Equal(t, "blue", report.LiveColourBefore)
+	assert.Equal(t, "green", report.LiveColourAfter)
+	assert.Equal(t, "green", test.liveColour())
+	assert.Equal(t, "v1.1.0", test.version("green"))
+	assert.Equal(t, stableReplicas, test.replicas("green"))
+	assert.True(t, test.hasHPA("green"))
+	assert.Equal(t, int32(0), test.replicas("blue"))
+	assert.False(t, test.hasHPA("blue"))
+	assert.Equal(t, []string{PHASE_COLOUR_DETERMINED, PHASE_RELEASE_DEPLOYED, PHASE_CANARY_STEP, PHASE_CANARY_STEP, PHASE_SERVICE_SWITCHED, PHASE_OFFLINE_SCALED}, test.phaseEvents())
+}
+
+func Test_E2E_Canary_Reverts_An_Unhealthy_Canary(t *testing.T) {
+	test := newE2E(t)
+	test.deployBlueGreen("v1.0.0")
+	stableReplicas := test.replicas("blue")
+	test.onServiceOpened(func(canary *appsv1.Deployment) {
+		pods := corev1.SchemeGroupVersion.WithResource("pods")
+		pod, err := test.cluster.Tracker().Get(pods, E2E_NAMESPACE, canary.GetName()+"-0")
+		if !assert.Nil(t, err) {
+			return
+		}
+		crashing := pod.(*corev1.Pod).DeepCopy()
+		crashing.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionFalse}}
+		assert.Nil(t, test.cluster.Tracker().Update(pods, crashing, E2E_NAMESPACE))
+	})
+
+	report, err := test.deployer.DeployCanary(context.TODO(), test.canarySpec("v1.1.0"))
+
+	assert.Equal(t, 6, runtime.ExitCode(err))
+	assert.Equal(t, "blue", report.LiveColourAfter)
+	assert.Equal(t, "blue", test.liveColour())
+	assert.Equal(t, stableReplicas, test.replicas("blue"))
+	assert.True(t, test.hasHPA("blue"))
+	assert.Equal(t, int32(0), test.replicas("green"))
+	assert.False(t, test.hasHPA("green"))
+}
+
+func Test_E2E_Plan_Reports_Changes_Without_Deploying(t *testing.T) {
+	test := newE2E(t)
+	test.deployBlueGreen("v1.0.0")
+
+	report, err := test.deployer.Plan(context.TODO(), DeployType.BLUEGREEN, test.spec("v1.1.0"))
+
+	assert.Equal(t, ErrPlanHasChanges, err)
+	assert.Equal(t, []string{"prod-green-some-api", "prod-service-some-api"}, releaseNames(report.Releases))
+	assert.True(t, report.Releases[0].Diff.HasChanges())
+	assert.Equal(t, "blue", test.liveColour())
+	_, err = action.NewGet(test.helmConfig).Run("prod-green-some-api")
+	assert.NotNil(t, err)
+}
+
+func Test_E2E_Plan_Reports_An_Unreachable_Cluster_As_A_Cluster_Error(t *testing.T) {
+	test := newE2E(t)
+	test.kubeClient.reachError = errors.New("Kubernetes cluster unreachable: dial tcp 10.0.0.1:443: i/o timeout")
+
+	_, err := test.deployer.Plan(context.TODO(), DeployType.STANDARD_CHART, Spec{ChartDir: E2E_STANDARD_CHART, AppName: E2E_APP_NAME, TargetEnv: E2E_TARGET_ENV})
+
+	assert.Equal(t, runtime.EXIT_CODE_CLUSTER, runtime.ExitCode(err))
+}
+
+func Test_E2E_StandardChart_Deploys_Overridden_Values(t *testing.T) {
+	test := newE2E(t)
+	spec := Spec{ChartDir: E2E_STANDARD_CHART, AppName: E2E_APP_NAME, TargetEnv: E2E_TARGET_ENV, Set: []string{"greeting=hi"}}
+	_, err := test.deployer.DeployStandardChart(context.TODO(), spec)
+	assert.Nil(t, err)
+	assert.Contains(t, test.release("prod-some-api").Manifest, `greeting: "hi"`)
+
+	spec.Set = nil
+	spec.SetString = []string{"greeting=0123"}
+	_, err = test.deployer.DeployStandardChart(context.TODO(), spec)
+	assert.Nil(t, err)
+	assert.Contains(t, test.release("prod-some-api").Manifest, `greeting: "0123"`)
+}
+
+func Test_E2E_StandardChart_Refuses_Values_That_Break_The_Schemas(t *testing.T) {
+	test := newE2E(t)
+	spec := Spec{ChartDir: E2E_SCHEMA_CHART, AppName: E2E_APP_NAME, TargetEnv: E2E_TARGET_ENV, ValuesFiles: []string{E2E_SCHEMA_CHART + "/invalid.yaml"}}
+	_, err := test.deployer.DeployStandardChart(context.TODO(), spec)
+
+	assert.Equal(t, runtime.EXIT_CODE_VALIDATION, runtime.ExitCode(err))
+	assert.Contains(t, err.Error(), "replicas: Invalid type. Expected: integer, given: string")
+	assert.Contains(t, err.Error(), "image.tag: Invalid type. Expected: string, given: integer")
+	assert.Contains(t, err.Error(), "jobs.schedule: Invalid type. Expected: string, given: integer")
+	_, err = test.helmConfig.Releases.Last("prod-some-api")
+	assert.NotNil(t, err)
+
+	spec.ValuesFiles = nil
+	_, err = test.deployer.DeployStandardChart(context.TODO(), spec)
+	assert.Nil(t, err)
+}
+
+func Test_E2E_BlueGreen_Refuses_Values_Missing_A_Required_Key(t *testing.T) {
+	test := newE2E(t)
+	test.useEnvironment(&config.Environment{
+		Namespace:       E2E_NAMESPACE,
+		InstallTimeout:  config.DEFAULT_TIMEOUT,
+		UpgradeTimeout:  config.DEFAULT_TIMEOUT,
+		RollbackTimeout: config.DEFAULT_ROLLBACK_TIMEOUT,
+		PendingTimeout:  config.DEFAULT_PENDING_TIMEOUT,
+		RequiredValues:  []string{"bluegreen.deployment.image", "bluegreen.ingress.host"},
+
+		MinBlueGreenMicroserviceVersion: config.DEFAULT_MIN_BLUE_GREEN_MICROSERVICE_VERSION,
+		MinMicroserviceVersion:          config.DEFAULT_MIN_MICROSERVICE_VERSION,
+	})
+	_, err := test.deployer.DeployBlueGreen(context.TODO(), test.spec("1.0.0"))
+
+	assert.Equal(t, runtime.EXIT_CODE_VALIDATION, runtime.ExitCode(err))
+	assert.Contains(t, err.Error(), "bluegreen.ingress.host: is required but not set")
+	assert.NotContains(t, err.Error(), "bluegreen.deployment.image")
+	assert.NotContains(t, test.phaseEvents(), PHASE_RELEASE_DEPLOYED)
+	_, err = action.NewGet(test.helmConfig).Run("prod-blue-some-api")
+	assert.NotNil(t, err)
+}
+
+func Test_E2E_BlueGreen_Validates_The_Values_Of_Each_Release_After_Editing_Them(t *testing.T) {
+	test := newE2E(t)
+	chartDir := test.copyChart(E2E_BLUEGREEN_CHART)
+	schema := `{"properties": {"bluegreen": {"properties": {"deployment": {"properties": {"version": {"type": "string", "pattern": "^v[0-9]"}}}}}}}`
+	if !assert.Nil(t, ioutil.WriteFile(filepath.Join(chartDir, "values.schema.json"), []byte(schema), 0644)) {
+		t.FailNow()
+	}
+	spec := test.spec("1.0.0")
+	spec.ChartDir = chartDir
+
+	_, err := test.deployer.DeployBlueGreen(context.TODO(), spec)
+
+	assert.Equal(t, runtime.EXIT_CODE_VALIDATION, runtime.ExitCode(err))
+	assert.Contains(t, err.Error(), "Chart values for prod-blue-some-api are invalid")
+	assert.Contains(t, err.Error(), "bluegreen.deployment.version: Does not match pattern")
+	assert.NotContains(t, test.phaseEvents(), PHASE_RELEASE_DEPLOYED)
+
+	spec.AppVersion = "v1.0.0"
+	_, err = test.deployer.DeployBlueGreen(context.TODO(), spec)
+	assert.Nil(t, err)
+	assert.Equal(t, "v1.0.0", test.version("blue"))
+}
+
+func Test_E2E_BlueGreen_Validates_The_Service_Release_Before_Deploying_A_Colour(t *testing.T) {
+	test := newE2E(t)
+	chartDir := test.copyChart(E2E_BLUEGREEN_CHART)
+	schema := `{"properties": {"bluegreen": {"properties": {"is_service_release": {"enum": [false]}}}}}`
+	if !assert.Nil(t, ioutil.WriteFile(filepath.Join(chartDir, "values.schema.json"), []byte(schema), 0644)) {
+		t.FailNow()
+	}
+	spec := test.spec("1.0.0")
+	spec.ChartDir = chartDir
+
+	_, err := test.deployer.DeployBlueGreen(context.TODO(), spec)
+
+	assert.Equal(t, runtime.EXIT_CODE_VALIDATION, runtime.ExitCode(err))
+	assert.Contains(t, err.Error(), "Chart values for prod-service-some-api are invalid")
+	assert.NotContains(t, err.Error(), "prod-blue-some-api")
+	assert.Empty(t, test.phaseEvents())
+	for _, releaseName := range []string{"prod-blue-some-api", "prod-green-some-api"} {
+		_, err = action.NewGet(test.helmConfig).Run(releaseName)
+		assert.NotNil(t, err)
+	}
+}
+
+// copyChart copies chartDir to a temporary dir, leaving out skipped files,
+// and returns the copy.
+func (test *e2e) copyChart(chartDir string, skipped ...string) string {
+	copied, err := ioutil.TempDir("", "chart")
+	if !assert.Nil(test.t, err) {
+		test.t.FailNow()
+	}
+	test.t.Cleanup(func() { os.RemoveAll(copied) })
+	err = filepath.Walk(chartDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relative, _ := filepath.Rel(chartDir, path)
+		for _, skip := range skipped {
+			if relative == skip && info.IsDir() {
+				return filepath.SkipDir
+			} else if relative == skip {
+				return nil
+			}
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(copied, relative), 0755)
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(copied, relative), contents, 0644)
+	})
+	assert.Nil(test.t, err)
+	return copied
+}
+
+func Test_E2E_BlueGreen_Refuses_A_Chart_Without_Dependencies(t *testing.T) {
+	test := newE2E(t)
+	spec := test.spec("v1.0.0")
+	spec.ChartDir = E2E_STANDARD_CHART
+
+	_, err := test.deployer.DeployBlueGreen(context.TODO(), spec)
+
+	assert.Equal(t, runtime.EXIT_CODE_VALIDATION, runtime.ExitCode(err))
+	assert.Contains(t, err.Error(), "has no list of dependencies")
+}
+
+func Test_E2E_BlueGreen_Refuses_A_Chart_Without_A_Lock(t *testing.T) {
+	test := newE2E(t)
+	spec := test.spec("1.0.0")
+	spec.ChartDir = test.copyChart(E2E_BLUEGREEN_CHART, "Chart.lock")
+	_, err := test.deployer.DeployBlueGreen(context.TODO(), spec)
+
+	assert.Equal(t, runtime.EXIT_CODE_VALIDATION, runtime.ExitCode(err))
+	assert.Contains(t, err.Error(), "helm dependency update")
+	assert.Empty(t, test.phaseEvents())
+}
+
+func Test_E2E_BlueGreen_Refuses_A_Chart_With_An_Out_Of_Date_Lock(t *testing.T) {
+	test := newE2E(t)
+	spec := test.spec("1.0.0")
+	spec.ChartDir = test.copyChart(E2E_BLUEGREEN_CHART)
+	chartYaml, _ := ioutil.ReadFile(spec.ChartDir + "/Chart.yaml")
+	assert.Nil(t, ioutil.WriteFile(spec.ChartDir+"/Chart.yaml", []byte(strings.Replace(string(chartYaml), ">=0.11.34", ">=0.11.35", 1)), 0644))
+	_, err := test.deployer.DeployBlueGreen(context.TODO(), spec)
+
+	assert.Equal(t, runtime.EXIT_CODE_VALIDATION, runtime.ExitCode(err))
+	assert.Contains(t, err.Error(), "out of date")
+	assert.Empty(t, test.phaseEvents())
+}
+
+func Test_E2E_BlueGreen_Refuses_A_Dependency_Older_Than_The_Configured_Minimum(t *testing.T) {
+	test := newE2E(t)
+	test.useEnvironment(&config.Environment{
+		Namespace:       E2E_NAMESPACE,
+		InstallTimeout:  config.DEFAULT_TIMEOUT,
+		UpgradeTimeout:  config.DEFAULT_TIMEOUT,
+		RollbackTimeout: config.DEFAULT_ROLLBACK_TIMEOUT,
+		PendingTimeout:  config.DEFAULT_PENDING_TIMEOUT,
+
+		MinBlueGreenMicroserviceVersion: "0.12.0",
+		MinMicroserviceVersion:          config.DEFAULT_MIN_MICROSERVICE_VERSION,
+	})
+	_, err := test.deployer.DeployBlueGreen(context.TODO(), test.spec("1.0.0"))
+
+	assert.Equal(t, runtime.EXIT_CODE_VALIDATION, runtime.ExitCode(err))
+	assert.Contains(t, err.Error(), "0.12.0")
+	assert.Empty(t, test.phaseEvents())
+}
+
+func Test_E2E_StandardChart_Builds_File_Dependencies(t *testing.T) {
+	test := newE2E(t)
+	spec := Spec{ChartDir: filepath.Join(test.copyChart("../testdata/local-deps"), "app"), AppName: E2E_APP_NAME, TargetEnv: E2E_TARGET_ENV}
+
+	spec.VerifyDependencies = true
+	_, err := test.deployer.DeployStandardChart(context.TODO(), spec)
+	assert.Equal(t, runtime.EXIT_CODE_VALIDATION, runtime.ExitCode(err))
+	assert.Contains(t, err.Error(), "worker 0.2.0")
+
+	spec.BuildDependencies = true
+	_, err = test.deployer.DeployStandardChart(context.TODO(), spec)
+	assert.Nil(t, err)
+	assert.Contains(t, test.release("prod-some-api").Manifest, "prod-some-api-worker")
+}
+
+// seedRepository adds the chart repository at url to the deployer's
+// repositories.yaml, with its index cached listing archive as served by a
+// local server, so helm's dependency build needs neither a repo update nor the
+// real repository.
+func (test *e2e) seedRepository(url, archive string) {
+	loaded, err := loader.LoadFile(archive)
+	if !assert.Nil(test.t, err) {
+		test.t.FailNow()
+	}
+	server := httptest.NewServer(http.FileServer(http.Dir(filepath.Dir(archive))))
+	test.t.Cleanup(server.Close)
+
+	index := repo.NewIndexFile()
+	assert.Nil(test.t, index.MustAdd(loaded.Metadata, filepath.Base(archive), server.URL, ""))
+	assert.Nil(test.t, index.WriteFile(filepath.Join(test.repositoryCache, helmpath.CacheIndexFile("seeded")), 0644))
+	repositories := repo.NewFile()
+	repositories.Add(&repo.Entry{Name: "seeded", URL: url})
+	assert.Nil(test.t, repositories.WriteFile(filepath.Join(test.repositoryCache, "repositories.yaml"), 0644))
+}
+
+func Test_E2E_BlueGreen_Builds_Dependencies_From_A_Cached_Repository_Index(t *testing.T) {
+	test := newE2E(t)
+	spec := test.spec("1.0.0")
+	spec.ChartDir = test.copyChart(E2E_BLUEGREEN_CHART, "charts")
+	spec.BuildDependencies = true
+	_, err := test.deployer.DeployBlueGreen(context.TODO(), spec)
+	assert.Equal(t, runtime.EXIT_CODE_VALIDATION, runtime.ExitCode(err))
+	assert.Contains(t, err.Error(), "Could not build the dependencies")
+	assert.Empty(t, test.phaseEvents())
+
+	test.seedRepository("https://chartmuseum.rnd.hutchison-rnd.co.uk", E2E_BLUEGREEN_CHART+"/charts/blue-green-microservice-0.11.35.tgz")
+	spec.VerifyDependencies = true
+	_, err = test.deployer.DeployBlueGreen(context.TODO(), spec)
+	assert.Nil(t, err)
+	assert.Equal(t, "blue", test.liveColour())
+}
@@ -0,0 +1,81 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/gosexy/to"
+	"github.com/Hutchison-Technologies/helm-deployer/gosexy/yaml"
+	"github.com/Hutchison-Technologies/helm-deployer/k8s"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (r *run) runHealthGate(targetEnv, colour, appName string, chartValuesYaml *yaml.Yaml, smokeTest bool, maxRestarts int32) error {
+	deploymentName := deployment.BlueGreenDeploymentName(targetEnv, colour, appName)
+	if err := r.waitForReadyReplicas(deploymentName, 1); err != nil {
+		return fmt.Errorf("%s did not become ready: %s", deploymentName, err)
+	}
+
+	r.logger.Printf("Checking pod readiness and restart counts of %s..", green(deploymentName))
+	if err := r.checkDeploymentPods(deploymentName, maxRestarts); err != nil {
+		return err
+	}
+	r.logger.Println("All pods are ready")
+
+	if !smokeTest {
+		r.logger.Println("Smoke test disabled, skipping.")
+		return nil
+	}
+
+	probePath := to.String(chartValuesYaml.Get("bluegreen", "deployment", "live_probe_path"))
+	if probePath == "" {
+		return errors.New("Smoke test enabled but bluegreen.deployment.live_probe_path is not set in the chart values")
+	}
+	return r.smokeTestOfflineService(targetEnv, appName, probePath)
+}
+
+func (r *run) checkDeploymentPods(deploymentName string, maxRestarts int32) error {
+	result, err := r.kube.AppsV1().Deployments(r.namespace).Get(r.ctx, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("Failed to get %s: %s", deploymentName, err)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(result.Spec.Selector)
+	if err != nil {
+		return fmt.Errorf("Failed to read the pod selector of %s: %s", deploymentName, err)
+	}
+
+	pods, err := r.kube.CoreV1().Pods(r.namespace).List(r.ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return fmt.Errorf("Failed to list the pods of %s: %s", deploymentName, err)
+	}
+
+	problems := k8s.PodHealthProblems(pods.Items, maxRestarts)
+	if len(problems) > 0 {
+		return fmt.Errorf("%s is unhealthy:\n\t%s", deploymentName, strings.Join(problems, "\n\t"))
+	}
+	return nil
+}
+
+func (r *run) smokeTestOfflineService(targetEnv, appName, probePath string) error {
+	kubeClient := r.kube.CoreV1()
+	offlineService, err := deployment.GetOfflineService(r.ctx, kubeClient, r.namespace, targetEnv, appName)
+	if err != nil {
+		return err
+	}
+	if len(offlineService.Spec.Ports) == 0 {
+		return fmt.Errorf("Offline service %s exposes no ports to smoke test", offlineService.GetName())
+	}
+	port := strconv.Itoa(int(offlineService.Spec.Ports[0].Port))
+
+	r.logger.Printf("Smoke testing %s through %s on port %s..", green(probePath), green(offlineService.GetName()), port)
+	_, err = kubeClient.Services(r.namespace).ProxyGet("http", offlineService.GetName(), port, probePath, nil).DoRaw(r.ctx)
+	if err != nil {
+		return fmt.Errorf("Smoke test of %s through %s failed: %s", probePath, offlineService.GetName(), err)
+	}
+	r.logger.Println("Smoke test passed")
+	return nil
+}
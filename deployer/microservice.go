@@ -0,0 +1,66 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Hutchison-Technologies/helm-deployer/charts"
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+)
+
+func (r *run) deployMicroservice(spec Spec) error {
+	if err := r.prepareDependencies(spec); err != nil {
+		return err
+	}
+
+	r.logger.Println("Asserting that this is a microservice chart..")
+	if err := r.assertChartIsMicroservice(spec.ChartDir); err != nil {
+		return err
+	}
+	r.logger.Println("This is a microservice chart!")
+
+	r.logger.Println("Loading chart values..")
+	chartValuesYaml, err := r.loadChartValues(spec)
+	if err != nil {
+		return err
+	}
+	r.logger.Println("Successfully loaded chart values")
+
+	deploymentName := deployment.StandardChartDeploymentName(spec.TargetEnv, spec.AppName)
+	chartValuesEdits := deployment.ChartValuesForMicroserviceDeployment(spec.AppVersion)
+	if err := r.validateReleaseValues(chartValuesYaml, spec.ChartDir, []releaseEdits{{deploymentName, chartValuesEdits}}); err != nil {
+		return err
+	}
+
+	r.logger.Printf("Preparing to deploy %s..", green(deploymentName))
+	deployedRelease, changed, err := r.releaseWithValues(
+		deploymentName,
+		chartValuesYaml,
+		chartValuesEdits,
+		spec.ChartDir)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return r.reportUnchanged(deploymentName, deployedRelease, spec.FailOnNoChange)
+	}
+	r.logger.Printf("Successfully deployed %s, the service is now live!", green(deploymentName))
+	r.printRelease(deployedRelease)
+	r.logPhase(Phase{Event: PHASE_RELEASE_DEPLOYED, Release: deploymentName, Revision: deployedRelease.Version})
+
+	return nil
+}
+
+func (r *run) assertChartIsMicroservice(chartDir string) error {
+	chartYamlPath := charts.ChartYamlPath(chartDir)
+	r.logger.Printf("Checking %s for microservice dependency..", green(chartYamlPath))
+	hasDependency, err := charts.HasDependency(chartYamlPath, "microservice", "")
+	if err != nil {
+		return runtime.ValidationError(err)
+	}
+	if !hasDependency {
+		return runtime.ValidationError(errors.New(fmt.Sprintf("Dependency %s must be present in the %s file in order to deploy using this program.", green("microservice"), green(chartYamlPath))))
+	}
+	return r.assertDependencyVersion(chartDir, "microservice", r.env.MinMicroserviceVersion)
+}
@@ -0,0 +1,182 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/gosexy/yaml"
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	PLAN_DIFF_CONTEXT = 3
+	// HELM_PENDING_OPERATION_ERROR is how helm refuses to act on a release
+	// that another install, upgrade or rollback is pending on.
+	HELM_PENDING_OPERATION_ERROR = "another operation (install/upgrade/rollback) is in progress"
+)
+
+type deployTypes struct {
+	BLUEGREEN      string
+	CANARY         string
+	MICROSERVICE   string
+	STANDARD_CHART string
+}
+
+// DeployType lists the kinds of deploy that can be planned.
+var DeployType = &deployTypes{
+	BLUEGREEN:      "bluegreen",
+	CANARY:         "canary",
+	MICROSERVICE:   "microservice",
+	STANDARD_CHART: "standard-chart",
+}
+
+var ErrPlanHasChanges = runtime.PlanHasChangesError(errors.New("The plan contains changes"))
+
+func (r *run) plan(deployType string, spec Spec) error {
+	if deployType != DeployType.STANDARD_CHART && spec.AppVersion == "" {
+		return runtime.ValidationError(fmt.Errorf("An app version is required to plan a %s deploy", orange(deployType)))
+	}
+	if err := r.prepareDependencies(spec); err != nil {
+		return err
+	}
+
+	r.logger.Println("Loading chart values..")
+	chartValuesYaml, err := r.loadChartValues(spec)
+	if err != nil {
+		return err
+	}
+	r.logger.Println("Successfully loaded chart values")
+
+	hasChanges := false
+	switch deployType {
+	case DeployType.BLUEGREEN, DeployType.CANARY:
+		if err := r.assertChartIsBlueGreen(spec.ChartDir); err != nil {
+			return err
+		}
+		if err := r.validateReleaseValues(chartValuesYaml, spec.ChartDir, blueGreenReleaseEdits(spec)); err != nil {
+			return err
+		}
+
+		r.logger.Println("Determining deploy colour..")
+		deployColour, err := r.determineDeployColour(spec.TargetEnv, spec.AppName)
+		if err != nil {
+			return err
+		}
+		r.logger.Printf("Determined deploy colour: %s", green(deployColour))
+
+		hasChanges, err = r.planRelease(
+			deployment.BlueGreenDeploymentName(spec.TargetEnv, deployColour, spec.AppName),
+			chartValuesYaml,
+			deployment.ChartValuesForDeployment(deployColour, spec.AppVersion),
+			spec.ChartDir)
+		if err != nil {
+			return err
+		}
+		serviceHasChanges, err := r.planRelease(
+			deployment.ServiceReleaseName(spec.TargetEnv, spec.AppName),
+			chartValuesYaml,
+			deployment.ChartValuesForServiceRelease(deployColour),
+			spec.ChartDir)
+		if err != nil {
+			return err
+		}
+		hasChanges = hasChanges || serviceHasChanges
+	case DeployType.MICROSERVICE:
+		if err := r.assertChartIsMicroservice(spec.ChartDir); err != nil {
+			return err
+		}
+		deploymentName := deployment.StandardChartDeploymentName(spec.TargetEnv, spec.AppName)
+		chartValuesEdits := deployment.ChartValuesForMicroserviceDeployment(spec.AppVersion)
+		if err := r.validateReleaseValues(chartValuesYaml, spec.ChartDir, []releaseEdits{{deploymentName, chartValuesEdits}}); err != nil {
+			return err
+		}
+		hasChanges, err = r.planRelease(deploymentName, chartValuesYaml, chartValuesEdits, spec.ChartDir)
+	case DeployType.STANDARD_CHART:
+		deploymentName := deployment.StandardChartDeploymentName(spec.TargetEnv, spec.AppName)
+		if err := r.validateReleaseValues(chartValuesYaml, spec.ChartDir, []releaseEdits{{deploymentName, [][]interface{}{}}}); err != nil {
+			return err
+		}
+		hasChanges, err = r.planRelease(deploymentName, chartValuesYaml, [][]interface{}{}, spec.ChartDir)
+	default:
+		return runtime.ValidationError(fmt.Errorf("Unable to plan a %s deploy", orange(deployType)))
+	}
+	if err != nil {
+		return err
+	}
+
+	if hasChanges {
+		r.logger.Printf("The plan contains changes, exiting with code %d", runtime.EXIT_CODE_PLAN_HAS_CHANGES)
+		return ErrPlanHasChanges
+	}
+	r.logger.Println("The plan contains no changes")
+	return nil
+}
+
+func (r *run) planRelease(releaseName string, chartValuesYaml *yaml.Yaml, chartValuesEdits [][]interface{}, chartDir string) (bool, error) {
+	r.logger.Printf("Editing chart values to plan %s..", green(releaseName))
+	chartValues, err := editChartValues(chartValuesYaml, chartValuesEdits)
+	if err != nil {
+		return false, err
+	}
+
+	r.logger.Printf("Checking for existing %s release..", green(releaseName))
+	existingRelease, err := action.NewGet(r.helmConfig).Run(releaseName)
+	if err != nil && isClusterFailure(err) {
+		return false, runtime.ClusterError(fmt.Errorf("Failed to get %s: %w", releaseName, err))
+	}
+
+	currentManifest := ""
+	var dryRunRelease *release.Release
+	releaseCourse := r.releaseCourse(releaseName, existingRelease, err)
+	if existingRelease != nil && existingRelease.Info.Status.IsPending() && releaseCourse != deployment.ReleaseCourse.RECOVER_PENDING {
+		fmt.Fprintf(r.out, "%s\n", orange(fmt.Sprintf("%s is %s, another deploy may still be running it.", releaseName, existingRelease.Info.Status)))
+		return false, runtime.ClusterError(fmt.Errorf("%s has been %s since %s and can't be planned until it settles: %w", releaseName, existingRelease.Info.Status, existingRelease.Info.LastDeployed.Format(time.RFC3339), errReleasePending))
+	}
+	switch releaseCourse {
+	case deployment.ReleaseCourse.RECOVER_PENDING:
+		fmt.Fprintf(r.out, "%s\n", orange(fmt.Sprintf("%s is stuck %s, deploying would recover it first.", releaseName, existingRelease.Info.Status)))
+		return false, runtime.ValidationError(fmt.Errorf("%s is stuck %s and can't be planned until a deploy has recovered it", releaseName, existingRelease.Info.Status))
+	case deployment.ReleaseCourse.INSTALL:
+		r.logger.Println("No existing release found, dry-running install..")
+		dryRunRelease, err = r.installRelease(releaseName, chartDir, chartValues, true)
+	case deployment.ReleaseCourse.UPGRADE_WITH_DIFF_CHECK:
+		currentManifest = existingRelease.Manifest
+		fallthrough
+	default:
+		r.logger.Println("Dry-running upgrade..")
+		dryRunRelease, err = r.upgradeRelease(releaseName, chartDir, chartValues, true)
+	}
+	if err != nil {
+		err = fmt.Errorf("Failed to dry-run %s: %w", releaseName, err)
+		if isClusterFailure(err) {
+			return false, runtime.ClusterError(err)
+		}
+		return false, runtime.ValidationError(err)
+	}
+
+	fmt.Fprintf(r.out, "%s\n", orange(fmt.Sprintf("Plan for %s:", releaseName)))
+	hasChanges := r.diffManifests(currentManifest, dryRunRelease.Manifest, PLAN_DIFF_CONTEXT, r.out)
+	r.report.recordDiff(releaseName, SummariseDiff(currentManifest, dryRunRelease.Manifest, r.namespace))
+	if !hasChanges {
+		fmt.Fprintln(r.out, "No changes.")
+	}
+	return hasChanges, nil
+}
+
+// isClusterFailure reports whether err came from reaching or talking to the
+// cluster, or from another operation in progress on the release, rather than
+// from the chart or its values.
+func isClusterFailure(err error) bool {
+	var apiStatus k8serrors.APIStatus
+	var netErr net.Error
+	return errors.As(err, &apiStatus) || errors.As(err, &netErr) || errors.Is(err, errReleasePending) ||
+		strings.Contains(err.Error(), "Kubernetes cluster unreachable") || strings.Contains(err.Error(), HELM_PENDING_OPERATION_ERROR)
+}
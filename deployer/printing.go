@@ -0,0 +1,43 @@
+package deployer
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+var ansiEscape = regexp.MustCompile("\033\\[[0-9;]*m")
+
+func (deployer *Deployer) printRelease(rel *release.Release) {
+	deployer.logger.Printf("\n\tName: %s\n\tRevision: %s\n\tStatus: %s\n\tLast Deployed: %s",
+		green(rel.Name), green(strconv.FormatInt(int64(rel.Version), 10)), green(rel.Info.Status.String()), green(rel.Info.LastDeployed.Local().String()))
+}
+
+func green(str string) string {
+	return fmt.Sprintf("\033[32m%s\033[97m", str)
+}
+
+func orange(str string) string {
+	return fmt.Sprintf("\033[33m%s\033[97m", str)
+}
+
+// StripColour removes the ANSI colour codes that green and orange add.
+func StripColour(str string) string {
+	return ansiEscape.ReplaceAllString(str, "")
+}
+
+// plainWriter strips colour from everything written to out, for callers that
+// haven't asked for it.
+type plainWriter struct {
+	out io.Writer
+}
+
+func (writer *plainWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(writer.out, StripColour(string(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
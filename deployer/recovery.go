@@ -0,0 +1,59 @@
+package deployer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Hutchison-Technologies/helm-deployer/h3lm"
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// recoverPendingRelease settles a release left pending by a deploy that never
+// finished, which helm would otherwise refuse to upgrade. It is rolled back
+// to its latest deployed revision or, when it was never deployed, uninstalled.
+func (r *run) recoverPendingRelease(stuckRelease *release.Release) error {
+	releaseName, revision, status := stuckRelease.Name, stuckRelease.Version, stuckRelease.Info.Status
+	r.logger.Printf("Revision %d of %s has been %s since %s, longer than the pending timeout of %s, recovering it..",
+		revision, green(releaseName), status, stuckRelease.Info.LastDeployed.Format(time.RFC3339), r.env.PendingTimeoutDuration())
+
+	history := action.NewHistory(r.helmConfig)
+	history.Max = ROLLBACK_VERSION_POOL
+	releaseHistory, err := history.Run(releaseName)
+	if err != nil {
+		return runtime.ClusterError(fmt.Errorf("Unable to read the history of %s to recover it: %s", releaseName, err))
+	}
+
+	deployedReleases := h3lm.FilterReleasesByStatusCode(releaseHistory, release.StatusDeployed)
+	if len(deployedReleases) > 0 {
+		target := h3lm.LatestRelease(deployedReleases)
+		r.logger.Printf("Rolling %s back to its latest deployed revision, %d..", green(releaseName), target.Version)
+		if err := r.helmRollback(releaseName, target.Version); err != nil {
+			return runtime.RollbackFailedError(fmt.Errorf("Unable to recover %s from %s: %s", releaseName, status, err))
+		}
+		r.logger.Printf("Recovered %s from %s by rolling it back to revision %d", green(releaseName), status, target.Version)
+		r.report.recordRecovery(releaseName, revision, status, RECOVERY_ROLLED_BACK, target.Version)
+		r.logPhase(Phase{Event: PHASE_RELEASE_RECOVERED, Release: releaseName, Revision: target.Version})
+		return nil
+	}
+
+	if status != release.StatusPendingInstall {
+		return runtime.RollbackFailedError(fmt.Errorf("%s is stuck %s with no deployed revision to roll back to, it needs recovering by hand", releaseName, status))
+	}
+	r.logger.Printf("%s was never deployed, uninstalling it..", green(releaseName))
+	uninstallManager := action.NewUninstall(r.helmConfig)
+	uninstallManager.Timeout = r.env.RollbackTimeoutDuration()
+	_, err = r.runHelm(releaseName, uninstallManager.Timeout, func() (*release.Release, error) {
+		_, err := uninstallManager.Run(releaseName)
+		return nil, err
+	})
+	if err != nil {
+		return runtime.RollbackFailedError(fmt.Errorf("Unable to recover %s from %s: %s", releaseName, status, err))
+	}
+	r.logger.Printf("Recovered %s from %s by uninstalling it", green(releaseName), status)
+	r.report.recordRecovery(releaseName, revision, status, RECOVERY_UNINSTALLED, 0)
+	r.logPhase(Phase{Event: PHASE_RELEASE_RECOVERED, Release: releaseName})
+	return nil
+}
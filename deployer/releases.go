@@ -0,0 +1,473 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/Hutchison-Technologies/helm-deployer/charts"
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/filesystem"
+	"github.com/Hutchison-Technologies/helm-deployer/gosexy/yaml"
+	"github.com/Hutchison-Technologies/helm-deployer/h3lm"
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+
+	"github.com/databus23/helm-diff/diff"
+	"github.com/databus23/helm-diff/manifest"
+	goYaml "github.com/ghodss/yaml"
+
+	"helm.sh/helm/v3/pkg/action"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// errReleasePending is why a release that is pending, but not yet taken to
+// be stuck, isn't deployed or rolled back: another deploy may still be
+// running it.
+var errReleasePending = errors.New("another operation is in progress on the release")
+
+// loadChartValues merges the chart's values in layers, each overriding the
+// last: values.yaml and common.yaml when the chart has them, <env>.yaml,
+// spec.ValuesFiles, then spec.Set and spec.SetString.
+func (r *run) loadChartValues(spec Spec) (*yaml.Yaml, error) {
+	valuesPaths := []string{}
+	for _, path := range []string{deployment.ChartDefaultValuesPath(spec.ChartDir), deployment.ChartCommonValuesPath(spec.ChartDir)} {
+		if filesystem.IsFile(path) {
+			valuesPaths = append(valuesPaths, path)
+		}
+	}
+	valuesPaths = append(valuesPaths, deployment.ChartValuesPath(spec.ChartDir, spec.TargetEnv))
+	valuesPaths = append(valuesPaths, spec.ValuesFiles...)
+	r.logger.Printf("Merging chart values from %s..", green(strings.Join(valuesPaths, ", ")))
+
+	values, err := charts.MergeValuesYaml(valuesPaths, spec.Set, spec.SetString)
+	if err != nil {
+		return nil, runtime.ValidationError(err)
+	}
+	if spec.ShowValues {
+		valuesYaml, err := values.Marshal()
+		if err != nil {
+			return nil, runtime.ValidationError(err)
+		}
+		fmt.Fprintf(r.out, "%s\n%s", orange(fmt.Sprintf("Values for %s:", spec.TargetEnv)), valuesYaml)
+	}
+	return values, nil
+}
+
+func editChartValues(valuesYaml *yaml.Yaml, settings [][]interface{}) ([]byte, error) {
+	values, err := charts.EditValuesYaml(valuesYaml, settings)
+	return values, runtime.ValidationError(err)
+}
+
+// releaseEdits are the edits made to the chart values to deploy a release.
+type releaseEdits struct {
+	releaseName string
+	edits       [][]interface{}
+}
+
+// blueGreenReleaseEdits lists the edits made to deploy the colour and service
+// releases of a bluegreen chart, as either colour.
+func blueGreenReleaseEdits(spec Spec) []releaseEdits {
+	releases := []releaseEdits{}
+	for _, colour := range colours {
+		releases = append(releases, releaseEdits{deployment.BlueGreenDeploymentName(spec.TargetEnv, colour, spec.AppName), deployment.ChartValuesForDeployment(colour, spec.AppVersion)})
+	}
+	for _, colour := range colours {
+		releases = append(releases, releaseEdits{deployment.ServiceReleaseName(spec.TargetEnv, spec.AppName), deployment.ChartValuesForServiceRelease(colour)})
+	}
+	return releases
+}
+
+// validateReleaseValues checks the values each of releases would be deployed
+// with against the chart's schemas and the environment's required values, so
+// that no release is touched when the values of any of them are invalid.
+func (r *run) validateReleaseValues(chartValuesYaml *yaml.Yaml, chartDir string, releases []releaseEdits) error {
+	r.logger.Println("Validating the chart values of each release against the chart's schemas and required values..")
+	invalid := map[string]bool{}
+	failures := []string{}
+	for _, edited := range releases {
+		if invalid[edited.releaseName] {
+			continue
+		}
+		chartValues, err := editChartValues(chartValuesYaml, edited.edits)
+		if err != nil {
+			return err
+		}
+		violations, err := charts.ValuesViolations(chartDir, chartValues, r.env.RequiredValues)
+		if err != nil {
+			return runtime.ValidationError(err)
+		}
+		if len(violations) > 0 {
+			invalid[edited.releaseName] = true
+			failures = append(failures, fmt.Sprintf("Chart values for %s are invalid:\n\t%s", edited.releaseName, strings.Join(violations, "\n\t")))
+		}
+	}
+	if len(failures) > 0 {
+		return runtime.ValidationError(errors.New(strings.Join(failures, "\n")))
+	}
+	r.logger.Println("The chart values are valid")
+	return nil
+}
+
+// releaseWithValues deploys the chart as releaseName, rolling back on failure.
+// When the diff check finds nothing to change, the existing release is
+// returned and changed is false.
+func (r *run) releaseWithValues(releaseName string, chartValuesYaml *yaml.Yaml, chartValuesEdits [][]interface{}, chartDir string) (deployedRelease *release.Release, changed bool, err error) {
+	r.logger.Printf("Editing chart values to deploy %s..", green(releaseName))
+	chartValues, err := editChartValues(chartValuesYaml, chartValuesEdits)
+	if err != nil {
+		return nil, false, err
+	}
+	r.logger.Printf("Successfully edited chart values:\n%s", orange(string(chartValues)))
+
+	r.logger.Printf("Deploying: %s..", green(releaseName))
+	deployedRelease, err = r.deployRelease(releaseName, chartDir, chartValues)
+	if err == nil {
+		r.report.recordRelease(releaseName, deployedRelease, true)
+		return deployedRelease, true, nil
+	}
+
+	if errors.Is(err, errReleasePending) {
+		r.logger.Printf("Not deploying %s: %s", green(releaseName), err.Error())
+		return nil, false, err
+	}
+	switch runtime.ExitCode(err) {
+	case runtime.EXIT_CODE_NO_CHANGES:
+		r.logger.Println(err.Error())
+		r.report.recordRelease(releaseName, deployedRelease, false)
+		return deployedRelease, false, nil
+	case runtime.EXIT_CODE_VALIDATION:
+		r.logger.Printf("Error deploying %s: %s", green(releaseName), err.Error())
+		r.logger.Println("Nothing was deployed, no rollback necessary")
+		return nil, false, err
+	}
+	r.logger.Printf("Error deploying %s: %s", green(releaseName), err.Error())
+	r.report.recordFailedRelease(releaseName)
+	if r.cancelled != nil {
+		return nil, false, err
+	}
+	r.reportFailure(releaseName)
+
+	if r.env.Atomic {
+		r.logger.Println("Atomic release, helm has already rolled back or uninstalled the failed release")
+		return nil, false, runtime.DeployFailedError(fmt.Errorf("Original deploy error: %s", err))
+	}
+
+	r.logger.Println("Determining whether rollback is necessary..")
+	rollBack, statusErr := r.shouldRollBack(releaseName)
+	if statusErr != nil {
+		return nil, false, runtime.RollbackFailedError(fmt.Errorf("Original deploy error: %s, unable to determine release status: %s", err, statusErr))
+	}
+	if rollBack {
+		r.logger.Println("Rollback is necessary")
+		if rollbackErr := r.rollbackToLatestSuccessful(releaseName); rollbackErr != nil {
+			return nil, false, runtime.RollbackFailedError(fmt.Errorf("Original deploy error: %s, rollback error: %s", err, rollbackErr))
+		}
+	} else {
+		r.logger.Println("Current release is ok, nothing to do")
+	}
+	return nil, false, runtime.DeployFailedError(fmt.Errorf("Original deploy error: %s", err))
+}
+
+// reportUnchanged treats an unchanged release as success, unless failOnNoChange
+// asks for it to fail the deploy.
+func (r *run) reportUnchanged(releaseName string, existingRelease *release.Release, failOnNoChange bool) error {
+	if failOnNoChange {
+		return runtime.NoChangesError(fmt.Errorf("%s is unchanged and fail-on-no-change is set", releaseName))
+	}
+	r.logger.Printf("%s is unchanged, nothing to deploy", green(releaseName))
+	phase := Phase{Event: PHASE_RELEASE_UNCHANGED, Release: releaseName}
+	if existingRelease != nil {
+		r.printRelease(existingRelease)
+		phase.Revision = existingRelease.Version
+	}
+	r.logPhase(phase)
+	return nil
+}
+
+func (r *run) deployRelease(releaseName, chartDir string, chartValues []byte) (*release.Release, error) {
+	r.logger.Printf("Checking for existing %s release..", green(releaseName))
+
+	// Create new fetchManager to get information about existing releases
+	fetchManager := action.NewGet(r.helmConfig)
+	releaseContent, err := fetchManager.Run(releaseName)
+
+	if releaseContent != nil {
+		r.logger.Println("Found existing release:")
+		r.printRelease(releaseContent)
+	}
+
+	releaseCourse := r.releaseCourse(releaseName, releaseContent, err)
+	if releaseContent != nil && releaseContent.Info.Status.IsPending() && releaseCourse != deployment.ReleaseCourse.RECOVER_PENDING {
+		r.logger.Printf("%s is recovered automatically once it has been pending for longer than the pending timeout, unless recover_pending is false", green(releaseName))
+		return nil, runtime.DeployFailedError(fmt.Errorf("%s has been %s since %s: %w", releaseName, releaseContent.Info.Status, releaseContent.Info.LastDeployed.Format(time.RFC3339), errReleasePending))
+	}
+	if releaseCourse == deployment.ReleaseCourse.UPGRADE_WITH_DIFF_CHECK && !r.env.RequiresDiffCheck() {
+		r.logger.Printf("Diff check disabled for %s, upgrading without it..", green(r.env.Name))
+		releaseCourse = deployment.ReleaseCourse.UPGRADE
+	}
+
+	switch releaseCourse {
+	case deployment.ReleaseCourse.RECOVER_PENDING:
+		if err := r.recoverPendingRelease(releaseContent); err != nil {
+			return nil, err
+		}
+		return r.deployRelease(releaseName, chartDir, chartValues)
+	case deployment.ReleaseCourse.INSTALL:
+		r.logger.Println("No existing release found, installing release..")
+		installedRelease, err := r.installRelease(releaseName, chartDir, chartValues, false)
+		if err != nil {
+			return nil, err
+		}
+		r.report.recordDiff(releaseName, SummariseDiff("", installedRelease.Manifest, r.namespace))
+		return installedRelease, nil
+	case deployment.ReleaseCourse.UPGRADE_WITH_DIFF_CHECK:
+		r.logger.Println("Dry-running release to obtain full manifest..")
+
+		dryRunRelease, err := r.upgradeRelease(releaseName, chartDir, chartValues, true)
+		if err != nil {
+			return nil, err
+		}
+
+		r.logger.Println("Checking proposed release for changes against existing release..")
+		hasChanges := r.diffManifests(releaseContent.Manifest, dryRunRelease.Manifest, -1, ioutil.Discard)
+		r.report.recordDiff(releaseName, SummariseDiff(releaseContent.Manifest, dryRunRelease.Manifest, r.namespace))
+		if !hasChanges {
+			return releaseContent, runtime.NoChangesError(errors.New("No difference detected between this release and the existing release, no deploy."))
+		}
+		fallthrough
+	case deployment.ReleaseCourse.UPGRADE:
+		r.logger.Printf("Upgrading release, will timeout after %s..", r.env.UpgradeTimeoutDuration())
+		upgradeRelease, err := r.upgradeRelease(releaseName, chartDir, chartValues, false)
+		if err != nil {
+			return nil, err
+		}
+		return upgradeRelease, nil
+	}
+
+	return nil, errors.New("Unknown release course")
+}
+
+// releaseCourse decides how to deploy releaseName over existingRelease, as
+// found by getting it, recovering it first when it is stuck pending and the
+// environment recovers pending releases.
+func (r *run) releaseCourse(releaseName string, existingRelease *release.Release, getErr error) int {
+	existingReleaseCode := release.StatusUnknown
+	var pendingFor, stuckAfter time.Duration
+	if existingRelease != nil {
+		existingReleaseCode = existingRelease.Info.Status
+		pendingFor = time.Since(existingRelease.Info.LastDeployed.Time)
+	}
+	if r.env.RecoversPending() {
+		stuckAfter = r.env.PendingTimeoutDuration()
+	}
+	return deployment.DetermineReleaseCourse(releaseName, existingReleaseCode, getErr, pendingFor, stuckAfter)
+}
+
+func (r *run) installRelease(releaseName, chartDir string, chartValues []byte, dryRun bool) (*release.Release, error) {
+	chart, err := loader.Load(chartDir)
+	if err != nil {
+		return nil, runtime.ValidationError(err)
+	}
+	r.logger.Println("Chart: ", chart)
+
+	installManager := action.NewInstall(r.helmConfig)
+	installManager.Namespace = r.namespace
+	installManager.CreateNamespace = r.env.CreateNamespace
+	installManager.ReleaseName = releaseName
+	installManager.Wait = true
+	installManager.WaitForJobs = r.env.InstallWaitsForJobs()
+	installManager.Atomic = r.env.Atomic
+	installManager.Timeout = r.env.InstallTimeoutDuration()
+	installManager.Description = "Some chart"
+	installManager.DryRun = dryRun
+	if !dryRun {
+		r.logger.Printf("Installing with timeout=%s atomic=%t wait_for_jobs=%t create_namespace=%t",
+			installManager.Timeout, installManager.Atomic, installManager.WaitForJobs, installManager.CreateNamespace)
+	}
+
+	vals := make(map[string]interface{})
+	err = goYaml.Unmarshal(chartValues, &vals)
+	if err != nil {
+		return nil, runtime.ValidationError(err)
+	}
+
+	// Push values to chart and install
+	installResponse, err := r.runHelm(releaseName, installManager.Timeout, func() (*release.Release, error) {
+		return installManager.Run(chart, vals)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		r.logger.Println("Installed release: ", installResponse)
+	}
+	return installResponse, nil
+}
+
+func (r *run) diffManifests(currentManifest, newManifest string, manifestContext int, out io.Writer) bool {
+	currentManifests := manifest.Parse(currentManifest, r.namespace)
+	newManifests := manifest.Parse(newManifest, r.namespace)
+	return diff.Manifests(currentManifests, newManifests, []string{}, false, manifestContext, out)
+}
+
+func (r *run) upgradeRelease(releaseName, chartDir string, chartValues []byte, dryRun bool) (*release.Release, error) {
+	chart, err := loader.Load(chartDir)
+	if err != nil {
+		return nil, runtime.ValidationError(err)
+	}
+	r.logger.Println("Chart: ", chart)
+
+	upgradeManager := action.NewUpgrade(r.helmConfig)
+	upgradeManager.Force = r.env.UsesForce()
+	upgradeManager.Recreate = r.env.UsesRecreate()
+	upgradeManager.Atomic = r.env.Atomic
+	upgradeManager.CleanupOnFail = r.env.CleanupOnFail
+	upgradeManager.MaxHistory = r.env.MaxHistory
+	upgradeManager.Wait = true
+	upgradeManager.WaitForJobs = r.env.WaitsForJobs()
+	upgradeManager.Timeout = r.env.UpgradeTimeoutDuration()
+	upgradeManager.DryRun = dryRun
+	if !dryRun {
+		r.logger.Printf("Upgrading with timeout=%s force=%t recreate=%t atomic=%t wait_for_jobs=%t max_history=%d cleanup_on_fail=%t",
+			upgradeManager.Timeout, upgradeManager.Force, upgradeManager.Recreate, upgradeManager.Atomic,
+			upgradeManager.WaitForJobs, upgradeManager.MaxHistory, upgradeManager.CleanupOnFail)
+	}
+
+	vals := make(map[string]interface{})
+	err = goYaml.Unmarshal(chartValues, &vals)
+	if err != nil {
+		return nil, runtime.ValidationError(err)
+	}
+
+	// Push values to upgrade request
+	res, err := r.runHelm(releaseName, upgradeManager.Timeout, func() (*release.Release, error) {
+		return upgradeManager.Run(releaseName, chart, vals)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *run) shouldRollBack(releaseName string) (bool, error) {
+	status := action.NewStatus(r.helmConfig)
+	releaseStatus, err := status.Run(releaseName)
+	if err != nil {
+		return false, err
+	}
+	return releaseStatus.Info.Status != release.StatusDeployed, nil
+}
+
+func (r *run) rollbackToLatestSuccessful(releaseName string) error {
+	r.logger.Printf("Gathering up to the last %d release(s)..", ROLLBACK_VERSION_POOL)
+
+	status := action.NewHistory(r.helmConfig)
+	status.Max = ROLLBACK_VERSION_POOL
+
+	releaseHistory, err := status.Run(releaseName)
+	if err != nil {
+		return err
+	}
+	if len(releaseHistory) == 0 {
+		return errors.New("No prior release(s) to roll back to!")
+	}
+
+	r.logger.Printf("Found %d prior release(s), filtering for successful release(s)..", len(releaseHistory))
+	successfullyDeployedReleases := h3lm.FilterReleasesByStatusCode(releaseHistory, release.StatusDeployed)
+
+	if len(successfullyDeployedReleases) == 0 {
+		return errors.New("No successfully deployed prior release(s) to roll back to!")
+	}
+
+	r.logger.Printf("Found %d prior successful release(s), finding the latest..", len(successfullyDeployedReleases))
+	latestSuccessfulRelease := h3lm.LatestRelease(successfullyDeployedReleases)
+
+	r.logger.Println("Latest successful release:")
+	r.printRelease(latestSuccessfulRelease)
+
+	return r.rollbackToRevision(releaseName, latestSuccessfulRelease.Version)
+}
+
+func (r *run) rollbackToRevision(releaseName string, revision int) error {
+	if err := r.helmRollback(releaseName, revision); err != nil {
+		return err
+	}
+	r.report.recordRollback(releaseName, revision)
+	r.logPhase(Phase{Event: PHASE_RELEASE_ROLLED_BACK, Release: releaseName, Revision: revision})
+	return nil
+}
+
+func (r *run) helmRollback(releaseName string, revision int) error {
+	r.logger.Printf("Rolling %s back to revision %d..", green(releaseName), revision)
+
+	rollbackManager := action.NewRollback(r.helmConfig)
+	rollbackManager.Force = r.env.UsesForce()
+	rollbackManager.Recreate = r.env.UsesRecreate()
+	rollbackManager.CleanupOnFail = r.env.CleanupOnFail
+	rollbackManager.MaxHistory = r.env.MaxHistory
+	rollbackManager.Wait = true
+	rollbackManager.WaitForJobs = r.env.WaitsForJobs()
+	rollbackManager.Timeout = r.env.RollbackTimeoutDuration()
+	rollbackManager.Version = revision
+	r.logger.Printf("Rolling back with timeout=%s force=%t recreate=%t wait_for_jobs=%t max_history=%d cleanup_on_fail=%t",
+		rollbackManager.Timeout, rollbackManager.Force, rollbackManager.Recreate,
+		rollbackManager.WaitForJobs, rollbackManager.MaxHistory, rollbackManager.CleanupOnFail)
+
+	_, err := r.runHelm(releaseName, rollbackManager.Timeout, func() (*release.Release, error) {
+		return nil, rollbackManager.Run(releaseName)
+	})
+
+	if err != nil {
+		return fmt.Errorf("Failed to rollback: %s", err)
+	}
+	r.logger.Printf("Successfully rolled %s back:", green(releaseName))
+	return nil
+}
+
+// rollbackTarget finds revision of releaseName or, when revision is 0, its
+// latest successful revision before the current one.
+func (r *run) rollbackTarget(releaseName string, revision int) (*release.Release, error) {
+	if revision > 0 {
+		r.logger.Printf("Fetching revision %d of %s..", revision, green(releaseName))
+		getManager := action.NewGet(r.helmConfig)
+		getManager.Version = revision
+		targetRelease, err := getManager.Run(releaseName)
+		if err != nil {
+			return nil, runtime.ValidationError(fmt.Errorf("Unable to find revision %d of %s: %s", revision, green(releaseName), err))
+		}
+		return targetRelease, nil
+	}
+
+	r.logger.Printf("Gathering up to the last %d release(s) of %s..", ROLLBACK_VERSION_POOL, green(releaseName))
+	history := action.NewHistory(r.helmConfig)
+	history.Max = ROLLBACK_VERSION_POOL
+	releaseHistory, err := history.Run(releaseName)
+	if err != nil {
+		return nil, runtime.RollbackFailedError(fmt.Errorf("Unable to read the history of %s: %s", green(releaseName), err))
+	}
+
+	currentRelease, err := action.NewGet(r.helmConfig).Run(releaseName)
+	if err != nil {
+		return nil, runtime.RollbackFailedError(fmt.Errorf("Unable to find the current release of %s: %s", green(releaseName), err))
+	}
+	r.logger.Println("Current release:")
+	r.printRelease(currentRelease)
+
+	priorReleases := h3lm.FilterReleasesBeforeVersion(releaseHistory, currentRelease.Version)
+	successfulPriorReleases := append(
+		h3lm.FilterReleasesByStatusCode(priorReleases, release.StatusSuperseded),
+		h3lm.FilterReleasesByStatusCode(priorReleases, release.StatusDeployed)...)
+	if len(successfulPriorReleases) == 0 {
+		return nil, runtime.RollbackFailedError(errors.New("No successfully deployed prior release(s) to roll back to!"))
+	}
+
+	targetRelease := h3lm.LatestRelease(successfulPriorReleases)
+	r.logger.Println("Previous successful release:")
+	r.printRelease(targetRelease)
+	return targetRelease, nil
+}
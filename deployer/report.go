@@ -0,0 +1,138 @@
+package deployer
+
+import (
+	"sort"
+
+	"github.com/databus23/helm-diff/manifest"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// Report describes what a deploy did to the cluster.
+type Report struct {
+	LiveColourBefore string            `json:"live_colour_before,omitempty"`
+	LiveColourAfter  string            `json:"live_colour_after,omitempty"`
+	Releases         []*ReleaseReport  `json:"releases"`
+	RolledBack       bool              `json:"rolled_back"`
+	Rollbacks        []*ReleaseReport  `json:"rollbacks,omitempty"`
+	Recoveries       []*RecoveryReport `json:"recoveries,omitempty"`
+}
+
+// RecoveryReport describes a release found stuck pending before deploying,
+// and what was done to it: rolled back to RolledBackTo, or uninstalled.
+type RecoveryReport struct {
+	Name         string `json:"name"`
+	Revision     int    `json:"revision"`
+	Status       string `json:"status"`
+	Action       string `json:"action"`
+	RolledBackTo int    `json:"rolled_back_to,omitempty"`
+}
+
+type ReleaseReport struct {
+	Name     string       `json:"name"`
+	Revision int          `json:"revision,omitempty"`
+	Status   string       `json:"status,omitempty"`
+	Changed  bool         `json:"changed"`
+	Diff     *DiffSummary `json:"diff,omitempty"`
+}
+
+// DiffSummary lists the resources, as keyed by helm-diff, that a release adds,
+// removes or modifies.
+type DiffSummary struct {
+	Added    []string `json:"added"`
+	Removed  []string `json:"removed"`
+	Modified []string `json:"modified"`
+}
+
+func NewReport() *Report {
+	return &Report{Releases: []*ReleaseReport{}}
+}
+
+// release returns the entry for releaseName, adding it when first touched.
+func (report *Report) release(releaseName string) *ReleaseReport {
+	for _, releaseReport := range report.Releases {
+		if releaseReport.Name == releaseName {
+			return releaseReport
+		}
+	}
+	releaseReport := &ReleaseReport{Name: releaseName}
+	report.Releases = append(report.Releases, releaseReport)
+	return releaseReport
+}
+
+func (report *Report) recordRelease(releaseName string, rel *release.Release, changed bool) {
+	releaseReport := report.release(releaseName)
+	releaseReport.Changed = changed
+	if rel != nil {
+		releaseReport.Revision = rel.Version
+		if rel.Info != nil {
+			releaseReport.Status = rel.Info.Status.String()
+		}
+	}
+}
+
+func (report *Report) recordFailedRelease(releaseName string) {
+	report.release(releaseName).Status = release.StatusFailed.String()
+}
+
+func (report *Report) recordDiff(releaseName string, diff *DiffSummary) {
+	releaseReport := report.release(releaseName)
+	releaseReport.Diff = diff
+	releaseReport.Changed = diff.HasChanges()
+}
+
+func (report *Report) recordRollback(releaseName string, revision int) {
+	report.RolledBack = true
+	report.Rollbacks = append(report.Rollbacks, &ReleaseReport{Name: releaseName, Revision: revision, Changed: true})
+}
+
+func (report *Report) recordRecovery(releaseName string, revision int, status release.Status, action string, rolledBackTo int) {
+	report.Recoveries = append(report.Recoveries, &RecoveryReport{
+		Name:         releaseName,
+		Revision:     revision,
+		Status:       status.String(),
+		Action:       action,
+		RolledBackTo: rolledBackTo,
+	})
+}
+
+// Unchanged reports whether the deploy touched releases but changed none of
+// them.
+func (report *Report) Unchanged() bool {
+	if len(report.Releases) == 0 || report.RolledBack {
+		return false
+	}
+	for _, releaseReport := range report.Releases {
+		if releaseReport.Changed {
+			return false
+		}
+	}
+	return true
+}
+
+func (diff *DiffSummary) HasChanges() bool {
+	return len(diff.Added) > 0 || len(diff.Removed) > 0 || len(diff.Modified) > 0
+}
+
+// SummariseDiff compares two release manifests resource by resource.
+func SummariseDiff(currentManifest, newManifest, namespace string) *DiffSummary {
+	currentResources := manifest.Parse(currentManifest, namespace)
+	newResources := manifest.Parse(newManifest, namespace)
+	summary := &DiffSummary{Added: []string{}, Removed: []string{}, Modified: []string{}}
+	for key, newResource := range newResources {
+		currentResource, ok := currentResources[key]
+		if !ok {
+			summary.Added = append(summary.Added, key)
+		} else if currentResource.Content != newResource.Content {
+			summary.Modified = append(summary.Modified, key)
+		}
+	}
+	for key := range currentResources {
+		if _, ok := newResources[key]; !ok {
+			summary.Removed = append(summary.Removed, key)
+		}
+	}
+	sort.Strings(summary.Added)
+	sort.Strings(summary.Removed)
+	sort.Strings(summary.Modified)
+	return summary
+}
@@ -0,0 +1,94 @@
+package deployer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"helm.sh/helm/v3/pkg/release"
+	"testing"
+)
+
+const TEST_CURRENT_MANIFEST = `---
+# Source: some-api/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: prod-some-api
+spec:
+  selector:
+    colour: blue
+---
+# Source: some-api/templates/configmap.yaml
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: prod-some-api-config
+data:
+  key: value
+`
+
+const TEST_NEW_MANIFEST = `---
+# Source: some-api/templates/service.yaml
+apiVersion: v1
+kind: Service
+metadata:
+  name: prod-some-api
+spec:
+  selector:
+    colour: green
+---
+# Source: some-api/templates/hpa.yaml
+apiVersion: autoscaling/v1
+kind: HorizontalPodAutoscaler
+metadata:
+  name: prod-some-api-hpa
+`
+
+func Test_SummariseDiff_Lists_Added_Removed_And_Modified_Resources(t *testing.T) {
+	summary := SummariseDiff(TEST_CURRENT_MANIFEST, TEST_NEW_MANIFEST, "apps")
+	assert.True(t, summary.HasChanges())
+	assert.Equal(t, 1, len(summary.Added))
+	assert.Contains(t, summary.Added[0], "prod-some-api-hpa")
+	assert.Equal(t, 1, len(summary.Removed))
+	assert.Contains(t, summary.Removed[0], "prod-some-api-config")
+	assert.Equal(t, 1, len(summary.Modified))
+	assert.Contains(t, summary.Modified[0], "prod-some-api")
+}
+
+func Test_SummariseDiff_Has_No_Changes_When_Manifests_Match(t *testing.T) {
+	assert.False(t, SummariseDiff(TEST_CURRENT_MANIFEST, TEST_CURRENT_MANIFEST, "apps").HasChanges())
+}
+
+func Test_Report_Unchanged_When_No_Release_Changed(t *testing.T) {
+	report := NewReport()
+	report.recordRelease("prod-some-api", &release.Release{Version: 3, Info: &release.Info{Status: release.StatusDeployed}}, false)
+	assert.True(t, report.Unchanged())
+	assert.Equal(t, 3, report.Releases[0].Revision)
+	assert.Equal(t, "deployed", report.Releases[0].Status)
+}
+
+func Test_Report_Changed_When_A_Release_Changed(t *testing.T) {
+	report := NewReport()
+	report.recordDiff("prod-blue-some-api", SummariseDiff(TEST_CURRENT_MANIFEST, TEST_NEW_MANIFEST, "apps"))
+	report.recordRelease("prod-blue-some-api", &release.Release{Version: 4, Info: &release.Info{Status: release.StatusDeployed}}, true)
+	report.recordRelease("prod-service-some-api", nil, false)
+	assert.False(t, report.Unchanged())
+	assert.Equal(t, 2, len(report.Releases))
+	assert.NotNil(t, report.Releases[0].Diff)
+}
+
+func Test_Report_Not_Unchanged_Without_Releases(t *testing.T) {
+	assert.False(t, NewReport().Unchanged())
+}
+
+func Test_Report_Records_Rollbacks(t *testing.T) {
+	report := NewReport()
+	report.recordRollback("prod-service-some-api", 7)
+	assert.True(t, report.RolledBack)
+	assert.Equal(t, 7, report.Rollbacks[0].Revision)
+	assert.False(t, report.Unchanged())
+}
+
+func Test_Report_Records_Failed_Releases(t *testing.T) {
+	report := NewReport()
+	report.recordFailedRelease("prod-some-api")
+	assert.Equal(t, "failed", report.Releases[0].Status)
+}
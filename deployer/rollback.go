@@ -0,0 +1,68 @@
+package deployer
+
+import (
+	"fmt"
+
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+
+	"helm.sh/helm/v3/pkg/action"
+)
+
+func (r *run) rollback(spec Spec) error {
+	if spec.Colour != "" {
+		releaseName := deployment.BlueGreenDeploymentName(spec.TargetEnv, spec.Colour, spec.AppName)
+		r.logger.Printf("Rolling back bluegreen deployment release %s..", green(releaseName))
+		return r.rollbackRelease(releaseName, spec.Revision)
+	}
+
+	serviceReleaseName := deployment.ServiceReleaseName(spec.TargetEnv, spec.AppName)
+	r.logger.Printf("Checking for bluegreen service release %s..", green(serviceReleaseName))
+	if _, err := action.NewGet(r.helmConfig).Run(serviceReleaseName); err == nil {
+		r.logger.Printf("Found %s, rolling back the bluegreen cutover..", green(serviceReleaseName))
+		return r.rollbackBlueGreenServiceRelease(spec.TargetEnv, spec.AppName, spec.Revision, spec.KeepWarm)
+	}
+
+	releaseName := deployment.StandardChartDeploymentName(spec.TargetEnv, spec.AppName)
+	r.logger.Printf("No bluegreen service release found, rolling back %s..", green(releaseName))
+	return r.rollbackRelease(releaseName, spec.Revision)
+}
+
+func (r *run) rollbackBlueGreenServiceRelease(targetEnv, appName string, revision int, keepWarmReplicas int32) error {
+	serviceReleaseName := deployment.ServiceReleaseName(targetEnv, appName)
+	targetRelease, err := r.rollbackTarget(serviceReleaseName, revision)
+	if err != nil {
+		return err
+	}
+
+	targetColour := deployment.ServiceReleaseColour(targetRelease.Config)
+	if targetColour == "" {
+		return runtime.RollbackFailedError(fmt.Errorf("Unable to determine the service selector colour of %s revision %d", green(serviceReleaseName), targetRelease.Version))
+	}
+	r.logger.Printf("Revision %d of %s routes traffic to %s", targetRelease.Version, green(serviceReleaseName), green(targetColour))
+
+	r.report.LiveColourBefore = r.currentLiveColour(targetEnv, appName)
+	r.report.LiveColourAfter = r.report.LiveColourBefore
+
+	targetDeploymentName := deployment.BlueGreenDeploymentName(targetEnv, targetColour, appName)
+	r.logger.Printf("Bringing %s back online before switching traffic to it..", green(targetDeploymentName))
+	if err := r.restoreOfflineDeployment(targetDeploymentName); err != nil {
+		return err
+	}
+
+	if err := r.rollbackToRevision(serviceReleaseName, targetRelease.Version); err != nil {
+		return runtime.RollbackFailedError(err)
+	}
+	r.report.LiveColourAfter = targetColour
+	r.logger.Printf("The service is now routing traffic to %s!", green(targetDeploymentName))
+
+	return r.retireOfflineDeployment(targetEnv, appName, keepWarmReplicas)
+}
+
+func (r *run) rollbackRelease(releaseName string, revision int) error {
+	targetRelease, err := r.rollbackTarget(releaseName, revision)
+	if err != nil {
+		return err
+	}
+	return runtime.RollbackFailedError(r.rollbackToRevision(releaseName, targetRelease.Version))
+}
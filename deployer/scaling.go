@@ -0,0 +1,222 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/k8s"
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+
+	"helm.sh/helm/v3/pkg/action"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+)
+
+func (r *run) retireOfflineDeployment(targetEnv, appName string, keepWarmReplicas int32) error {
+	r.logger.Printf("To reduce costing, number of pods in offline deployments will now be scaled to %d.", keepWarmReplicas)
+	currentOfflineColour, err := r.determineDeployColour(targetEnv, appName)
+	if err != nil {
+		return err
+	}
+	r.logger.Printf("Offline colour is %s", currentOfflineColour)
+
+	offlineDeploymentName := deployment.BlueGreenDeploymentName(targetEnv, currentOfflineColour, appName)
+	r.scaleDownDeployment(offlineDeploymentName, keepWarmReplicas)
+	r.logPhase(Phase{Event: PHASE_OFFLINE_SCALED, Release: offlineDeploymentName, Colour: currentOfflineColour})
+	return nil
+}
+
+func (r *run) restoreOfflineDeployment(deploymentName string) error {
+	r.logger.Printf("Scaling %s up to a minimum of 1..", green(deploymentName))
+	if err := r.scaleReplicaSet(deploymentName, 1); err != nil {
+		return runtime.ClusterError(fmt.Errorf("Failed to scale %s: %v", deploymentName, err))
+	}
+	if err := r.waitForReadyReplicas(deploymentName, 1); err != nil {
+		return runtime.DeployFailedError(fmt.Errorf("%s did not become ready: %s", deploymentName, err))
+	}
+
+	r.logger.Printf("Restoring the Horizontal Pod Autoscaler of %s..", green(deploymentName))
+	return runtime.ClusterError(r.restoreHPA(deploymentName))
+}
+
+func (r *run) scaleDownDeployment(deploymentName string, replicas int32) {
+	r.removeHPA(deploymentName)
+
+	r.logger.Printf("Now updating the %s replica set to %d.", green(deploymentName), replicas)
+	scaleReplicaSetResult := r.scaleReplicaSet(deploymentName, replicas)
+	if scaleReplicaSetResult != nil {
+		r.logger.Printf("Failed to scale replica set HPA: %v", scaleReplicaSetResult)
+		r.logger.Println("This can happen if this is a  first deployment; skipping.")
+	}
+}
+
+// removeHPA snapshots and deletes the HPA of deploymentName, so that it can
+// be scaled by hand and the HPA restored later.
+func (r *run) removeHPA(deploymentName string) {
+	hpaName := deployment.HPAName(deploymentName)
+
+	r.logger.Printf("Snapshotting the Horizontal Pod Autoscaler (%s) so it can be restored when %s goes live again..", hpaName, green(deploymentName))
+	snapshotResult := r.snapshotHPA(hpaName, deployment.HPASnapshotName(deploymentName))
+	if snapshotResult != nil {
+		r.logger.Printf("Failed to snapshot HPA: %v", snapshotResult)
+		r.logger.Println("It will be restored from the release manifest instead.")
+	}
+
+	r.logger.Printf("We will first remove the Horizontal Pod Autoscaler (%s) from %s.", hpaName, green(deploymentName))
+	deletionResult := r.deleteHPA(hpaName)
+	if deletionResult != nil {
+		r.logger.Printf("Failed to delete HPA: %v", deletionResult)
+		r.logger.Println("This can happen if this is a  first deployment; skipping.")
+	}
+}
+
+// restoreHPA recreates the HPA of deploymentName from its snapshot, or from
+// its release manifest without one, then deletes the snapshot.
+func (r *run) restoreHPA(deploymentName string) error {
+	hpaName := deployment.HPAName(deploymentName)
+	snapshotName := deployment.HPASnapshotName(deploymentName)
+
+	r.logger.Printf("Looking for HPA snapshot %s..", snapshotName)
+	hpa, err := r.getHPASnapshot(snapshotName)
+	if err != nil {
+		r.logger.Printf("No usable HPA snapshot (%v), falling back to the %s release manifest..", err, green(deploymentName))
+		deploymentRelease, err := action.NewGet(r.helmConfig).Run(deploymentName)
+		if err != nil {
+			return err
+		}
+		hpa, err = k8s.FindHPAInManifest(deploymentRelease.Manifest, hpaName)
+		if err != nil {
+			return err
+		}
+	}
+	if err := r.createHPA(hpa); err != nil {
+		return err
+	}
+	r.deleteHPASnapshot(snapshotName)
+	return nil
+}
+
+func (r *run) deleteHPA(offlineHPAName string) error {
+	deletionError := k8s.DeleteHPA(r.ctx, r.kube, r.namespace, offlineHPAName)
+	if deletionError != nil {
+		r.logger.Printf("Error deleting HPA (%s): %v", offlineHPAName, deletionError)
+		return deletionError
+	}
+	r.logger.Printf("Success! Removed the HPA (%s).", offlineHPAName)
+	return nil
+}
+
+func (r *run) snapshotHPA(hpaName, snapshotName string) error {
+	hpa, getError := k8s.GetHPA(r.ctx, r.kube, r.namespace, hpaName)
+	if k8serrors.IsNotFound(getError) {
+		r.logger.Printf("No HPA (%s) to snapshot, skipping.", hpaName)
+		return nil
+	}
+	if getError != nil {
+		return getError
+	}
+
+	snapshot, err := k8s.HPASnapshotConfigMap(snapshotName, hpa)
+	if err != nil {
+		return err
+	}
+
+	configMapClient := r.kube.CoreV1().ConfigMaps(r.namespace)
+	_, err = configMapClient.Create(r.ctx, snapshot, metav1.CreateOptions{})
+	if k8serrors.IsAlreadyExists(err) {
+		_, err = configMapClient.Update(r.ctx, snapshot, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		r.logger.Printf("Error snapshotting HPA (%s) to %s: %v", hpaName, snapshotName, err)
+		return err
+	}
+	r.logger.Printf("Success! Snapshotted the HPA (%s) to %s.", hpaName, snapshotName)
+	return nil
+}
+
+func (r *run) getHPASnapshot(snapshotName string) (*unstructured.Unstructured, error) {
+	snapshot, err := r.kube.CoreV1().ConfigMaps(r.namespace).Get(r.ctx, snapshotName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return k8s.HPAFromSnapshot(snapshot)
+}
+
+// deleteHPASnapshot deletes a restored HPA's snapshot, so that a stale one
+// isn't restored should the HPA later go offline without being snapshotted.
+func (r *run) deleteHPASnapshot(snapshotName string) {
+	err := r.kube.CoreV1().ConfigMaps(r.namespace).Delete(r.ctx, snapshotName, metav1.DeleteOptions{})
+	if k8serrors.IsNotFound(err) {
+		return
+	}
+	if err != nil {
+		r.logger.Printf("Error deleting HPA snapshot %s: %v", snapshotName, err)
+		return
+	}
+	r.logger.Printf("Removed HPA snapshot %s.", snapshotName)
+}
+
+func (r *run) createHPA(hpa *unstructured.Unstructured) error {
+	hpa.SetResourceVersion("")
+	creationError := k8s.CreateHPA(r.ctx, r.kube, r.namespace, hpa)
+	if k8serrors.IsAlreadyExists(creationError) {
+		r.logger.Printf("HPA (%s) already exists, nothing to do.", hpa.GetName())
+		return nil
+	}
+	if creationError != nil {
+		r.logger.Printf("Error creating HPA (%s): %v", hpa.GetName(), creationError)
+		return creationError
+	}
+	r.logger.Printf("Success! Restored the HPA (%s).", hpa.GetName())
+	return nil
+}
+
+func (r *run) waitForReadyReplicas(deploymentName string, minReady int32) error {
+	deploymentsClient := r.kube.AppsV1().Deployments(r.namespace)
+	r.logger.Printf("Waiting up to %s for %s to have %d ready replica(s)..", r.scaleUpTimeout, green(deploymentName), minReady)
+	ctx, cancel := context.WithTimeout(r.ctx, r.scaleUpTimeout)
+	defer cancel()
+	return wait.PollImmediateUntil(5*time.Second, func() (bool, error) {
+		result, err := deploymentsClient.Get(ctx, deploymentName, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		return result.Status.ReadyReplicas >= minReady, nil
+	}, ctx.Done())
+}
+
+func (r *run) scaleReplicaSet(offlineDeploymentName string, scaleSize int32) error {
+	deploymentsClient := r.kube.AppsV1().Deployments(r.namespace)
+	retryErr := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		// Retrieve the latest version of Deployment before attempting update
+		// RetryOnConflict uses exponential backoff to avoid exhausting the apiserver
+		result, getErr := deploymentsClient.Get(r.ctx, offlineDeploymentName, metav1.GetOptions{})
+		if getErr != nil {
+			return fmt.Errorf("Failed to get latest version of Deployment: %v", getErr)
+		}
+
+		var numberOfReplicas int32 = scaleSize
+		result.Spec.Replicas = &numberOfReplicas
+
+		_, updateErr := deploymentsClient.Update(r.ctx, result, metav1.UpdateOptions{})
+		return updateErr
+	})
+
+	return retryErr
+}
+
+func (r *run) deploymentReplicas(deploymentName string) (int32, error) {
+	dep, err := r.kube.AppsV1().Deployments(r.namespace).Get(r.ctx, deploymentName, metav1.GetOptions{})
+	if err != nil {
+		return 0, runtime.ClusterError(fmt.Errorf("Failed to get %s: %s", deploymentName, err))
+	}
+	if dep.Spec.Replicas == nil || *dep.Spec.Replicas < 1 {
+		return 1, nil
+	}
+	return *dep.Spec.Replicas, nil
+}
@@ -0,0 +1,40 @@
+package deployer
+
+import (
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+)
+
+func (r *run) deployStandardChart(spec Spec) error {
+	if err := r.prepareDependencies(spec); err != nil {
+		return err
+	}
+
+	r.logger.Println("Loading chart values..")
+	chartValuesYaml, err := r.loadChartValues(spec)
+	if err != nil {
+		return err
+	}
+	r.logger.Println("Successfully loaded chart values")
+
+	deploymentName := deployment.StandardChartDeploymentName(spec.TargetEnv, spec.AppName)
+	if err := r.validateReleaseValues(chartValuesYaml, spec.ChartDir, []releaseEdits{{deploymentName, [][]interface{}{}}}); err != nil {
+		return err
+	}
+
+	r.logger.Printf("Preparing to deploy %s..", green(deploymentName))
+	deployedRelease, changed, err := r.releaseWithValues(
+		deploymentName,
+		chartValuesYaml,
+		[][]interface{}{},
+		spec.ChartDir)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return r.reportUnchanged(deploymentName, deployedRelease, spec.FailOnNoChange)
+	}
+	r.logger.Printf("Successfully deployed %s, the service is now live!", green(deploymentName))
+	r.printRelease(deployedRelease)
+	r.logPhase(Phase{Event: PHASE_RELEASE_DEPLOYED, Release: deploymentName, Revision: deployedRelease.Version})
+	return nil
+}
@@ -0,0 +1,84 @@
+package deployer
+
+import (
+	"fmt"
+
+	"github.com/Hutchison-Technologies/helm-deployer/deployment"
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
+)
+
+func (r *run) swap(spec Spec) error {
+	if err := r.prepareDependencies(spec); err != nil {
+		return err
+	}
+
+	r.logger.Println("Asserting that this is a bluegreen microservice chart..")
+	if err := r.assertChartIsBlueGreen(spec.ChartDir); err != nil {
+		return err
+	}
+	r.logger.Println("This is a bluegreen microservice chart!")
+
+	r.logger.Println("Loading chart values..")
+	chartValuesYaml, err := r.loadChartValues(spec)
+	if err != nil {
+		return err
+	}
+	r.logger.Println("Successfully loaded chart values")
+
+	serviceReleaseName := deployment.ServiceReleaseName(spec.TargetEnv, spec.AppName)
+	serviceReleases := []releaseEdits{}
+	for _, colour := range colours {
+		serviceReleases = append(serviceReleases, releaseEdits{serviceReleaseName, deployment.ChartValuesForServiceRelease(colour)})
+	}
+	if err := r.validateReleaseValues(chartValuesYaml, spec.ChartDir, serviceReleases); err != nil {
+		return err
+	}
+
+	r.logger.Println("Determining live colour..")
+	liveColour, err := r.determineLiveColour(spec.TargetEnv, spec.AppName)
+	if err != nil {
+		return err
+	}
+	r.logger.Printf("Determined live colour: %s", green(liveColour))
+	r.report.LiveColourBefore = liveColour
+	r.report.LiveColourAfter = liveColour
+
+	r.logger.Println("Determining offline colour..")
+	targetColour, err := r.determineDeployColour(spec.TargetEnv, spec.AppName)
+	if err != nil {
+		return err
+	}
+	r.logger.Printf("Determined offline colour: %s", green(targetColour))
+	r.logPhase(Phase{Event: PHASE_COLOUR_DETERMINED, Colour: targetColour})
+	if targetColour == liveColour {
+		return runtime.ValidationError(fmt.Errorf("Live and offline services both select %s, refusing to swap", green(liveColour)))
+	}
+
+	targetDeploymentName := deployment.BlueGreenDeploymentName(spec.TargetEnv, targetColour, spec.AppName)
+	r.logger.Printf("Bringing %s back online..", green(targetDeploymentName))
+	if err := r.restoreOfflineDeployment(targetDeploymentName); err != nil {
+		return err
+	}
+	r.logger.Printf("%s is ready to receive traffic", green(targetDeploymentName))
+
+	r.logger.Printf("Switching %s from %s to %s..", green(serviceReleaseName), green(liveColour), green(targetColour))
+	swappedServiceRelease, _, err := r.releaseWithValues(
+		serviceReleaseName,
+		chartValuesYaml,
+		deployment.ChartValuesForServiceRelease(targetColour),
+		spec.ChartDir)
+	if err != nil {
+		return err
+	}
+	r.logger.Printf("Successfully swapped %s, the service is now live!", green(targetDeploymentName))
+	r.printRelease(swappedServiceRelease)
+	r.logPhase(Phase{Event: PHASE_SERVICE_SWITCHED, Release: serviceReleaseName, Revision: swappedServiceRelease.Version, Colour: targetColour})
+	r.report.LiveColourAfter = targetColour
+
+	if err := r.retireOfflineDeployment(spec.TargetEnv, spec.AppName, spec.KeepWarm); err != nil {
+		return err
+	}
+	r.logger.Println("Swap complete!")
+
+	return nil
+}
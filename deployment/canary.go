@@ -0,0 +1,54 @@
+package deployment
+
+import (
+	"strconv"
+	"strings"
+)
+
+const DEFAULT_CANARY_STEPS = "10,25,50,100"
+
+// CanarySteps parses a comma-separated list of traffic weights, returning nil
+// unless they are percentages that rise to 100.
+func CanarySteps(steps string) []int {
+	weights := make([]int, 0)
+	for _, step := range strings.Split(steps, ",") {
+		weight, err := strconv.Atoi(strings.TrimSpace(step))
+		if err != nil || weight < 1 || weight > 100 {
+			return nil
+		}
+		if len(weights) > 0 && weight <= weights[len(weights)-1] {
+			return nil
+		}
+		weights = append(weights, weight)
+	}
+	if weights[len(weights)-1] != 100 {
+		return nil
+	}
+	return weights
+}
+
+func IsValidCanarySteps(steps string) bool {
+	return CanarySteps(steps) != nil
+}
+
+// CanaryReplicas splits total replicas between the canary and stable
+// deployments so that the canary receives roughly weight percent of the
+// traffic. The canary always has at least one replica, and the stable
+// deployment keeps at least one until the canary takes all of the traffic.
+func CanaryReplicas(total int32, weight int) (canary, stable int32) {
+	if total < 1 {
+		total = 1
+	}
+	if weight >= 100 {
+		return total, 0
+	}
+	canary = (total*int32(weight) + 99) / 100
+	if canary < 1 {
+		canary = 1
+	}
+	stable = total - canary
+	if stable < 1 {
+		stable = 1
+	}
+	return canary, stable
+}
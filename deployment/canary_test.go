@@ -0,0 +1,52 @@
+package deployment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CanarySteps_Returns_Nil_When_Given_Invalid_Steps(t *testing.T) {
+	assert.Nil(t, CanarySteps(""))
+	assert.Nil(t, CanarySteps("10,twenty,100"))
+	assert.Nil(t, CanarySteps("0,100"))
+	assert.Nil(t, CanarySteps("10,150"))
+	assert.Nil(t, CanarySteps("50,25,100"))
+	assert.Nil(t, CanarySteps("10,25,50"))
+}
+
+func Test_CanarySteps_Returns_Weights_When_Given_Valid_Steps(t *testing.T) {
+	assert.Equal(t, []int{10, 25, 50, 100}, CanarySteps(DEFAULT_CANARY_STEPS))
+	assert.Equal(t, []int{100}, CanarySteps("100"))
+	assert.Equal(t, []int{5, 100}, CanarySteps("5, 100"))
+}
+
+func Test_CanaryReplicas_Splits_Replicas_By_Weight(t *testing.T) {
+	canary, stable := CanaryReplicas(10, 25)
+	assert.Equal(t, int32(3), canary)
+	assert.Equal(t, int32(7), stable)
+
+	canary, stable = CanaryReplicas(4, 50)
+	assert.Equal(t, int32(2), canary)
+	assert.Equal(t, int32(2), stable)
+}
+
+func Test_CanaryReplicas_Keeps_One_Of_Each_Until_100(t *testing.T) {
+	canary, stable := CanaryReplicas(10, 1)
+	assert.Equal(t, int32(1), canary)
+	assert.Equal(t, int32(9), stable)
+
+	canary, stable = CanaryReplicas(2, 90)
+	assert.Equal(t, int32(2), canary)
+	assert.Equal(t, int32(1), stable)
+
+	canary, stable = CanaryReplicas(0, 10)
+	assert.Equal(t, int32(1), canary)
+	assert.Equal(t, int32(1), stable)
+}
+
+func Test_CanaryReplicas_Moves_Everything_At_100(t *testing.T) {
+	canary, stable := CanaryReplicas(6, 100)
+	assert.Equal(t, int32(6), canary)
+	assert.Equal(t, int32(0), stable)
+}
@@ -1,7 +1,9 @@
 package deployment
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/Hutchison-Technologies/helm-deployer/k8s"
 	corev1 "k8s.io/api/core/v1"
@@ -15,26 +17,43 @@ type list struct {
 	INSTALL                 alias
 	UPGRADE_WITH_DIFF_CHECK alias
 	UPGRADE                 alias
+	RECOVER_PENDING         alias
 }
 
 var ReleaseCourse = &list{
 	INSTALL:                 0,
 	UPGRADE_WITH_DIFF_CHECK: 1,
 	UPGRADE:                 2,
+	RECOVER_PENDING:         3,
 }
 
-func GetOfflineService(kubeClient v1.CoreV1Interface, targetEnv, appName string) (*corev1.Service, error) {
+func GetOfflineService(ctx context.Context, kubeClient v1.CoreV1Interface, namespace, targetEnv, appName string) (*corev1.Service, error) {
 	offlineServiceName := OfflineServiceName(targetEnv, appName)
-	service, err := k8s.GetService(kubeClient, offlineServiceName)
+	service, err := k8s.GetService(ctx, kubeClient, namespace, offlineServiceName)
 	if err != nil {
 		return nil, fmt.Errorf("Error looking for offline service: %s", err)
 	}
 	return service, nil
 }
 
-func DetermineReleaseCourse(releaseName string, statusCode release.Status, err error) int {
+func GetLiveService(ctx context.Context, kubeClient v1.CoreV1Interface, namespace, targetEnv, appName string) (*corev1.Service, error) {
+	liveServiceName := LiveServiceName(targetEnv, appName)
+	service, err := k8s.GetService(ctx, kubeClient, namespace, liveServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("Error looking for live service: %s", err)
+	}
+	return service, nil
+}
+
+// DetermineReleaseCourse decides how to deploy over an existing release. A
+// release that has been pending for longer than stuckAfter is taken to have
+// been left behind by a deploy that never finished, and is recovered first,
+// unless stuckAfter is 0.
+func DetermineReleaseCourse(releaseName string, statusCode release.Status, err error, pendingFor, stuckAfter time.Duration) int {
 	if err != nil && statusCode == release.StatusUnknown {
 		return ReleaseCourse.INSTALL
+	} else if statusCode.IsPending() && stuckAfter > 0 && pendingFor > stuckAfter {
+		return ReleaseCourse.RECOVER_PENDING
 	} else if statusCode == release.StatusUninstalled {
 		return ReleaseCourse.UPGRADE
 	} else {
@@ -62,3 +81,17 @@ func ChartValuesForMicroserviceDeployment(appVersion string) [][]interface{} {
 		[]interface{}{"microservice", "deployment", "version", appVersion},
 	}
 }
+
+func ServiceReleaseColour(releaseValues map[string]interface{}) string {
+	route := []string{"bluegreen", "service", "selector", "colour"}
+	var node interface{} = releaseValues
+	for _, key := range route {
+		asMap, ok := node.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		node = asMap[key]
+	}
+	colour, _ := node.(string)
+	return colour
+}
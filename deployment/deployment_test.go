@@ -3,28 +3,42 @@ package deployment
 import (
 	"github.com/stretchr/testify/assert"
 	"helm.sh/helm/v3/pkg/release"
-	storageerrors "k8s.io/helm/pkg/storage/errors"
+	"helm.sh/helm/v3/pkg/storage/driver"
 	"testing"
+	"time"
 )
 
 func Test_DetermineReleaseCourse_Returns_INSTALL_When_Error_Contains_Not_Found_Error(t *testing.T) {
 	releaseName := "best-api"
-	assert.Equal(t, ReleaseCourse.INSTALL, DetermineReleaseCourse(releaseName, release.Status_DEPLOYED, storageerrors.ErrReleaseNotFound(releaseName)))
+	assert.Equal(t, ReleaseCourse.INSTALL, DetermineReleaseCourse(releaseName, release.StatusUnknown, driver.ErrReleaseNotFound, 0, 0))
 }
 
 func Test_DetermineReleaseCourse_Returns_UPGRADE_WITH_DIFF_CHECK_When_Error_Is_Nil_And_Status_Code_Is_Not_DELETED(t *testing.T) {
-	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.Status_DEPLOYED, nil))
-	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.Status_DELETING, nil))
-	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.Status_FAILED, nil))
-	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.Status_UNKNOWN, nil))
-	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.Status_SUPERSEDED, nil))
-	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.Status_PENDING_INSTALL, nil))
-	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.Status_PENDING_ROLLBACK, nil))
-	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.Status_PENDING_UPGRADE, nil))
+	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.StatusDeployed, nil, 0, 0))
+	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.StatusUninstalling, nil, 0, 0))
+	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.StatusFailed, nil, 0, 0))
+	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.StatusUnknown, nil, 0, 0))
+	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.StatusSuperseded, nil, 0, 0))
+	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.StatusPendingInstall, nil, 0, 0))
+	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.StatusPendingRollback, nil, 0, 0))
+	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.StatusPendingUpgrade, nil, 0, 0))
+}
+
+func Test_DetermineReleaseCourse_Returns_RECOVER_PENDING_When_Pending_For_Longer_Than_Stuck_After(t *testing.T) {
+	assert.Equal(t, ReleaseCourse.RECOVER_PENDING, DetermineReleaseCourse("best-api", release.StatusPendingInstall, nil, time.Hour, 30*time.Minute))
+	assert.Equal(t, ReleaseCourse.RECOVER_PENDING, DetermineReleaseCourse("best-api", release.StatusPendingUpgrade, nil, time.Hour, 30*time.Minute))
+	assert.Equal(t, ReleaseCourse.RECOVER_PENDING, DetermineReleaseCourse("best-api", release.StatusPendingRollback, nil, time.Hour, 30*time.Minute))
+}
+
+func Test_DetermineReleaseCourse_Leaves_Recent_Or_Settled_Releases_Alone(t *testing.T) {
+	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.StatusPendingUpgrade, nil, time.Minute, 30*time.Minute))
+	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.StatusPendingUpgrade, nil, time.Hour, 0))
+	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.StatusFailed, nil, time.Hour, 30*time.Minute))
+	assert.Equal(t, ReleaseCourse.UPGRADE_WITH_DIFF_CHECK, DetermineReleaseCourse("best-api", release.StatusDeployed, nil, time.Hour, 30*time.Minute))
 }
 
 func Test_DetermineReleaseCourse_Returns_UPGRADE_When_Error_Is_Nil_And_Status_Code_Is_DELETED(t *testing.T) {
-	assert.Equal(t, ReleaseCourse.UPGRADE, DetermineReleaseCourse("best-api", release.Status_DELETED, nil))
+	assert.Equal(t, ReleaseCourse.UPGRADE, DetermineReleaseCourse("best-api", release.StatusUninstalled, nil, 0, 0))
 }
 
 func Test_ChartValuesForDeployment_Returns_Correct_Nested_Interface_Array(t *testing.T) {
@@ -43,3 +57,27 @@ func Test_ChartValuesForServiceRelease_Returns_Correct_Nested_Interface_Array(t
 		[]interface{}{"bluegreen", "service", "selector", "colour", deployColour},
 	}, ChartValuesForServiceRelease(deployColour))
 }
+
+func Test_ServiceReleaseColour_Returns_Empty_String_When_Given_Nil(t *testing.T) {
+	assert.Equal(t, "", ServiceReleaseColour(nil))
+}
+
+func Test_ServiceReleaseColour_Returns_Empty_String_When_Selector_Missing(t *testing.T) {
+	assert.Equal(t, "", ServiceReleaseColour(map[string]interface{}{
+		"bluegreen": map[string]interface{}{
+			"service": map[string]interface{}{},
+		},
+	}))
+}
+
+func Test_ServiceReleaseColour_Returns_Selector_Colour(t *testing.T) {
+	assert.Equal(t, "green", ServiceReleaseColour(map[string]interface{}{
+		"bluegreen": map[string]interface{}{
+			"service": map[string]interface{}{
+				"selector": map[string]interface{}{
+					"colour": "green",
+				},
+			},
+		},
+	}))
+}
@@ -12,6 +12,10 @@ func StandardChartDeploymentName(targetEnv, appName string) string {
 	return fmt.Sprintf("%s-%s", targetEnv, appName)
 }
 
+func LiveServiceName(targetEnv, appName string) string {
+	return fmt.Sprintf("%s-%s", targetEnv, appName)
+}
+
 func OfflineServiceName(targetEnv, appName string) string {
 	return fmt.Sprintf("%s-%s-offline", targetEnv, appName)
 }
@@ -19,3 +23,11 @@ func OfflineServiceName(targetEnv, appName string) string {
 func ServiceReleaseName(targetEnv, appName string) string {
 	return fmt.Sprintf("%s-service-%s", targetEnv, appName)
 }
+
+func HPAName(deploymentName string) string {
+	return fmt.Sprintf("%s-hpa", deploymentName)
+}
+
+func HPASnapshotName(deploymentName string) string {
+	return fmt.Sprintf("%s-hpa-snapshot", deploymentName)
+}
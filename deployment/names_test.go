@@ -59,6 +59,14 @@ func Test_ServiceReleaseName_Returns_Name_Containing_Service(t *testing.T) {
 	assert.Regexp(t, regexp.MustCompile(".*-service-.*"), ServiceReleaseName("prod", "some-api"))
 }
 
+func Test_LiveServiceName_Returns_Valid_AppName(t *testing.T) {
+	assert.True(t, IsValidAppName(LiveServiceName("prod", "some-api")))
+}
+
+func Test_LiveServiceName_Returns_Name_Prefixed_With_TargetEnv_And_Affixed_With_AppName(t *testing.T) {
+	assert.Equal(t, "prod-some-api", LiveServiceName("prod", "some-api"))
+}
+
 func Test_OfflineServiceName_Returns_Valid_AppName(t *testing.T) {
 	assert.True(t, IsValidAppName(OfflineServiceName("prod", "some-api")))
 }
@@ -76,3 +84,15 @@ func Test_OfflineServiceName_Returns_Name_Containing_AppName(t *testing.T) {
 func Test_OfflineServiceName_Returns_Name_Affixed_With_Offline(t *testing.T) {
 	assert.Regexp(t, regexp.MustCompile(".*-offline$"), OfflineServiceName("prod", "some-api"))
 }
+
+func Test_HPAName_Returns_Name_Affixed_With_Hpa(t *testing.T) {
+	assert.Equal(t, "prod-blue-some-api-hpa", HPAName("prod-blue-some-api"))
+}
+
+func Test_HPASnapshotName_Returns_Valid_AppName(t *testing.T) {
+	assert.True(t, IsValidAppName(HPASnapshotName("prod-blue-some-api")))
+}
+
+func Test_HPASnapshotName_Returns_Name_Affixed_With_Hpa_Snapshot(t *testing.T) {
+	assert.Equal(t, "prod-blue-some-api-hpa-snapshot", HPASnapshotName("prod-blue-some-api"))
+}
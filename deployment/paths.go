@@ -7,3 +7,11 @@ import (
 func ChartValuesPath(chartDir, targetEnv string) string {
 	return fmt.Sprintf("%s/%s.yaml", chartDir, targetEnv)
 }
+
+func ChartDefaultValuesPath(chartDir string) string {
+	return fmt.Sprintf("%s/values.yaml", chartDir)
+}
+
+func ChartCommonValuesPath(chartDir string) string {
+	return fmt.Sprintf("%s/common.yaml", chartDir)
+}
@@ -20,3 +20,11 @@ func Test_ChartValuesPath_Returns_Path_To_TargetEnv_Yaml_File(t *testing.T) {
 	targetEnv := "yayForDeployments"
 	assert.Regexp(t, regexp.MustCompile(fmt.Sprintf("^.*/%s.yaml$", targetEnv)), ChartValuesPath("/some/dir", targetEnv))
 }
+
+func Test_ChartDefaultValuesPath_Returns_Path_To_Values_Yaml_File(t *testing.T) {
+	assert.Equal(t, "/some/dir/values.yaml", ChartDefaultValuesPath("/some/dir"))
+}
+
+func Test_ChartCommonValuesPath_Returns_Path_To_Common_Yaml_File(t *testing.T) {
+	assert.Equal(t, "/some/dir/common.yaml", ChartCommonValuesPath("/some/dir"))
+}
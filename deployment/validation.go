@@ -1,7 +1,12 @@
 package deployment
 
 import (
+	"net/url"
 	"regexp"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v3/pkg/strvals"
 )
 
 func IsValidAppName(appName string) bool {
@@ -13,5 +18,53 @@ func IsValidAppVersion(appName string) bool {
 }
 
 func IsValidTargetEnv(targetEnv string) bool {
-	return targetEnv == "prod" || targetEnv == "staging"
+	return len(targetEnv) < 32 && regexp.MustCompile(`^[a-z][a-z0-9-]*$`).MatchString(targetEnv)
+}
+
+func IsValidNamespace(namespace string) bool {
+	return len(namespace) < 64 && regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`).MatchString(namespace)
+}
+
+func IsValidRevision(revision string) bool {
+	return regexp.MustCompile(`^[1-9][0-9]*$`).MatchString(revision)
+}
+
+func IsValidColour(colour string) bool {
+	return colour == "blue" || colour == "green"
+}
+
+func IsValidBoolean(value string) bool {
+	return value == "true" || value == "false"
+}
+
+func IsValidCount(count string) bool {
+	return regexp.MustCompile(`^(0|[1-9][0-9]*)$`).MatchString(count)
+}
+
+func IsValidDuration(duration string) bool {
+	parsed, err := time.ParseDuration(duration)
+	return err == nil && parsed > 0
+}
+
+func IsValidKubeContext(kubeContext string) bool {
+	return len(kubeContext) < 254 && regexp.MustCompile(`^[^\s]+$`).MatchString(kubeContext)
+}
+
+func IsValidWebhookURLs(webhookURLs string) bool {
+	for _, webhookURL := range strings.Split(webhookURLs, ",") {
+		parsed, err := url.ParseRequestURI(webhookURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return false
+		}
+	}
+	return true
+}
+
+func IsValidValuesKey(key string) bool {
+	return regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`).MatchString(key)
+}
+
+func IsValidSetValues(setValues string) bool {
+	_, err := strvals.Parse(setValues)
+	return err == nil
 }
@@ -44,16 +44,152 @@ func Test_IsValidAppVersion_Returns_True_When_Given_Valid_AppVersion(t *testing.
 func Test_IsValidTargetEnv_Returns_False_When_Given_Invalid_TargetEnv(t *testing.T) {
 	assert.False(t, IsValidTargetEnv(""))
 	assert.False(t, IsValidTargetEnv(" "))
-	assert.False(t, IsValidTargetEnv("green"))
 	assert.False(t, IsValidTargetEnv("124"))
-	assert.False(t, IsValidTargetEnv("test"))
 	assert.False(t, IsValidTargetEnv("PROD"))
 	assert.False(t, IsValidTargetEnv("STAGING"))
-	assert.False(t, IsValidTargetEnv("production"))
-	assert.False(t, IsValidTargetEnv("stage"))
+	assert.False(t, IsValidTargetEnv("contains space"))
+	assert.False(t, IsValidTargetEnv("contains_underscore"))
+	assert.False(t, IsValidTargetEnv("-leading-dash"))
+	assert.False(t, IsValidTargetEnv("contains-more-than-32-characters-here"))
 }
 
 func Test_IsValidTargetEnv_Returns_True_When_Given_Valid_TargetEnv(t *testing.T) {
 	assert.True(t, IsValidTargetEnv("staging"))
 	assert.True(t, IsValidTargetEnv("prod"))
+	assert.True(t, IsValidTargetEnv("dev"))
+	assert.True(t, IsValidTargetEnv("uat"))
+	assert.True(t, IsValidTargetEnv("customer-a"))
+}
+
+func Test_IsValidNamespace_Returns_False_When_Given_Invalid_Namespace(t *testing.T) {
+	assert.False(t, IsValidNamespace(""))
+	assert.False(t, IsValidNamespace(" "))
+	assert.False(t, IsValidNamespace("Default"))
+	assert.False(t, IsValidNamespace("contains_underscore"))
+	assert.False(t, IsValidNamespace("-leading-dash"))
+	assert.False(t, IsValidNamespace("trailing-dash-"))
+	assert.False(t, IsValidNamespace("contains-more-than-64-characters-because-that-shit-dont-fly-here-son"))
+}
+
+func Test_IsValidNamespace_Returns_True_When_Given_Valid_Namespace(t *testing.T) {
+	assert.True(t, IsValidNamespace("default"))
+	assert.True(t, IsValidNamespace("kube-system"))
+	assert.True(t, IsValidNamespace("team-a-prod"))
+	assert.True(t, IsValidNamespace("123"))
+}
+
+func Test_IsValidRevision_Returns_False_When_Given_Invalid_Revision(t *testing.T) {
+	assert.False(t, IsValidRevision(""))
+	assert.False(t, IsValidRevision(" "))
+	assert.False(t, IsValidRevision("0"))
+	assert.False(t, IsValidRevision("-1"))
+	assert.False(t, IsValidRevision("01"))
+	assert.False(t, IsValidRevision("v1"))
+	assert.False(t, IsValidRevision("latest"))
+}
+
+func Test_IsValidRevision_Returns_True_When_Given_Valid_Revision(t *testing.T) {
+	assert.True(t, IsValidRevision("1"))
+	assert.True(t, IsValidRevision("10"))
+	assert.True(t, IsValidRevision("123"))
+}
+
+func Test_IsValidColour_Returns_False_When_Given_Invalid_Colour(t *testing.T) {
+	assert.False(t, IsValidColour(""))
+	assert.False(t, IsValidColour(" "))
+	assert.False(t, IsValidColour("red"))
+	assert.False(t, IsValidColour("BLUE"))
+	assert.False(t, IsValidColour("prod"))
+}
+
+func Test_IsValidColour_Returns_True_When_Given_Valid_Colour(t *testing.T) {
+	assert.True(t, IsValidColour("blue"))
+	assert.True(t, IsValidColour("green"))
+}
+
+func Test_IsValidBoolean_Returns_False_When_Given_Invalid_Boolean(t *testing.T) {
+	assert.False(t, IsValidBoolean(""))
+	assert.False(t, IsValidBoolean("yes"))
+	assert.False(t, IsValidBoolean("TRUE"))
+	assert.False(t, IsValidBoolean("1"))
+}
+
+func Test_IsValidBoolean_Returns_True_When_Given_Valid_Boolean(t *testing.T) {
+	assert.True(t, IsValidBoolean("true"))
+	assert.True(t, IsValidBoolean("false"))
+}
+
+func Test_IsValidCount_Returns_False_When_Given_Invalid_Count(t *testing.T) {
+	assert.False(t, IsValidCount(""))
+	assert.False(t, IsValidCount("-1"))
+	assert.False(t, IsValidCount("01"))
+	assert.False(t, IsValidCount("one"))
+}
+
+func Test_IsValidCount_Returns_True_When_Given_Valid_Count(t *testing.T) {
+	assert.True(t, IsValidCount("0"))
+	assert.True(t, IsValidCount("3"))
+	assert.True(t, IsValidCount("25"))
+}
+
+func Test_IsValidDuration_Returns_False_When_Given_Invalid_Duration(t *testing.T) {
+	assert.False(t, IsValidDuration(""))
+	assert.False(t, IsValidDuration("300"))
+	assert.False(t, IsValidDuration("soon"))
+	assert.False(t, IsValidDuration("0s"))
+	assert.False(t, IsValidDuration("-5m"))
+}
+
+func Test_IsValidDuration_Returns_True_When_Given_Valid_Duration(t *testing.T) {
+	assert.True(t, IsValidDuration("300s"))
+	assert.True(t, IsValidDuration("15m"))
+	assert.True(t, IsValidDuration("1h30m"))
+}
+
+func Test_IsValidKubeContext_Returns_False_When_Given_Invalid_Context(t *testing.T) {
+	assert.False(t, IsValidKubeContext(""))
+	assert.False(t, IsValidKubeContext("prod cluster"))
+}
+
+func Test_IsValidKubeContext_Returns_True_When_Given_Valid_Context(t *testing.T) {
+	assert.True(t, IsValidKubeContext("prod-cluster"))
+	assert.True(t, IsValidKubeContext("gke_project_europe-west2_prod"))
+	assert.True(t, IsValidKubeContext("arn:aws:eks:eu-west-2:123456789012:cluster/prod"))
+}
+
+func Test_IsValidWebhookURLs_Returns_False_When_Given_Invalid_URLs(t *testing.T) {
+	assert.False(t, IsValidWebhookURLs(""))
+	assert.False(t, IsValidWebhookURLs("hooks.example.com/deploys"))
+	assert.False(t, IsValidWebhookURLs("ftp://hooks.example.com/deploys"))
+	assert.False(t, IsValidWebhookURLs("https://hooks.example.com/deploys,"))
+}
+
+func Test_IsValidWebhookURLs_Returns_True_When_Given_Valid_URLs(t *testing.T) {
+	assert.True(t, IsValidWebhookURLs("https://hooks.example.com/deploys"))
+	assert.True(t, IsValidWebhookURLs("https://hooks.example.com/deploys,http://localhost:8080/hook"))
+}
+
+func Test_IsValidValuesKey_Returns_False_When_Given_Invalid_Key(t *testing.T) {
+	assert.False(t, IsValidValuesKey(""))
+	assert.False(t, IsValidValuesKey(".image"))
+	assert.False(t, IsValidValuesKey("bluegreen..image"))
+	assert.False(t, IsValidValuesKey("bluegreen.deployment image"))
+}
+
+func Test_IsValidValuesKey_Returns_True_When_Given_Valid_Key(t *testing.T) {
+	assert.True(t, IsValidValuesKey("replicas"))
+	assert.True(t, IsValidValuesKey("bluegreen.deployment.image"))
+	assert.True(t, IsValidValuesKey("cloud_sql.instance-name"))
+}
+
+func Test_IsValidSetValues_Returns_False_When_Given_Invalid_Values(t *testing.T) {
+	assert.False(t, IsValidSetValues("replicas"))
+	assert.False(t, IsValidSetValues("image.tag=v1,replicas"))
+	assert.False(t, IsValidSetValues("list[x]=1"))
+}
+
+func Test_IsValidSetValues_Returns_True_When_Given_Valid_Values(t *testing.T) {
+	assert.True(t, IsValidSetValues("replicas=2"))
+	assert.True(t, IsValidSetValues("image.tag=v1.2.3,ingress.enabled=true"))
+	assert.True(t, IsValidSetValues("hosts[0]=api.example.com"))
 }
@@ -55,6 +55,7 @@ replace k8s.io/sample-cli-plugin => k8s.io/sample-cli-plugin v0.21.0
 replace k8s.io/sample-controller => k8s.io/sample-controller v0.21.0
 
 require (
+	github.com/Masterminds/semver/v3 v3.1.1
 	github.com/databus23/helm-diff v3.1.1+incompatible
 	github.com/ghodss/yaml v1.0.0
 	github.com/golang/protobuf v1.5.1
@@ -62,10 +63,12 @@ require (
 	github.com/mgutz/ansi v0.0.0-20200706080929-d51e80ef957d // indirect
 	github.com/opencontainers/runc v1.0.0-rc10 // indirect
 	github.com/stretchr/testify v1.7.0
+	github.com/xeipuuv/gojsonschema v1.2.0
 	gopkg.in/yaml.v1 v1.0.0-20140924161607-9f9df34309c0
 	helm.sh/helm/v3 v3.6.3
 	k8s.io/api v0.21.0
 	k8s.io/apimachinery v0.21.0
+	k8s.io/cli-runtime v0.21.0
 	k8s.io/client-go v0.21.0
 	k8s.io/helm v2.17.0+incompatible
 	rsc.io/letsencrypt v0.0.3 // indirect
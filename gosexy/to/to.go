@@ -254,7 +254,6 @@ func Duration(val interface{}) time.Duration {
 	default:
 		return strToDuration(String(val))
 	}
-	panic("Reached")
 }
 
 /*
@@ -317,8 +316,6 @@ func Bytes(val interface{}) []byte {
 	default:
 		return []byte(fmt.Sprintf("%v", val))
 	}
-
-	panic("Reached.")
 }
 
 /*
@@ -478,9 +475,6 @@ func Int64(val interface{}) int64 {
 		i, _ := strconv.ParseInt(String(val), 10, 64)
 		return i
 	}
-
-	panic("Reached")
-
 }
 
 /*
@@ -523,9 +517,6 @@ func Uint64(val interface{}) uint64 {
 		i, _ := strconv.ParseUint(String(val), 10, 64)
 		return i
 	}
-
-	panic("Reached")
-
 }
 
 /*
@@ -59,6 +59,21 @@ func Open(file string) (*Yaml, error) {
 	return self, nil
 }
 
+/*
+	Creates and returns a YAML struct, from YAML bytes.
+*/
+func Parse(data []byte) (*Yaml, error) {
+	self := New()
+
+	err := yaml.Unmarshal(data, &self.values)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return self, nil
+}
+
 /*
 	Sets a YAML setting
 */
@@ -142,7 +157,13 @@ func (self *Yaml) Save() error {
 	} else {
 		return fmt.Errorf("No file specified.")
 	}
-	return nil
+}
+
+/*
+	Returns the current in-memory YAML struct as bytes, without touching disk.
+*/
+func (self *Yaml) Marshal() ([]byte, error) {
+	return yaml.Marshal(self.values)
 }
 
 /*
@@ -150,7 +171,7 @@ func (self *Yaml) Save() error {
 */
 func (self *Yaml) Write(filename string) error {
 
-	out, err := yaml.Marshal(self.values)
+	out, err := self.Marshal()
 
 	if err != nil {
 		return err
@@ -101,6 +101,24 @@ func TestWrite(t *testing.T) {
 	}
 }
 
+func TestMarshal(t *testing.T) {
+	settings := New()
+
+	settings.Set("test_map", "element_3", "test_bool", true)
+
+	out, err := settings.Marshal()
+
+	if err != nil {
+		t.Errorf("Test failed: %v", err.Error())
+	}
+
+	test1 := "test_map:\n  element_3:\n    test_bool: true\n"
+
+	if string(out) != test1 {
+		t.Errorf("Got %v expecting %v.", string(out), test1)
+	}
+}
+
 // Testing compat with deprecated calls
 
 func TestCompatGet(t *testing.T) {
@@ -28,3 +28,13 @@ func FilterReleasesByStatusCode(releases []*release.Release, code release.Status
 	}
 	return filtered
 }
+
+func FilterReleasesBeforeVersion(releases []*release.Release, version int) []*release.Release {
+	filtered := make([]*release.Release, 0)
+	for _, oldRelease := range releases {
+		if oldRelease.Version < version {
+			filtered = append(filtered, oldRelease)
+		}
+	}
+	return filtered
+}
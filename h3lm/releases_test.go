@@ -1,28 +1,30 @@
 package h3lm
 
 import (
-	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/stretchr/testify/assert"
 	"helm.sh/helm/v3/pkg/release"
+	helmtime "helm.sh/helm/v3/pkg/time"
 	"testing"
 )
 
 func makeReleaseWithCode(code release.Status) *release.Release {
 	return &release.Release{
 		Info: &release.Info{
-			Status: &release.Status{
-				Code: code,
-			},
+			Status: code,
 		},
 	}
 }
 
+func makeReleaseWithVersion(version int) *release.Release {
+	return &release.Release{
+		Version: version,
+	}
+}
+
 func makeReleaseWithTime(epoch int64) *release.Release {
 	return &release.Release{
 		Info: &release.Info{
-			LastDeployed: &timestamp.Timestamp{
-				Seconds: epoch,
-			},
+			LastDeployed: helmtime.Unix(epoch, 0),
 		},
 	}
 }
@@ -69,24 +71,24 @@ func Test_LatestRelease_Does_Not_Mutate_Input_Array(t *testing.T) {
 }
 
 func Test_FilterReleasesByStatusCode_Returns_Empty_Array_When_Given_Nil(t *testing.T) {
-	assert.Equal(t, make([]*release.Release, 0), FilterReleasesByStatusCode(nil, release.Status_DEPLOYED))
+	assert.Equal(t, make([]*release.Release, 0), FilterReleasesByStatusCode(nil, release.StatusDeployed))
 }
 
 func Test_FilterReleasesByStatusCode_Returns_Empty_Array_When_Given_Empty_Array(t *testing.T) {
-	assert.Equal(t, make([]*release.Release, 0), FilterReleasesByStatusCode(make([]*release.Release, 0), release.Status_DEPLOYED))
+	assert.Equal(t, make([]*release.Release, 0), FilterReleasesByStatusCode(make([]*release.Release, 0), release.StatusDeployed))
 }
 
 func Test_FilterReleasesByStatusCode_Returns_Empty_Array_When_None_Match(t *testing.T) {
 	input := []*release.Release{
-		makeReleaseWithCode(release.Status_DELETED),
-		makeReleaseWithCode(release.Status_DELETING),
-		makeReleaseWithCode(release.Status_FAILED),
+		makeReleaseWithCode(release.StatusUninstalled),
+		makeReleaseWithCode(release.StatusUninstalling),
+		makeReleaseWithCode(release.StatusFailed),
 	}
-	assert.Equal(t, make([]*release.Release, 0), FilterReleasesByStatusCode(input, release.Status_DEPLOYED))
+	assert.Equal(t, make([]*release.Release, 0), FilterReleasesByStatusCode(input, release.StatusDeployed))
 }
 
 func Test_FilterReleasesByStatusCode_Returns_Entire_Array_When_All_Match(t *testing.T) {
-	code := release.Status_DEPLOYED
+	code := release.StatusDeployed
 	input := []*release.Release{
 		makeReleaseWithCode(code),
 		makeReleaseWithCode(code),
@@ -96,14 +98,14 @@ func Test_FilterReleasesByStatusCode_Returns_Entire_Array_When_All_Match(t *test
 }
 
 func Test_FilterReleasesByStatusCode_Returns_Partial_Array_When_Some_Match(t *testing.T) {
-	code := release.Status_DEPLOYED
+	code := release.StatusDeployed
 	input := []*release.Release{
 		makeReleaseWithCode(code),
 		makeReleaseWithCode(code),
 		makeReleaseWithCode(code),
-		makeReleaseWithCode(release.Status_DELETED),
-		makeReleaseWithCode(release.Status_DELETING),
-		makeReleaseWithCode(release.Status_FAILED),
+		makeReleaseWithCode(release.StatusUninstalled),
+		makeReleaseWithCode(release.StatusUninstalling),
+		makeReleaseWithCode(release.StatusFailed),
 	}
 	assert.Equal(t, []*release.Release{
 		makeReleaseWithCode(code),
@@ -111,3 +113,20 @@ func Test_FilterReleasesByStatusCode_Returns_Partial_Array_When_Some_Match(t *te
 		makeReleaseWithCode(code),
 	}, FilterReleasesByStatusCode(input, code))
 }
+
+func Test_FilterReleasesBeforeVersion_Returns_Empty_Array_When_Given_Nil(t *testing.T) {
+	assert.Equal(t, make([]*release.Release, 0), FilterReleasesBeforeVersion(nil, 3))
+}
+
+func Test_FilterReleasesBeforeVersion_Returns_Only_Earlier_Versions(t *testing.T) {
+	input := []*release.Release{
+		makeReleaseWithVersion(1),
+		makeReleaseWithVersion(2),
+		makeReleaseWithVersion(3),
+		makeReleaseWithVersion(4),
+	}
+	assert.Equal(t, []*release.Release{
+		makeReleaseWithVersion(1),
+		makeReleaseWithVersion(2),
+	}, FilterReleasesBeforeVersion(input, 3))
+}
@@ -0,0 +1,54 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// EventTime returns when an event last happened, whichever of its timestamps
+// is set.
+func EventTime(event *corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	}
+	return event.FirstTimestamp.Time
+}
+
+// RecentEvents returns, oldest first, up to the limit most recent events about
+// the named objects.
+func RecentEvents(events []corev1.Event, objectNames []string, limit int) []corev1.Event {
+	names := make(map[string]bool)
+	for _, name := range objectNames {
+		names[name] = true
+	}
+	recent := make([]corev1.Event, 0)
+	for _, event := range events {
+		if names[event.InvolvedObject.Name] {
+			recent = append(recent, event)
+		}
+	}
+	sort.SliceStable(recent, func(i, j int) bool {
+		return EventTime(&recent[i]).Before(EventTime(&recent[j]))
+	})
+	if len(recent) > limit {
+		recent = recent[len(recent)-limit:]
+	}
+	return recent
+}
+
+func DescribeEvent(event *corev1.Event) string {
+	return fmt.Sprintf("%s %s %s/%s %s: %s (x%d)",
+		EventTime(event).UTC().Format(time.RFC3339),
+		event.Type,
+		event.InvolvedObject.Kind,
+		event.InvolvedObject.Name,
+		event.Reason,
+		event.Message,
+		event.Count)
+}
@@ -0,0 +1,55 @@
+package k8s
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func makeEvent(objectName, reason string, minutesAgo int) corev1.Event {
+	return corev1.Event{
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: objectName},
+		Reason:         reason,
+		Message:        "something happened",
+		Type:           "Warning",
+		Count:          1,
+		LastTimestamp:  metav1.NewTime(time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC).Add(-time.Duration(minutesAgo) * time.Minute)),
+	}
+}
+
+func Test_RecentEvents_Returns_Only_Events_About_The_Named_Objects(t *testing.T) {
+	events := RecentEvents([]corev1.Event{
+		makeEvent("pod-1", "BackOff", 1),
+		makeEvent("other-pod", "BackOff", 1),
+	}, []string{"pod-1"}, 10)
+	assert.Len(t, events, 1)
+	assert.Equal(t, "pod-1", events[0].InvolvedObject.Name)
+}
+
+func Test_RecentEvents_Returns_The_Most_Recent_Events_Oldest_First(t *testing.T) {
+	events := RecentEvents([]corev1.Event{
+		makeEvent("pod-1", "Pulled", 1),
+		makeEvent("pod-1", "Scheduled", 10),
+		makeEvent("pod-1", "BackOff", 0),
+	}, []string{"pod-1"}, 2)
+	assert.Len(t, events, 2)
+	assert.Equal(t, "Pulled", events[0].Reason)
+	assert.Equal(t, "BackOff", events[1].Reason)
+}
+
+func Test_EventTime_Falls_Back_To_EventTime_And_FirstTimestamp(t *testing.T) {
+	first := time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+	event := corev1.Event{FirstTimestamp: metav1.NewTime(first)}
+	assert.Equal(t, first, EventTime(&event))
+
+	event.EventTime = metav1.NewMicroTime(first.Add(time.Minute))
+	assert.Equal(t, first.Add(time.Minute), EventTime(&event))
+}
+
+func Test_DescribeEvent_Describes_The_Event(t *testing.T) {
+	event := makeEvent("pod-1", "BackOff", 0)
+	assert.Equal(t, "2021-08-01T12:00:00Z Warning Pod/pod-1 BackOff: something happened (x1)", DescribeEvent(&event))
+}
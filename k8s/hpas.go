@@ -0,0 +1,105 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	autoscalingv2beta1 "k8s.io/api/autoscaling/v2beta1"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	HPA_KIND                = "HorizontalPodAutoscaler"
+	HPA_API_VERSION_V1      = "autoscaling/v1"
+	HPA_API_VERSION_V2BETA1 = "autoscaling/v2beta1"
+	HPA_API_VERSION_V2BETA2 = "autoscaling/v2beta2"
+)
+
+// hpaAPIVersions are the autoscaling versions an HPA is read in, richest
+// first, as autoscaling/v1 drops the metrics and behavior of later versions.
+var hpaAPIVersions = []string{HPA_API_VERSION_V2BETA2, HPA_API_VERSION_V2BETA1, HPA_API_VERSION_V1}
+
+// GetHPA gets the HPA hpaName in the richest autoscaling version that serves
+// it.
+func GetHPA(ctx context.Context, kubeClient kubernetes.Interface, namespace, hpaName string) (*unstructured.Unstructured, error) {
+	var err error
+	for _, apiVersion := range hpaAPIVersions {
+		var hpa k8sruntime.Object
+		switch apiVersion {
+		case HPA_API_VERSION_V2BETA2:
+			hpa, err = kubeClient.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Get(ctx, hpaName, metav1.GetOptions{})
+		case HPA_API_VERSION_V2BETA1:
+			hpa, err = kubeClient.AutoscalingV2beta1().HorizontalPodAutoscalers(namespace).Get(ctx, hpaName, metav1.GetOptions{})
+		default:
+			hpa, err = kubeClient.AutoscalingV1().HorizontalPodAutoscalers(namespace).Get(ctx, hpaName, metav1.GetOptions{})
+		}
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		object, err := k8sruntime.DefaultUnstructuredConverter.ToUnstructured(hpa)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading HPA \033[32m%s\033[97m: %s", hpaName, err)
+		}
+		unstructuredHPA := &unstructured.Unstructured{Object: object}
+		unstructuredHPA.SetAPIVersion(apiVersion)
+		unstructuredHPA.SetKind(HPA_KIND)
+		return unstructuredHPA, nil
+	}
+	return nil, err
+}
+
+// CreateHPA creates hpa in the autoscaling version it declares.
+func CreateHPA(ctx context.Context, kubeClient kubernetes.Interface, namespace string, hpa *unstructured.Unstructured) error {
+	var err error
+	switch hpa.GetAPIVersion() {
+	case HPA_API_VERSION_V2BETA2:
+		typed := &autoscalingv2beta2.HorizontalPodAutoscaler{}
+		if err = k8sruntime.DefaultUnstructuredConverter.FromUnstructured(hpa.Object, typed); err == nil {
+			_, err = kubeClient.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Create(ctx, typed, metav1.CreateOptions{})
+		}
+	case HPA_API_VERSION_V2BETA1:
+		typed := &autoscalingv2beta1.HorizontalPodAutoscaler{}
+		if err = k8sruntime.DefaultUnstructuredConverter.FromUnstructured(hpa.Object, typed); err == nil {
+			_, err = kubeClient.AutoscalingV2beta1().HorizontalPodAutoscalers(namespace).Create(ctx, typed, metav1.CreateOptions{})
+		}
+	case HPA_API_VERSION_V1:
+		typed := &autoscalingv1.HorizontalPodAutoscaler{}
+		if err = k8sruntime.DefaultUnstructuredConverter.FromUnstructured(hpa.Object, typed); err == nil {
+			_, err = kubeClient.AutoscalingV1().HorizontalPodAutoscalers(namespace).Create(ctx, typed, metav1.CreateOptions{})
+		}
+	default:
+		err = fmt.Errorf("HPA \033[32m%s\033[97m is %s, which can't be created, must be one of %v", hpa.GetName(), hpa.GetAPIVersion(), hpaAPIVersions)
+	}
+	return err
+}
+
+// DeleteHPA deletes the HPA hpaName, in whichever autoscaling version serves
+// it.
+func DeleteHPA(ctx context.Context, kubeClient kubernetes.Interface, namespace, hpaName string) error {
+	deletePolicy := metav1.DeletePropagationBackground
+	options := metav1.DeleteOptions{PropagationPolicy: &deletePolicy}
+	var err error
+	for _, apiVersion := range hpaAPIVersions {
+		switch apiVersion {
+		case HPA_API_VERSION_V2BETA2:
+			err = kubeClient.AutoscalingV2beta2().HorizontalPodAutoscalers(namespace).Delete(ctx, hpaName, options)
+		case HPA_API_VERSION_V2BETA1:
+			err = kubeClient.AutoscalingV2beta1().HorizontalPodAutoscalers(namespace).Delete(ctx, hpaName, options)
+		default:
+			err = kubeClient.AutoscalingV1().HorizontalPodAutoscalers(namespace).Delete(ctx, hpaName, options)
+		}
+		if !k8serrors.IsNotFound(err) {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,69 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const TEST_HPA_NAMESPACE = "apps"
+
+func Test_GetHPA_Returns_The_HPA_In_The_Richest_Version_Serving_It(t *testing.T) {
+	typed := makeTypedHPA()
+	typed.Namespace = TEST_HPA_NAMESPACE
+	kubeClient := fake.NewSimpleClientset(typed)
+
+	hpa, err := GetHPA(context.TODO(), kubeClient, TEST_HPA_NAMESPACE, typed.GetName())
+	assert.Nil(t, err)
+	assert.Equal(t, HPA_API_VERSION_V2BETA2, hpa.GetAPIVersion())
+	assert.Equal(t, HPA_KIND, hpa.GetKind())
+	assert.Contains(t, hpa.Object["spec"], "behavior")
+}
+
+func Test_GetHPA_Falls_Back_To_autoscaling_v1(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod-blue-some-api-hpa", Namespace: TEST_HPA_NAMESPACE},
+	})
+
+	hpa, err := GetHPA(context.TODO(), kubeClient, TEST_HPA_NAMESPACE, "prod-blue-some-api-hpa")
+	assert.Nil(t, err)
+	assert.Equal(t, HPA_API_VERSION_V1, hpa.GetAPIVersion())
+}
+
+func Test_GetHPA_Returns_Not_Found_When_No_Version_Serves_It(t *testing.T) {
+	_, err := GetHPA(context.TODO(), fake.NewSimpleClientset(), TEST_HPA_NAMESPACE, "prod-blue-some-api-hpa")
+	assert.True(t, k8serrors.IsNotFound(err))
+}
+
+func Test_CreateHPA_Creates_The_HPA_In_Its_Declared_Version(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset()
+	hpa := makeHPA()
+	hpa.SetResourceVersion("")
+
+	assert.Nil(t, CreateHPA(context.TODO(), kubeClient, TEST_HPA_NAMESPACE, hpa))
+	created, err := kubeClient.AutoscalingV2beta2().HorizontalPodAutoscalers(TEST_HPA_NAMESPACE).Get(context.TODO(), hpa.GetName(), metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, makeTypedHPA().Spec, created.Spec)
+}
+
+func Test_CreateHPA_Returns_Error_For_An_Unknown_Version(t *testing.T) {
+	hpa := makeHPA()
+	hpa.SetAPIVersion("autoscaling/v3")
+	assert.NotNil(t, CreateHPA(context.TODO(), fake.NewSimpleClientset(), TEST_HPA_NAMESPACE, hpa))
+}
+
+func Test_DeleteHPA_Deletes_The_HPA_In_Whichever_Version_Serves_It(t *testing.T) {
+	kubeClient := fake.NewSimpleClientset(&autoscalingv1.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: "prod-blue-some-api-hpa", Namespace: TEST_HPA_NAMESPACE},
+	})
+
+	assert.Nil(t, DeleteHPA(context.TODO(), kubeClient, TEST_HPA_NAMESPACE, "prod-blue-some-api-hpa"))
+	_, err := GetHPA(context.TODO(), kubeClient, TEST_HPA_NAMESPACE, "prod-blue-some-api-hpa")
+	assert.True(t, k8serrors.IsNotFound(err))
+	assert.True(t, k8serrors.IsNotFound(DeleteHPA(context.TODO(), kubeClient, TEST_HPA_NAMESPACE, "prod-blue-some-api-hpa")))
+}
@@ -0,0 +1,58 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+
+	goYaml "github.com/ghodss/yaml"
+	"helm.sh/helm/v3/pkg/releaseutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type manifestHead struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+}
+
+// FindHPAInManifest finds the HPA hpaName in a release manifest, in the
+// autoscaling version the manifest declares it in.
+func FindHPAInManifest(releaseManifest, hpaName string) (*unstructured.Unstructured, error) {
+	for _, resource := range releaseutil.SplitManifests(releaseManifest) {
+		var head manifestHead
+		if err := goYaml.Unmarshal([]byte(resource), &head); err != nil {
+			return nil, fmt.Errorf("Error parsing release manifest: %s", err)
+		}
+		if head.Kind != HPA_KIND || head.Name != hpaName {
+			continue
+		}
+
+		hpaJson, err := goYaml.YAMLToJSON([]byte(resource))
+		if err != nil {
+			return nil, fmt.Errorf("Error parsing HPA \033[32m%s\033[97m from release manifest: %s", hpaName, err)
+		}
+		hpa := &unstructured.Unstructured{}
+		if err := hpa.UnmarshalJSON(hpaJson); err != nil {
+			return nil, fmt.Errorf("Error parsing HPA \033[32m%s\033[97m from release manifest: %s", hpaName, err)
+		}
+		return hpa, nil
+	}
+	return nil, fmt.Errorf("HPA \033[32m%s\033[97m not found in release manifest", hpaName)
+}
+
+// FindDeploymentNamesInManifest lists the names of the Deployments a release
+// manifest creates.
+func FindDeploymentNamesInManifest(releaseManifest string) ([]string, error) {
+	names := make([]string, 0)
+	for _, resource := range releaseutil.SplitManifests(releaseManifest) {
+		var head manifestHead
+		if err := goYaml.Unmarshal([]byte(resource), &head); err != nil {
+			return nil, fmt.Errorf("Error parsing release manifest: %s", err)
+		}
+		if head.Kind == "Deployment" && head.Name != "" {
+			names = append(names, head.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,108 @@
+package k8s
+
+import (
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"testing"
+)
+
+const TEST_MANIFEST = `---
+# Source: chart/charts/bluegreen/templates/deployment.yaml
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: "prod-blue-some-api"
+spec:
+  replicas: 1
+---
+# Source: chart/charts/bluegreen/templates/autoscaler.yaml
+apiVersion: autoscaling/v1
+kind: HorizontalPodAutoscaler
+metadata:
+  name: "prod-blue-some-api-hpa"
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: "prod-blue-some-api"
+  minReplicas: 2
+  maxReplicas: 25
+  targetCPUUtilizationPercentage: 80
+`
+
+const TEST_V2BETA2_MANIFEST = `---
+# Source: chart/charts/bluegreen/templates/autoscaler.yaml
+apiVersion: autoscaling/v2beta2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: "prod-blue-some-api-hpa"
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: "prod-blue-some-api"
+  minReplicas: 2
+  maxReplicas: 25
+  metrics:
+    - type: Resource
+      resource:
+        name: cpu
+        target:
+          type: Utilization
+          averageUtilization: 80
+    - type: Pods
+      pods:
+        metric:
+          name: requests_per_second
+        target:
+          type: AverageValue
+          averageValue: "100"
+  behavior:
+    scaleDown:
+      stabilizationWindowSeconds: 600
+`
+
+func Test_FindHPAInManifest_Returns_Error_When_Manifest_Empty(t *testing.T) {
+	_, err := FindHPAInManifest("", "prod-blue-some-api-hpa")
+	assert.NotNil(t, err)
+}
+
+func Test_FindHPAInManifest_Returns_Error_When_HPA_Not_Present(t *testing.T) {
+	_, err := FindHPAInManifest(TEST_MANIFEST, "prod-green-some-api-hpa")
+	assert.NotNil(t, err)
+}
+
+func Test_FindHPAInManifest_Returns_HPA_When_Present(t *testing.T) {
+	hpa, err := FindHPAInManifest(TEST_MANIFEST, "prod-blue-some-api-hpa")
+	assert.Nil(t, err)
+	assert.Equal(t, "prod-blue-some-api-hpa", hpa.GetName())
+	assert.Equal(t, HPA_API_VERSION_V1, hpa.GetAPIVersion())
+	target, _, _ := unstructured.NestedString(hpa.Object, "spec", "scaleTargetRef", "name")
+	assert.Equal(t, "prod-blue-some-api", target)
+	minReplicas, _, _ := unstructured.NestedInt64(hpa.Object, "spec", "minReplicas")
+	assert.Equal(t, int64(2), minReplicas)
+	maxReplicas, _, _ := unstructured.NestedInt64(hpa.Object, "spec", "maxReplicas")
+	assert.Equal(t, int64(25), maxReplicas)
+}
+
+func Test_FindHPAInManifest_Keeps_The_Metrics_And_Behavior_Of_Later_Versions(t *testing.T) {
+	hpa, err := FindHPAInManifest(TEST_V2BETA2_MANIFEST, "prod-blue-some-api-hpa")
+	assert.Nil(t, err)
+	assert.Equal(t, HPA_API_VERSION_V2BETA2, hpa.GetAPIVersion())
+	metrics, _, _ := unstructured.NestedSlice(hpa.Object, "spec", "metrics")
+	assert.Len(t, metrics, 2)
+	window, _, _ := unstructured.NestedInt64(hpa.Object, "spec", "behavior", "scaleDown", "stabilizationWindowSeconds")
+	assert.Equal(t, int64(600), window)
+}
+
+func Test_FindDeploymentNamesInManifest_Returns_Empty_When_Manifest_Empty(t *testing.T) {
+	names, err := FindDeploymentNamesInManifest("")
+	assert.Nil(t, err)
+	assert.Empty(t, names)
+}
+
+func Test_FindDeploymentNamesInManifest_Returns_Deployment_Names(t *testing.T) {
+	names, err := FindDeploymentNamesInManifest(TEST_MANIFEST)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"prod-blue-some-api"}, names)
+}
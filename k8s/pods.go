@@ -0,0 +1,73 @@
+package k8s
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func IsPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+func PodRestartCount(pod *corev1.Pod) int32 {
+	var restarts int32 = 0
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		restarts += containerStatus.RestartCount
+	}
+	return restarts
+}
+
+func PodHealthProblems(pods []corev1.Pod, maxRestarts int32) []string {
+	problems := make([]string, 0)
+	livePods := 0
+	for i := range pods {
+		pod := &pods[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		livePods++
+		if !IsPodReady(pod) {
+			problems = append(problems, fmt.Sprintf("pod %s is not ready", pod.GetName()))
+		}
+		if restarts := PodRestartCount(pod); restarts > maxRestarts {
+			problems = append(problems, fmt.Sprintf("pod %s has restarted %d time(s), more than the allowed %d", pod.GetName(), restarts, maxRestarts))
+		}
+	}
+	if livePods == 0 {
+		problems = append(problems, "no running pods found")
+	}
+	return problems
+}
+
+// IsContainerCrashing reports whether a container is crash looping or has
+// exited in error, so its logs are worth reading.
+func IsContainerCrashing(containerStatus corev1.ContainerStatus) bool {
+	if containerStatus.State.Waiting != nil && containerStatus.State.Waiting.Reason == "CrashLoopBackOff" {
+		return true
+	}
+	if containerStatus.State.Terminated != nil && containerStatus.State.Terminated.ExitCode != 0 {
+		return true
+	}
+	return containerStatus.RestartCount > 0
+}
+
+// DescribeContainerState summarises what a container is doing, for reports.
+func DescribeContainerState(containerStatus corev1.ContainerStatus) string {
+	state := "running"
+	switch {
+	case containerStatus.State.Waiting != nil:
+		state = fmt.Sprintf("waiting (%s)", containerStatus.State.Waiting.Reason)
+		if containerStatus.State.Waiting.Message != "" {
+			state = fmt.Sprintf("%s: %s", state, containerStatus.State.Waiting.Message)
+		}
+	case containerStatus.State.Terminated != nil:
+		state = fmt.Sprintf("terminated (%s, exit code %d)", containerStatus.State.Terminated.Reason, containerStatus.State.Terminated.ExitCode)
+	}
+	return fmt.Sprintf("container %s is %s, ready: %t, restarts: %d", containerStatus.Name, state, containerStatus.Ready, containerStatus.RestartCount)
+}
@@ -0,0 +1,97 @@
+package k8s
+
+import (
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func makePod(name string, ready bool, restarts int32) corev1.Pod {
+	readyStatus := corev1.ConditionFalse
+	if ready {
+		readyStatus = corev1.ConditionTrue
+	}
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.PodStatus{
+			Conditions: []corev1.PodCondition{
+				corev1.PodCondition{Type: corev1.PodReady, Status: readyStatus},
+			},
+			ContainerStatuses: []corev1.ContainerStatus{
+				corev1.ContainerStatus{RestartCount: restarts},
+			},
+		},
+	}
+}
+
+func Test_IsPodReady_Returns_False_When_Pod_Has_No_Conditions(t *testing.T) {
+	assert.False(t, IsPodReady(&corev1.Pod{}))
+}
+
+func Test_IsPodReady_Returns_False_When_Ready_Condition_Is_False(t *testing.T) {
+	pod := makePod("some-pod", false, 0)
+	assert.False(t, IsPodReady(&pod))
+}
+
+func Test_IsPodReady_Returns_True_When_Ready_Condition_Is_True(t *testing.T) {
+	pod := makePod("some-pod", true, 0)
+	assert.True(t, IsPodReady(&pod))
+}
+
+func Test_PodRestartCount_Sums_Container_Restarts(t *testing.T) {
+	pod := makePod("some-pod", true, 2)
+	pod.Status.ContainerStatuses = append(pod.Status.ContainerStatuses, corev1.ContainerStatus{RestartCount: 3})
+	assert.Equal(t, int32(5), PodRestartCount(&pod))
+}
+
+func Test_PodHealthProblems_Reports_No_Pods(t *testing.T) {
+	assert.Len(t, PodHealthProblems(nil, 0), 1)
+}
+
+func Test_PodHealthProblems_Returns_Empty_When_All_Pods_Healthy(t *testing.T) {
+	assert.Empty(t, PodHealthProblems([]corev1.Pod{
+		makePod("pod-1", true, 0),
+		makePod("pod-2", true, 1),
+	}, 1))
+}
+
+func Test_PodHealthProblems_Reports_Unready_And_Restarting_Pods(t *testing.T) {
+	assert.Len(t, PodHealthProblems([]corev1.Pod{
+		makePod("pod-1", false, 0),
+		makePod("pod-2", true, 3),
+		makePod("pod-3", true, 0),
+	}, 1), 2)
+}
+
+func Test_PodHealthProblems_Ignores_Terminating_Pods(t *testing.T) {
+	terminating := makePod("pod-1", false, 10)
+	terminating.DeletionTimestamp = &metav1.Time{}
+	assert.Empty(t, PodHealthProblems([]corev1.Pod{
+		terminating,
+		makePod("pod-2", true, 0),
+	}, 0))
+}
+
+func Test_IsContainerCrashing_Returns_False_When_Running_Without_Restarts(t *testing.T) {
+	assert.False(t, IsContainerCrashing(corev1.ContainerStatus{
+		State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	}))
+}
+
+func Test_IsContainerCrashing_Returns_True_When_Crash_Looping_Or_Failed(t *testing.T) {
+	assert.True(t, IsContainerCrashing(corev1.ContainerStatus{
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+	}))
+	assert.True(t, IsContainerCrashing(corev1.ContainerStatus{
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1}},
+	}))
+	assert.True(t, IsContainerCrashing(corev1.ContainerStatus{RestartCount: 2}))
+}
+
+func Test_DescribeContainerState_Describes_Waiting_Containers(t *testing.T) {
+	assert.Equal(t, "container api is waiting (ImagePullBackOff): Back-off pulling image, ready: false, restarts: 0", DescribeContainerState(corev1.ContainerStatus{
+		Name:  "api",
+		State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}},
+	}))
+}
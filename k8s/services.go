@@ -18,10 +18,31 @@ func ServiceSelectorColour(service *corev1.Service) string {
 	return ""
 }
 
-func GetService(kubeClient v1.CoreV1Interface, serviceName string) (*corev1.Service, error) {
-	service, err := kubeClient.Services("default").Get(context.TODO(), serviceName, metav1.GetOptions{})
+func GetService(ctx context.Context, kubeClient v1.CoreV1Interface, namespace, serviceName string) (*corev1.Service, error) {
+	service, err := kubeClient.Services(namespace).Get(ctx, serviceName, metav1.GetOptions{})
 	if err != nil {
 		return nil, errors.New(fmt.Sprintf("Error getting service \033[32m%s\033[97m, %s", serviceName, err.Error()))
 	}
 	return service, nil
 }
+
+// ServiceSelectorColourPatch is a merge patch setting the colour of a
+// service's selector, or removing it when colour is empty so that the service
+// selects every colour.
+func ServiceSelectorColourPatch(colour string) []byte {
+	if colour == "" {
+		return []byte(`{"spec":{"selector":{"colour":null}}}`)
+	}
+	return []byte(fmt.Sprintf(`{"spec":{"selector":{"colour":%q}}}`, colour))
+}
+
+// SelectsBeyondColour reports whether a service's selector has keys other than
+// colour, so that removing the colour still only selects the app's pods.
+func SelectsBeyondColour(service *corev1.Service) bool {
+	for key := range service.Spec.Selector {
+		if key != "colour" {
+			return true
+		}
+	}
+	return false
+}
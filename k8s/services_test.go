@@ -40,3 +40,24 @@ func Test_ServiceSelectorColour_Returns_Green_When_Given_Service_Has_Green_Selec
 		},
 	}))
 }
+
+func Test_ServiceSelectorColourPatch_Sets_The_Colour(t *testing.T) {
+	assert.Equal(t, `{"spec":{"selector":{"colour":"blue"}}}`, string(ServiceSelectorColourPatch("blue")))
+}
+
+func Test_ServiceSelectorColourPatch_Removes_The_Colour_When_Empty(t *testing.T) {
+	assert.Equal(t, `{"spec":{"selector":{"colour":null}}}`, string(ServiceSelectorColourPatch("")))
+}
+
+func Test_SelectsBeyondColour_Returns_False_When_Selector_Is_Only_Colour(t *testing.T) {
+	assert.False(t, SelectsBeyondColour(&corev1.Service{}))
+	assert.False(t, SelectsBeyondColour(&corev1.Service{
+		Spec: corev1.ServiceSpec{Selector: map[string]string{"colour": "blue"}},
+	}))
+}
+
+func Test_SelectsBeyondColour_Returns_True_When_Selector_Has_Other_Keys(t *testing.T) {
+	assert.True(t, SelectsBeyondColour(&corev1.Service{
+		Spec: corev1.ServiceSpec{Selector: map[string]string{"colour": "blue", "app": "some-api"}},
+	}))
+}
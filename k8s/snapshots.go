@@ -0,0 +1,57 @@
+package k8s
+
+import (
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const HPA_SNAPSHOT_KEY = "hpa.json"
+
+// HPASnapshotConfigMap snapshots the name, labels and spec of hpa, in the
+// autoscaling version it was read in.
+func HPASnapshotConfigMap(configMapName string, hpa *unstructured.Unstructured) (*corev1.ConfigMap, error) {
+	if hpa == nil {
+		return nil, errors.New("Cannot snapshot a nil HPA")
+	}
+	snapshot := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": hpa.Object["spec"],
+	}}
+	snapshot.SetAPIVersion(hpa.GetAPIVersion())
+	snapshot.SetKind(HPA_KIND)
+	snapshot.SetName(hpa.GetName())
+	snapshot.SetLabels(hpa.GetLabels())
+	snapshotJson, err := snapshot.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("Error snapshotting HPA \033[32m%s\033[97m: %s", hpa.GetName(), err)
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: configMapName,
+			Labels: map[string]string{
+				"app.kubernetes.io/managed-by": "helm-deployer",
+			},
+		},
+		Data: map[string]string{
+			HPA_SNAPSHOT_KEY: string(snapshotJson),
+		},
+	}, nil
+}
+
+func HPAFromSnapshot(configMap *corev1.ConfigMap) (*unstructured.Unstructured, error) {
+	if configMap == nil {
+		return nil, errors.New("Cannot restore an HPA from a nil snapshot")
+	}
+	snapshotJson, ok := configMap.Data[HPA_SNAPSHOT_KEY]
+	if !ok {
+		return nil, fmt.Errorf("Snapshot \033[32m%s\033[97m does not contain %s", configMap.GetName(), HPA_SNAPSHOT_KEY)
+	}
+	hpa := &unstructured.Unstructured{}
+	if err := hpa.UnmarshalJSON([]byte(snapshotJson)); err != nil {
+		return nil, fmt.Errorf("Error reading HPA snapshot \033[32m%s\033[97m: %s", configMap.GetName(), err)
+	}
+	return hpa, nil
+}
@@ -0,0 +1,101 @@
+package k8s
+
+import (
+	"github.com/stretchr/testify/assert"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"testing"
+)
+
+func makeTypedHPA() *autoscalingv2beta2.HorizontalPodAutoscaler {
+	var minReplicas int32 = 2
+	var averageUtilization int32 = 80
+	var stabilizationWindowSeconds int32 = 600
+	return &autoscalingv2beta2.HorizontalPodAutoscaler{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: HPA_API_VERSION_V2BETA2,
+			Kind:       HPA_KIND,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "prod-blue-some-api-hpa",
+			Labels:          map[string]string{"app": "some-api"},
+			ResourceVersion: "12345",
+			UID:             "some-uid",
+		},
+		Spec: autoscalingv2beta2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2beta2.CrossVersionObjectReference{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Name:       "prod-blue-some-api",
+			},
+			MinReplicas: &minReplicas,
+			MaxReplicas: 25,
+			Metrics: []autoscalingv2beta2.MetricSpec{{
+				Type: autoscalingv2beta2.ResourceMetricSourceType,
+				Resource: &autoscalingv2beta2.ResourceMetricSource{
+					Name:   corev1.ResourceCPU,
+					Target: autoscalingv2beta2.MetricTarget{Type: autoscalingv2beta2.UtilizationMetricType, AverageUtilization: &averageUtilization},
+				},
+			}},
+			Behavior: &autoscalingv2beta2.HorizontalPodAutoscalerBehavior{
+				ScaleDown: &autoscalingv2beta2.HPAScalingRules{StabilizationWindowSeconds: &stabilizationWindowSeconds},
+			},
+		},
+	}
+}
+
+func makeHPA() *unstructured.Unstructured {
+	object, _ := k8sruntime.DefaultUnstructuredConverter.ToUnstructured(makeTypedHPA())
+	return &unstructured.Unstructured{Object: object}
+}
+
+func Test_HPASnapshotConfigMap_Returns_Error_When_Given_Nil(t *testing.T) {
+	_, err := HPASnapshotConfigMap("some-snapshot", nil)
+	assert.NotNil(t, err)
+}
+
+func Test_HPASnapshotConfigMap_Returns_Named_ConfigMap(t *testing.T) {
+	configMap, err := HPASnapshotConfigMap("some-snapshot", makeHPA())
+	assert.Nil(t, err)
+	assert.Equal(t, "some-snapshot", configMap.GetName())
+	assert.Contains(t, configMap.Data, HPA_SNAPSHOT_KEY)
+}
+
+func Test_HPAFromSnapshot_Returns_Error_When_Given_Nil(t *testing.T) {
+	_, err := HPAFromSnapshot(nil)
+	assert.NotNil(t, err)
+}
+
+func Test_HPAFromSnapshot_Returns_Error_When_Snapshot_Key_Missing(t *testing.T) {
+	_, err := HPAFromSnapshot(&corev1.ConfigMap{})
+	assert.NotNil(t, err)
+}
+
+func Test_HPAFromSnapshot_Restores_Snapshotted_HPA_Without_Server_Fields(t *testing.T) {
+	original := makeTypedHPA()
+	configMap, _ := HPASnapshotConfigMap("some-snapshot", makeHPA())
+	snapshot, err := HPAFromSnapshot(configMap)
+	assert.Nil(t, err)
+	assert.Equal(t, HPA_API_VERSION_V2BETA2, snapshot.GetAPIVersion())
+
+	restored := &autoscalingv2beta2.HorizontalPodAutoscaler{}
+	assert.Nil(t, k8sruntime.DefaultUnstructuredConverter.FromUnstructured(snapshot.Object, restored))
+	assert.Equal(t, original.GetName(), restored.GetName())
+	assert.Equal(t, original.GetLabels(), restored.GetLabels())
+	assert.Equal(t, original.Spec, restored.Spec)
+	assert.Equal(t, "", restored.ResourceVersion)
+	assert.Equal(t, "", string(restored.UID))
+}
+
+func Test_HPAFromSnapshot_Restores_An_autoscaling_v1_Snapshot(t *testing.T) {
+	configMap := &corev1.ConfigMap{Data: map[string]string{
+		HPA_SNAPSHOT_KEY: `{"apiVersion":"autoscaling/v1","kind":"HorizontalPodAutoscaler","metadata":{"name":"prod-blue-some-api-hpa"},"spec":{"maxReplicas":25,"targetCPUUtilizationPercentage":80}}`,
+	}}
+	snapshot, err := HPAFromSnapshot(configMap)
+	assert.Nil(t, err)
+	assert.Equal(t, HPA_API_VERSION_V1, snapshot.GetAPIVersion())
+	assert.Equal(t, "prod-blue-some-api-hpa", snapshot.GetName())
+}
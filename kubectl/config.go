@@ -3,28 +3,53 @@ package kubectl
 import (
 	"errors"
 	"fmt"
-	"path/filepath"
+	"os"
 
 	"github.com/Hutchison-Technologies/helm-deployer/filesystem"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
-func ConfigPath(homeDir string) (string, error) {
-	if homeDir == "" {
-		return "", errors.New("Home dir must not be empty")
-	}
-	return filepath.Join(homeDir, ".kube", "config"), nil
+const (
+	IN_CLUSTER_TOKEN_PATH = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
+// IsInCluster reports whether we are running in a pod with a service account.
+func IsInCluster() bool {
+	return os.Getenv("KUBERNETES_SERVICE_HOST") != "" && filesystem.IsFile(IN_CLUSTER_TOKEN_PATH)
 }
 
-func Config(configPath string) (*rest.Config, error) {
-	if !filesystem.IsFile(configPath) {
-		return nil, errors.New(fmt.Sprintf("kubeconfig does not exist at path: %s", configPath))
+// UsesInClusterConfig reports whether RESTConfig will use the pod's service
+// account, which it does when running in a pod with no kubeconfig to hand.
+func UsesInClusterConfig(kubeconfigPath string) bool {
+	if kubeconfigPath != "" || os.Getenv(clientcmd.RecommendedConfigPathEnvVar) != "" {
+		return false
 	}
+	return !filesystem.IsFile(clientcmd.RecommendedHomeFile) && IsInCluster()
+}
 
-	config, err := clientcmd.BuildConfigFromFlags("", configPath)
-	if err != nil {
-		return nil, err
+// ClientConfig loads the given kubeconfig, or $KUBECONFIG, or ~/.kube/config
+// when empty, selecting kubeContext and namespace when given.
+func ClientConfig(kubeconfigPath, kubeContext, namespace string) clientcmd.ClientConfig {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = kubeconfigPath
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	overrides.Context.Namespace = namespace
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+}
+
+// RESTConfig resolves the cluster connection shared by helm and the kubectl
+// clients: an explicit kubeconfig, then $KUBECONFIG, then ~/.kube/config and
+// finally the pod's service account.
+func RESTConfig(kubeconfigPath, kubeContext string) (*rest.Config, error) {
+	if kubeconfigPath != "" && !filesystem.IsFile(kubeconfigPath) {
+		return nil, errors.New(fmt.Sprintf("kubeconfig does not exist at path: %s", kubeconfigPath))
+	}
+	if UsesInClusterConfig(kubeconfigPath) {
+		if kubeContext != "" {
+			return nil, errors.New(fmt.Sprintf("Cannot select kube context %s when using the in-cluster config", kubeContext))
+		}
+		return rest.InClusterConfig()
 	}
-	return config, nil
+	return ClientConfig(kubeconfigPath, kubeContext, "").ClientConfig()
 }
@@ -5,12 +5,43 @@ import (
 	"testing"
 )
 
-func Test_ConfigPath_Returns_Error_When_Home_Empty(t *testing.T) {
-	_, err := ConfigPath("")
+const TEST_KUBECONFIG = "../testdata/kubeconfig"
+
+func Test_RESTConfig_Returns_Error_When_File_Does_Not_Exist(t *testing.T) {
+	_, err := RESTConfig("/some/nonexistent/file/path", "")
 	assert.NotNil(t, err)
 }
 
-func Test_Config_Returns_Error_When_File_Does_Not_Exist(t *testing.T) {
-	_, err := Config("/some/nonexistent/file/path")
+func Test_RESTConfig_Uses_Current_Context_By_Default(t *testing.T) {
+	config, err := RESTConfig(TEST_KUBECONFIG, "")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://staging.example.com", config.Host)
+}
+
+func Test_RESTConfig_Uses_Given_Context(t *testing.T) {
+	config, err := RESTConfig(TEST_KUBECONFIG, "prod")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://prod.example.com", config.Host)
+}
+
+func Test_RESTConfig_Returns_Error_When_Context_Does_Not_Exist(t *testing.T) {
+	_, err := RESTConfig(TEST_KUBECONFIG, "some-context")
 	assert.NotNil(t, err)
 }
+
+func Test_UsesInClusterConfig_Returns_False_When_Kubeconfig_Given(t *testing.T) {
+	assert.False(t, UsesInClusterConfig(TEST_KUBECONFIG))
+}
+
+func Test_RESTClientGetter_Shares_Config_And_Namespace(t *testing.T) {
+	config, _ := RESTConfig(TEST_KUBECONFIG, "prod")
+	getter := RESTClientGetter(config, TEST_KUBECONFIG, "prod", "apps")
+
+	getterConfig, err := getter.ToRESTConfig()
+	assert.Nil(t, err)
+	assert.Equal(t, "https://prod.example.com", getterConfig.Host)
+
+	namespace, _, err := getter.ToRawKubeConfigLoader().Namespace()
+	assert.Nil(t, err)
+	assert.Equal(t, "apps", namespace)
+}
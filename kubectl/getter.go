@@ -0,0 +1,52 @@
+package kubectl
+
+import (
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// restClientGetter hands helm an already resolved rest.Config, so that helm
+// talks to the same cluster as the kubectl clients.
+type restClientGetter struct {
+	config       *rest.Config
+	clientConfig clientcmd.ClientConfig
+}
+
+// RESTClientGetter adapts config for helm's action.Configuration, defaulting
+// helm's kube client to namespace.
+func RESTClientGetter(config *rest.Config, kubeconfigPath, kubeContext, namespace string) genericclioptions.RESTClientGetter {
+	return &restClientGetter{
+		config:       config,
+		clientConfig: ClientConfig(kubeconfigPath, kubeContext, namespace),
+	}
+}
+
+func (getter *restClientGetter) ToRESTConfig() (*rest.Config, error) {
+	return rest.CopyConfig(getter.config), nil
+}
+
+func (getter *restClientGetter) ToDiscoveryClient() (discovery.CachedDiscoveryInterface, error) {
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(getter.config)
+	if err != nil {
+		return nil, err
+	}
+	return memory.NewMemCacheClient(discoveryClient), nil
+}
+
+func (getter *restClientGetter) ToRESTMapper() (meta.RESTMapper, error) {
+	discoveryClient, err := getter.ToDiscoveryClient()
+	if err != nil {
+		return nil, err
+	}
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(discoveryClient)
+	return restmapper.NewShortcutExpander(mapper, discoveryClient), nil
+}
+
+func (getter *restClientGetter) ToRawKubeConfigLoader() clientcmd.ClientConfig {
+	return getter.clientConfig
+}
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"log"
+	"os"
+
 	"github.com/Hutchison-Technologies/helm-deployer/cli"
+	"github.com/Hutchison-Technologies/helm-deployer/runtime"
 )
 
 func main() {
 	err := cli.Run()
 	if err != nil {
-		panic(err.Error())
+		log.Println(err.Error())
 	}
+	os.Exit(runtime.ExitCode(err))
 }
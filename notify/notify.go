@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	EVENT_STARTED     = "started"
+	EVENT_SUCCEEDED   = "succeeded"
+	EVENT_ROLLED_BACK = "rolled_back"
+	EVENT_FAILED      = "failed"
+)
+
+const (
+	FORMAT_JSON  = "json"
+	FORMAT_SLACK = "slack"
+)
+
+const DELIVERY_TIMEOUT = 10 * time.Second
+
+// Event is the JSON payload POSTed to webhooks in the json format.
+type Event struct {
+	Event     string `json:"event"`
+	Time      string `json:"time"`
+	Command   string `json:"command"`
+	App       string `json:"app,omitempty"`
+	Env       string `json:"env,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Release   string `json:"release,omitempty"`
+	Revision  int    `json:"revision,omitempty"`
+	Colour    string `json:"colour,omitempty"`
+	Outcome   string `json:"outcome,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// Webhook is a URL to POST events to, either as an Event, in a chat tool's
+// format, or rendered by a text/template given the Event.
+type Webhook struct {
+	URL      string `json:"url"`
+	Format   string `json:"format,omitempty"`
+	Template string `json:"template,omitempty"`
+}
+
+type Notifier struct {
+	Webhooks []*Webhook
+	Client   *http.Client
+	Logf     func(format string, args ...interface{})
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		encoded, err := json.Marshal(value)
+		return string(encoded), err
+	},
+}
+
+func NewNotifier(webhooks []*Webhook, logf func(format string, args ...interface{})) *Notifier {
+	return &Notifier{
+		Webhooks: webhooks,
+		Client:   &http.Client{Timeout: DELIVERY_TIMEOUT},
+		Logf:     logf,
+	}
+}
+
+// Summary describes event in a sentence, for chat tools.
+func (event Event) Summary() string {
+	subject := event.App
+	if event.Version != "" {
+		subject = fmt.Sprintf("%s %s", subject, event.Version)
+	}
+	summary := fmt.Sprintf("helm-deployer %s of %s to %s %s", event.Command, subject, event.Env, strings.Replace(event.Event, "_", " ", -1))
+	if event.Release != "" {
+		summary = fmt.Sprintf("%s (%s", summary, event.Release)
+		if event.Revision > 0 {
+			summary = fmt.Sprintf("%s revision %d", summary, event.Revision)
+		}
+		summary += ")"
+	}
+	if event.Message != "" {
+		summary = fmt.Sprintf("%s: %s", summary, event.Message)
+	}
+	return summary
+}
+
+func (webhook *Webhook) Validate() error {
+	if webhook.URL == "" {
+		return errors.New("url must be set")
+	}
+	switch webhook.Format {
+	case "", FORMAT_JSON, FORMAT_SLACK:
+	default:
+		return fmt.Errorf("unknown format %s, must be %s or %s", webhook.Format, FORMAT_JSON, FORMAT_SLACK)
+	}
+	if webhook.Template != "" {
+		if _, err := template.New("webhook").Funcs(templateFuncs).Parse(webhook.Template); err != nil {
+			return fmt.Errorf("invalid template: %s", err)
+		}
+	}
+	return nil
+}
+
+// Body renders event for the webhook.
+func (webhook *Webhook) Body(event Event) ([]byte, error) {
+	if webhook.Template != "" {
+		tmpl, err := template.New("webhook").Funcs(templateFuncs).Parse(webhook.Template)
+		if err != nil {
+			return nil, err
+		}
+		body := &bytes.Buffer{}
+		if err := tmpl.Execute(body, event); err != nil {
+			return nil, err
+		}
+		return body.Bytes(), nil
+	}
+	if webhook.Format == FORMAT_SLACK {
+		return json.Marshal(map[string]string{"text": event.Summary()})
+	}
+	return json.Marshal(event)
+}
+
+// Send POSTs event to webhook, failing on any non-2xx response.
+func (notifier *Notifier) Send(webhook *Webhook, event Event) error {
+	body, err := webhook.Body(event)
+	if err != nil {
+		return fmt.Errorf("Failed to render %s event: %s", event.Event, err)
+	}
+	response, err := notifier.Client.Post(os.ExpandEnv(webhook.URL), "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The URL often carries a token, so leave it out of the error.
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("Failed to deliver %s event: %s", event.Event, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("Failed to deliver %s event: webhook responded %s", event.Event, response.Status)
+	}
+	return nil
+}
+
+// Notify sends event to every webhook. Delivery failures are logged and
+// never returned, so that notifications cannot fail a deploy.
+func (notifier *Notifier) Notify(event Event) {
+	if notifier == nil {
+		return
+	}
+	if event.Time == "" {
+		event.Time = time.Now().UTC().Format(time.RFC3339)
+	}
+	for i, webhook := range notifier.Webhooks {
+		if err := notifier.Send(webhook, event); err != nil && notifier.Logf != nil {
+			notifier.Logf("Webhook %d: %s", i+1, err)
+		}
+	}
+}
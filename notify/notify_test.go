@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testEvent() Event {
+	return Event{
+		Event:    EVENT_SUCCEEDED,
+		Time:     "2021-08-01T12:00:00Z",
+		Command:  "bluegreen",
+		App:      "example-app",
+		Env:      "prod",
+		Version:  "1.2.3",
+		Release:  "prod-example-app-blue",
+		Revision: 4,
+		Outcome:  "succeeded",
+	}
+}
+
+// recordingServer responds to every request with status, recording the bodies
+// it was sent.
+func recordingServer(status int) (*httptest.Server, *[]string) {
+	bodies := &[]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		*bodies = append(*bodies, string(body))
+		w.WriteHeader(status)
+	}))
+	return server, bodies
+}
+
+func Test_Send_Posts_The_Event_As_JSON_By_Default(t *testing.T) {
+	server, bodies := recordingServer(http.StatusOK)
+	defer server.Close()
+
+	err := NewNotifier(nil, nil).Send(&Webhook{URL: server.URL}, testEvent())
+
+	assert.Nil(t, err)
+	assert.Len(t, *bodies, 1)
+	var sent Event
+	assert.Nil(t, json.Unmarshal([]byte((*bodies)[0]), &sent))
+	assert.Equal(t, testEvent(), sent)
+}
+
+func Test_Send_Posts_A_Summary_In_The_Slack_Format(t *testing.T) {
+	server, bodies := recordingServer(http.StatusOK)
+	defer server.Close()
+
+	err := NewNotifier(nil, nil).Send(&Webhook{URL: server.URL, Format: FORMAT_SLACK}, testEvent())
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"text":"helm-deployer bluegreen of example-app 1.2.3 to prod succeeded (prod-example-app-blue revision 4)"}`, (*bodies)[0])
+}
+
+func Test_Send_Renders_The_Template_When_Given(t *testing.T) {
+	server, bodies := recordingServer(http.StatusOK)
+	defer server.Close()
+
+	webhook := &Webhook{URL: server.URL, Template: `{"msg": {{ json .App }}, "status": "{{ .Event }}"}`}
+	err := NewNotifier(nil, nil).Send(webhook, testEvent())
+
+	assert.Nil(t, err)
+	assert.Equal(t, `{"msg": "example-app", "status": "succeeded"}`, (*bodies)[0])
+}
+
+func Test_Send_Expands_Environment_Variables_In_The_URL(t *testing.T) {
+	server, bodies := recordingServer(http.StatusOK)
+	defer server.Close()
+	os.Setenv("NOTIFY_TEST_WEBHOOK_URL", server.URL)
+	defer os.Unsetenv("NOTIFY_TEST_WEBHOOK_URL")
+
+	err := NewNotifier(nil, nil).Send(&Webhook{URL: "${NOTIFY_TEST_WEBHOOK_URL}"}, testEvent())
+
+	assert.Nil(t, err)
+	assert.Len(t, *bodies, 1)
+}
+
+func Test_Send_Returns_Error_When_The_Webhook_Responds_Non_2xx(t *testing.T) {
+	server, _ := recordingServer(http.StatusInternalServerError)
+	defer server.Close()
+
+	err := NewNotifier(nil, nil).Send(&Webhook{URL: server.URL}, testEvent())
+
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "500")
+}
+
+func Test_Notify_Logs_Failures_And_Carries_On_To_The_Next_Webhook(t *testing.T) {
+	failing, _ := recordingServer(http.StatusBadGateway)
+	defer failing.Close()
+	working, bodies := recordingServer(http.StatusNoContent)
+	defer working.Close()
+	unreachable, _ := recordingServer(http.StatusOK)
+	unreachable.Close()
+
+	logged := []string{}
+	logf := func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}
+	notifier := NewNotifier([]*Webhook{{URL: unreachable.URL}, {URL: failing.URL}, {URL: working.URL}}, logf)
+
+	assert.NotPanics(t, func() { notifier.Notify(testEvent()) })
+	assert.Len(t, logged, 2)
+	assert.Contains(t, logged[0], "Webhook 1")
+	assert.Contains(t, logged[1], "Webhook 2")
+	assert.Len(t, *bodies, 1)
+}
+
+func Test_Notify_Does_Not_Log_The_Webhook_URL(t *testing.T) {
+	unreachable, _ := recordingServer(http.StatusOK)
+	unreachable.Close()
+	webhookURL := unreachable.URL + "/hooks/secret-token"
+
+	logged := []string{}
+	logf := func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}
+	NewNotifier([]*Webhook{{URL: webhookURL}}, logf).Notify(testEvent())
+
+	assert.Len(t, logged, 1)
+	assert.NotContains(t, logged[0], "secret-token")
+	assert.NotContains(t, logged[0], unreachable.URL)
+}
+
+func Test_Notify_Does_Nothing_When_Nil(t *testing.T) {
+	var notifier *Notifier
+	assert.NotPanics(t, func() { notifier.Notify(testEvent()) })
+}
+
+func Test_Notify_Sets_The_Time_When_Missing(t *testing.T) {
+	server, bodies := recordingServer(http.StatusOK)
+	defer server.Close()
+
+	event := testEvent()
+	event.Time = ""
+	NewNotifier([]*Webhook{{URL: server.URL}}, nil).Notify(event)
+
+	var sent Event
+	assert.Nil(t, json.Unmarshal([]byte((*bodies)[0]), &sent))
+	assert.NotEmpty(t, sent.Time)
+}
+
+func Test_Validate_Returns_Error_When_Webhook_Is_Invalid(t *testing.T) {
+	assert.NotNil(t, (&Webhook{}).Validate())
+	assert.NotNil(t, (&Webhook{URL: "https://hooks.example.com", Format: "carrier-pigeon"}).Validate())
+	assert.NotNil(t, (&Webhook{URL: "https://hooks.example.com", Template: "{{ .App "}).Validate())
+	assert.Nil(t, (&Webhook{URL: "https://hooks.example.com", Format: FORMAT_SLACK}).Validate())
+}
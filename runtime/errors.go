@@ -1,5 +1,93 @@
 package runtime
 
+import (
+	"errors"
+)
+
+const (
+	EXIT_CODE_SUCCESS          = 0
+	EXIT_CODE_UNEXPECTED       = 1
+	EXIT_CODE_VALIDATION       = 2
+	EXIT_CODE_PLAN_HAS_CHANGES = 3
+	EXIT_CODE_CLUSTER          = 4
+	EXIT_CODE_NO_CHANGES       = 5
+	EXIT_CODE_DEPLOY_FAILED    = 6
+	EXIT_CODE_ROLLBACK_FAILED  = 7
+)
+
+// DeployerError classifies an error so that the process can exit with a code
+// describing what went wrong.
+type DeployerError struct {
+	ExitCode int
+	Err      error
+}
+
+func (e *DeployerError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DeployerError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError classifies err as bad flags, config, chart or values.
+func ValidationError(err error) error {
+	return classify(EXIT_CODE_VALIDATION, err)
+}
+
+// PlanHasChangesError classifies err as a plan that would change the cluster.
+func PlanHasChangesError(err error) error {
+	return classify(EXIT_CODE_PLAN_HAS_CHANGES, err)
+}
+
+// ClusterError classifies err as a failure to reach or talk to the cluster.
+func ClusterError(err error) error {
+	return classify(EXIT_CODE_CLUSTER, err)
+}
+
+// NoChangesError classifies err as a release with nothing to deploy.
+func NoChangesError(err error) error {
+	return classify(EXIT_CODE_NO_CHANGES, err)
+}
+
+// DeployFailedError classifies err as a deploy that failed and left, or put,
+// the previous release back in place.
+func DeployFailedError(err error) error {
+	return classify(EXIT_CODE_DEPLOY_FAILED, err)
+}
+
+// RollbackFailedError classifies err as a failed deploy that could not be
+// rolled back, leaving the release in need of attention.
+func RollbackFailedError(err error) error {
+	return classify(EXIT_CODE_ROLLBACK_FAILED, err)
+}
+
+// classify wraps err with exitCode, unless err has already been classified,
+// in which case the original classification wins.
+func classify(exitCode int, err error) error {
+	if err == nil {
+		return nil
+	}
+	var deployerError *DeployerError
+	if errors.As(err, &deployerError) {
+		return err
+	}
+	return &DeployerError{ExitCode: exitCode, Err: err}
+}
+
+// ExitCode returns the process exit code for err: 0 when nil, its
+// classification's code when classified and 1 otherwise.
+func ExitCode(err error) int {
+	if err == nil {
+		return EXIT_CODE_SUCCESS
+	}
+	var deployerError *DeployerError
+	if errors.As(err, &deployerError) {
+		return deployerError.ExitCode
+	}
+	return EXIT_CODE_UNEXPECTED
+}
+
 func PanicIfError(e error) {
 	if e != nil {
 		panic(e.Error())
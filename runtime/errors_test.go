@@ -2,6 +2,7 @@ package runtime
 
 import (
 	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -13,3 +14,39 @@ func Test_PanicIfError_Panics_When_Error_Is_Not_Nil(t *testing.T) {
 func Test_PanicIfError_Does_Not_Panic_When_Error_Is_Nil(t *testing.T) {
 	assert.NotPanics(t, func() { PanicIfError(nil ) })
 }
+
+func Test_ExitCode_Is_Success_When_Error_Is_Nil(t *testing.T) {
+	assert.Equal(t, EXIT_CODE_SUCCESS, ExitCode(nil))
+}
+
+func Test_ExitCode_Is_Unexpected_When_Error_Is_Not_Classified(t *testing.T) {
+	assert.Equal(t, EXIT_CODE_UNEXPECTED, ExitCode(errors.New("boom")))
+}
+
+func Test_ExitCode_Matches_Classification(t *testing.T) {
+	err := errors.New("boom")
+	assert.Equal(t, EXIT_CODE_VALIDATION, ExitCode(ValidationError(err)))
+	assert.Equal(t, EXIT_CODE_PLAN_HAS_CHANGES, ExitCode(PlanHasChangesError(err)))
+	assert.Equal(t, EXIT_CODE_CLUSTER, ExitCode(ClusterError(err)))
+	assert.Equal(t, EXIT_CODE_NO_CHANGES, ExitCode(NoChangesError(err)))
+	assert.Equal(t, EXIT_CODE_DEPLOY_FAILED, ExitCode(DeployFailedError(err)))
+	assert.Equal(t, EXIT_CODE_ROLLBACK_FAILED, ExitCode(RollbackFailedError(err)))
+}
+
+func Test_ExitCode_Survives_Wrapping(t *testing.T) {
+	err := fmt.Errorf("context: %w", ClusterError(errors.New("boom")))
+	assert.Equal(t, EXIT_CODE_CLUSTER, ExitCode(err))
+}
+
+func Test_Classification_Keeps_The_First_Classification(t *testing.T) {
+	err := DeployFailedError(ValidationError(errors.New("boom")))
+	assert.Equal(t, EXIT_CODE_VALIDATION, ExitCode(err))
+}
+
+func Test_Classification_Keeps_The_Message(t *testing.T) {
+	assert.Equal(t, "boom", ClusterError(errors.New("boom")).Error())
+}
+
+func Test_Classification_Of_Nil_Is_Nil(t *testing.T) {
+	assert.Nil(t, ValidationError(nil))
+}